@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"cv-platform/internal/adapter/gcp"
+	"cv-platform/internal/adapter/localfs"
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/adapter/postgres"
+	"cv-platform/internal/adapter/s3"
+	"cv-platform/internal/adapter/sqlite"
+	"cv-platform/internal/adapter/timeout"
+	"cv-platform/internal/config"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/cursor"
+	"errors"
+	"fmt"
+)
+
+// probeKey and probeID name an object and a CV that never exist. Looking them
+// up on start checks that the backends are reachable and that the credentials
+// grant access, instead of failing the first request.
+const (
+	probeKey = "_probe/startup"
+	probeID  = "_probe"
+)
+
+// adapters are the port implementations selected by STORAGE_DRIVER and
+// REPO_DRIVER, bounded by the configured timeouts.
+type adapters struct {
+	storage    port.BlobStorage
+	repo       port.CVRepository
+	localStore *localfs.Storage
+	// closers release the clients of the adapters, in reverse order.
+	closers []func()
+}
+
+// Close releases the clients the adapters hold.
+func (a *adapters) Close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// buildAdapters builds the storage and the repository the config selects. It
+// fails on backends that cannot be reached or used with the configured
+// credentials, after releasing whatever it built already.
+func buildAdapters(ctx context.Context, cfg *config.Config) (_ *adapters, err error) {
+	storageTimeouts := timeout.Config{Default: cfg.StorageTimeout, Ops: cfg.StorageOpTimeouts}
+	if err := storageTimeouts.Check(timeout.StorageOps); err != nil {
+		return nil, fmt.Errorf("invalid storage timeouts: %w", err)
+	}
+	repoTimeouts := timeout.Config{Default: cfg.RepoTimeout, Ops: cfg.RepoOpTimeouts}
+	if err := repoTimeouts.Check(timeout.RepositoryOps); err != nil {
+		return nil, fmt.Errorf("invalid repository timeouts: %w", err)
+	}
+
+	a := &adapters{}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+	if err := a.buildStorage(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if err := a.buildRepository(ctx, cfg); err != nil {
+		return nil, err
+	}
+	a.storage = timeout.NewBlobStorage(a.storage, storageTimeouts)
+	a.repo = timeout.NewCVRepository(a.repo, repoTimeouts)
+
+	if _, _, err := a.storage.Head(ctx, probeKey); err != nil {
+		return nil, fmt.Errorf("blob storage %s is not usable: %w", cfg.StorageDriver, err)
+	}
+	if _, err := a.repo.FindByID(ctx, probeID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("repository %s is not usable: %w", cfg.RepoDriver, err)
+	}
+	return a, nil
+}
+
+func (a *adapters) buildStorage(ctx context.Context, cfg *config.Config) error {
+	log := logger.Simple()
+	switch cfg.StorageDriver {
+	case "gcs":
+		store, err := gcp.NewGCSStorage(ctx, cfg.BucketName, cfg.CredsJSON)
+		if err != nil {
+			return fmt.Errorf("failed to create gcs storage: %w", err)
+		}
+		a.closers = append(a.closers, closer("gcs client", store.Close))
+		a.storage = store
+		log.Infof("using gcs blob storage: bucket=%s", cfg.BucketName)
+	case "s3":
+		store, err := newS3Storage(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create s3 storage: %w", err)
+		}
+		a.storage = store
+		log.Infof("using s3 blob storage: bucket=%s endpoint=%s", cfg.S3Bucket, cfg.S3Endpoint)
+	case "localfs":
+		store, err := localfs.NewStorage(cfg.LocalFSRoot, cfg.PublicBaseURL+"/api/v1/blobs", []byte(cfg.LocalFSSigningKey))
+		if err != nil {
+			return fmt.Errorf("failed to create local storage: %w", err)
+		}
+		if cfg.LocalFSSigningKey == "" {
+			log.Warn("LOCALFS_SIGNING_KEY is not set; signed urls will not survive a restart")
+		}
+		a.localStore = store
+		a.storage = store
+		log.Infof("using local blob storage: root=%s", cfg.LocalFSRoot)
+	case "memory":
+		a.storage = memory.NewBlobStorage()
+		log.Warn("using in-memory blob storage; all files are lost on restart")
+	default:
+		return fmt.Errorf("invalid STORAGE_DRIVER %q: want gcs, s3, localfs or memory", cfg.StorageDriver)
+	}
+	return nil
+}
+
+func newS3Storage(ctx context.Context, cfg *config.Config) (*s3.S3Storage, error) {
+	var opts []s3.S3Option
+	if cfg.S3Region != "" {
+		opts = append(opts, s3.WithRegion(cfg.S3Region))
+	}
+	if cfg.S3Endpoint != "" {
+		opts = append(opts, s3.WithEndpoint(cfg.S3Endpoint))
+	}
+	opts = append(opts, s3.WithPathStyle(cfg.S3UsePathStyle))
+	if cfg.S3AccessKeyID != "" {
+		opts = append(opts, s3.WithStaticCredentials(cfg.S3AccessKeyID, cfg.S3SecretAccessKey))
+	}
+	return s3.NewS3Storage(ctx, cfg.S3Bucket, opts...)
+}
+
+func (a *adapters) buildRepository(ctx context.Context, cfg *config.Config) error {
+	log := logger.Simple()
+	cursors := cursor.NewCodec([]byte(cfg.CursorSigningKey))
+	switch cfg.RepoDriver {
+	case "firestore":
+		repo, err := gcp.NewFirestoreCVRepo(ctx, cfg.ProjectID, cfg.CredsJSON, cursors)
+		if err != nil {
+			return fmt.Errorf("failed to create firestore repository: %w", err)
+		}
+		a.closers = append(a.closers, closer("firestore client", repo.Close))
+		a.repo = repo
+		log.Infof("using firestore repository: project=%s", cfg.ProjectID)
+	case "postgres":
+		pool, err := postgres.Open(ctx, cfg.PostgresDSN)
+		if err != nil {
+			return fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		a.closers = append(a.closers, pool.Close)
+		pending, err := postgres.PendingMigrations(ctx, pool)
+		if err != nil {
+			return fmt.Errorf("failed to check postgres migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("postgres schema is out of date: %d pending migrations, run `api migrate`", len(pending))
+		}
+		a.repo = postgres.NewCVRepository(pool, cursors)
+		log.Info("using postgres repository")
+	case "sqlite":
+		db, err := openSQLite(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		a.closers = append(a.closers, closer("sqlite database", db.Close))
+		// A single node has no rollout to coordinate with: migrate on start.
+		applied, err := sqlite.Migrate(ctx, db)
+		if err != nil {
+			return fmt.Errorf("failed to migrate sqlite database: %w", err)
+		}
+		for _, m := range applied {
+			log.Infof("applied sqlite migration: %s", m)
+		}
+		a.repo = sqlite.NewCVRepository(db, cursors)
+		log.Infof("using sqlite repository: path=%s", cfg.SQLitePath)
+	case "memory":
+		a.repo = memory.NewCVRepository()
+		log.Warn("using in-memory repository; all CV metadata is lost on restart")
+	default:
+		return fmt.Errorf("invalid REPO_DRIVER %q: want firestore, postgres, sqlite or memory", cfg.RepoDriver)
+	}
+	return nil
+}
+
+// closer adapts the Close method of a client to the adapters' closers,
+// logging its error.
+func closer(name string, close func() error) func() {
+	return func() {
+		if err := close(); err != nil {
+			logger.Simple().Warnf("failed to close %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"cv-platform/internal/config"
+	"errors"
+	"flag"
+	"os"
+)
+
+// configCommand inspects the configuration. "config print" writes the
+// effective settings, after the environment overrode the config file, as a
+// YAML config file; with -redacted secrets are masked.
+func configCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New("usage: api config print [-redacted]")
+	}
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "mask signing keys, passwords and credentials")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	return cfg.WriteYAML(os.Stdout, *redacted)
+}
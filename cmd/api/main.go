@@ -1,45 +1,143 @@
 package main
 
 import (
+	"context"
 	"cv-platform/internal/adapter/http"
 	"cv-platform/internal/config"
 	logger "cv-platform/internal/log"
 	"cv-platform/internal/usecase"
+	"flag"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
+// Usage:
+//
+//	api [-config file] [serve]           run the HTTP server (default)
+//	api [-config file] reap [flags]      expire abandoned pending uploads once and exit
+//	api [-config file] migrate [flags]   apply the pending database migrations and exit
+//	api [-config file] config print [-redacted]
+//	                                     print the effective configuration and exit
+//
+// The config file defaults to $CONFIG_FILE; environment variables override it.
 func main() {
-	logger.Init("info", false) // Use console format for development
+	flags := flag.NewFlagSet("api", flag.ExitOnError)
+	configFile := flags.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	_ = flags.Parse(os.Args[1:])
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Init("info", false)
+		logger.Simple().Errorf("invalid configuration:\n%v", err)
+		os.Exit(1)
+	}
+	logger.Init(cfg.LogLevel, cfg.LogFormat == "json")
 	log := logger.Simple()
 
-	cfg, err := config.Load()
+	command, args := "serve", []string(nil)
+	if flags.NArg() > 0 {
+		command, args = flags.Arg(0), flags.Args()[1:]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case "serve":
+		err = serve(ctx, cfg)
+	case "reap":
+		err = reap(ctx, cfg, args)
+	case "migrate":
+		err = migrate(ctx, cfg, args)
+	case "config":
+		err = configCommand(cfg, args)
+	default:
+		log.Errorf("unknown command %q (want serve, reap, migrate or config)", command)
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Errorf("failed to load config: %v", err)
-		return
+		log.Errorf("%s failed: %v", command, err)
+		os.Exit(1)
 	}
+}
 
+func serve(ctx context.Context, cfg *config.Config) error {
+	log := logger.Simple()
 	log.Infof("starting cv-platform API server: port=%s, version=%s", cfg.Port, "1.0.0")
 
-	// ctx := context.Background()
-	// storage, err := gcp.NewGCSStorage(ctx, cfg.BucketName, cfg.CredsJSON)
-	// if err != nil {
-	// 	log.Errorf("failed to create gcs storage: %v", err)
-	// 	return
-	// }
-
-	// repo, err := gcp.NewFirestoreCVRepo(ctx, cfg.ProjectID, cfg.CredsJSON)
-	// if err != nil {
-	// 	log.Errorf("failed to create firestore cv repo: %v", err)
-	// 	return
-	// }
-
-	// cvUploadUC := usecase.NewCVUploadUC(storage, repo)
-	var cvUploadUC *usecase.CVUploadUC
+	adapters, err := buildAdapters(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer adapters.Close()
+
+	cvUploadUC := usecase.NewCVUploadUC(adapters.storage, adapters.repo, uploadPolicy(cfg))
+	cvQueryUC := usecase.NewCVQueryUC(adapters.repo)
+	downloadUC := usecase.NewCVDownloadUC(adapters.storage, adapters.repo, usecase.DownloadMode(cfg.DownloadMode))
+	manageUC := usecase.NewCVManageUC(adapters.storage, adapters.repo)
+	var tusUC *usecase.ProxiedUploadUC
+	if cfg.TusEnabled {
+		log.Info("tus upload server enabled under /api/v1/cvs/tus/")
+		tusUC = usecase.NewProxiedUploadUC(cvUploadUC)
+	}
+	if cfg.ReaperEnabled {
+		reaper := usecase.NewPendingReaperUC(adapters.storage, adapters.repo, reaperConfig(cfg))
+		log.Infof("starting pending upload reaper: interval=%s", cfg.ReaperInterval)
+		go reaper.Run(ctx, cfg.ReaperInterval)
+	}
 	profileStoreUC := usecase.NewProfileStoreUC()
 
-	r := http.NewRouter(cvUploadUC, profileStoreUC)
+	deps := http.Deps{
+		Upload:      cvUploadUC,
+		Query:       cvQueryUC,
+		Profile:     profileStoreUC,
+		Download:    downloadUC,
+		Manage:      manageUC,
+		Tus:         tusUC,
+		CORSOrigins: cfg.CORSAllowedOrigins,
+	}
+	if adapters.localStore != nil {
+		deps.BlobStore = adapters.localStore
+	}
+	r := http.NewRouter(deps)
+	srv := &nethttp.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 
+	served := make(chan error, 1)
+	go func() { served <- srv.ListenAndServe() }()
 	log.Infof("server starting on address: :%s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Errorf("failed to run server: %v", err)
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	log.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func uploadPolicy(cfg *config.Config) usecase.UploadPolicy {
+	p := usecase.DefaultUploadPolicy()
+	if len(cfg.UploadAllowedMimeTypes) > 0 {
+		p.AllowedMimeTypes = cfg.UploadAllowedMimeTypes
+	}
+	if len(cfg.UploadAllowedExtensions) > 0 {
+		p.AllowedExtensions = cfg.UploadAllowedExtensions
 	}
+	p.MaxSize = cfg.UploadMaxSize
+	p.MaxFileNameLength = cfg.UploadMaxFileNameLength
+	p.ASCIIFileNames = cfg.UploadASCIIFileNames
+	p.Duplicates = usecase.DuplicateMode(cfg.UploadDuplicates)
+	p.DuplicatesPerOwner = cfg.UploadDuplicatesPerOwner
+	p.UploadTTL = cfg.UploadTTL
+	p.ResumableTTL = cfg.UploadResumableTTL
+	return p
 }
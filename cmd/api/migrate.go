@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"cv-platform/internal/adapter/postgres"
+	"cv-platform/internal/adapter/sqlite"
+	"cv-platform/internal/config"
+	logger "cv-platform/internal/log"
+	"cv-platform/pkg/migration"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// migrate applies the pending schema migrations of the REPO_DRIVER database.
+// With -dry-run it only lists them.
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	log := logger.Simple()
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "list the pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var pending, apply func(context.Context) ([]migration.Migration, error)
+	switch cfg.RepoDriver {
+	case "postgres":
+		pool, err := postgres.Open(ctx, cfg.PostgresDSN)
+		if err != nil {
+			return fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		defer pool.Close()
+		pending = func(ctx context.Context) ([]migration.Migration, error) { return postgres.PendingMigrations(ctx, pool) }
+		apply = func(ctx context.Context) ([]migration.Migration, error) { return postgres.Migrate(ctx, pool) }
+	case "sqlite":
+		db, err := openSQLite(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		pending = func(ctx context.Context) ([]migration.Migration, error) { return sqlite.PendingMigrations(ctx, db) }
+		apply = func(ctx context.Context) ([]migration.Migration, error) { return sqlite.Migrate(ctx, db) }
+	default:
+		return fmt.Errorf("REPO_DRIVER=%s has no migrations", cfg.RepoDriver)
+	}
+
+	if *dryRun {
+		ms, err := pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			log.Infof("pending migration: %s", m)
+		}
+		log.Infof("migrate dry run finished: pending=%d", len(ms))
+		return nil
+	}
+
+	applied, err := apply(ctx)
+	for _, m := range applied {
+		log.Infof("applied migration: %s", m)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("migrate finished: applied=%d", len(applied))
+	return nil
+}
+
+// openSQLite opens the SQLITE_PATH database, creating its directory.
+func openSQLite(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.SQLitePath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+	}
+	db, err := sqlite.Open(ctx, cfg.SQLitePath, cfg.SQLiteBusyTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %w", cfg.SQLitePath, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"cv-platform/internal/config"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/usecase"
+	"errors"
+	"flag"
+)
+
+// reap runs a single sweep of the pending upload reaper. Flags override the
+// REAPER_* settings for this run.
+func reap(ctx context.Context, cfg *config.Config, args []string) error {
+	log := logger.Simple()
+
+	fs := flag.NewFlagSet("reap", flag.ContinueOnError)
+	grace := fs.Duration("grace", cfg.ReaperGracePeriod, "grace period after upload expiry")
+	batch := fs.Int("batch-size", cfg.ReaperBatchSize, "CVs loaded per page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	adapters, err := buildAdapters(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer adapters.Close()
+
+	rc := reaperConfig(cfg)
+	rc.GracePeriod = *grace
+	rc.BatchSize = *batch
+
+	res, err := usecase.NewPendingReaperUC(adapters.storage, adapters.repo, rc).Sweep(ctx)
+	if err != nil {
+		return err
+	}
+	log.Infof("reap finished: scanned=%d, expired=%d, cleaned=%d, failed=%d", res.Scanned, res.Expired, res.Cleaned, res.Failed)
+	if res.Failed > 0 {
+		return errors.New("some pending uploads could not be expired")
+	}
+	return nil
+}
+
+func reaperConfig(cfg *config.Config) usecase.ReaperConfig {
+	return usecase.ReaperConfig{
+		GracePeriod: cfg.ReaperGracePeriod,
+		BatchSize:   cfg.ReaperBatchSize,
+		UploadTTL:   cfg.UploadTTL,
+	}
+}
@@ -0,0 +1,80 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cv-platform/internal/domain"
+)
+
+// fromGRPC classifies a Firestore error by its gRPC status code. Failures of
+// the service's own credentials stay internal errors: the caller cannot fix
+// them.
+func fromGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	var kind error
+	switch status.Code(err) {
+	case codes.NotFound:
+		kind = domain.ErrNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		kind = domain.ErrConflict
+	case codes.InvalidArgument, codes.OutOfRange:
+		kind = domain.ErrValidation
+	case codes.FailedPrecondition:
+		kind = domain.ErrPreconditionFailed
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		kind = domain.ErrUnavailable
+	default:
+		return fromContext(err)
+	}
+	return domain.WithKind(kind, err)
+}
+
+// fromGCS classifies a Cloud Storage error by its HTTP status.
+func fromGCS(err error) error {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return withStatus(gerr.Code, err)
+	}
+	return fromContext(err)
+}
+
+// withStatus classifies err, reporting an HTTP response with status code.
+func withStatus(code int, err error) error {
+	if kind := kindOfStatus(code); kind != nil {
+		return domain.WithKind(kind, err)
+	}
+	return err
+}
+
+// kindOfStatus is the kind of an HTTP error status of a Google API, or nil.
+func kindOfStatus(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return domain.ErrNotFound
+	case code == http.StatusConflict:
+		return domain.ErrConflict
+	case code == http.StatusBadRequest:
+		return domain.ErrValidation
+	case code == http.StatusPreconditionFailed:
+		return domain.ErrPreconditionFailed
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout, code >= 500:
+		return domain.ErrUnavailable
+	}
+	return nil
+}
+
+// fromContext classifies the timeouts of calls bound to a deadline.
+func fromContext(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return domain.WithKind(domain.ErrUnavailable, err)
+	}
+	return err
+}
@@ -3,22 +3,28 @@ package gcp
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"cv-platform/internal/domain"
 	"cv-platform/internal/port"
+	"cv-platform/pkg/cursor"
 )
 
 type FirestoreCVRepo struct {
-	cl   *firestore.Client
-	coll string
+	cl      *firestore.Client
+	coll    string
+	cursors *cursor.Codec
 }
 
-func NewFirestoreCVRepo(ctx context.Context, projectID string, credsJSON []byte) (port.CVRepository, error) {
+// NewFirestoreCVRepo connects to Firestore. cursors signs the List cursors; a
+// nil codec uses a random key, so cursors do not survive a restart.
+func NewFirestoreCVRepo(ctx context.Context, projectID string, credsJSON []byte, cursors *cursor.Codec) (*FirestoreCVRepo, error) {
 	var (
 		cl  *firestore.Client
 		err error
@@ -31,29 +37,98 @@ func NewFirestoreCVRepo(ctx context.Context, projectID string, credsJSON []byte)
 	if err != nil {
 		return nil, err
 	}
-	return &FirestoreCVRepo{cl: cl, coll: "cvs"}, nil
+	return NewFirestoreCVRepoFromClient(cl, "cvs", cursors), nil
 }
 
-func (r *FirestoreCVRepo) Create(cv *domain.CV) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// NewFirestoreCVRepoFromClient builds a repository on an existing client and
+// collection, e.g. a per-test collection on the Firestore emulator.
+func NewFirestoreCVRepoFromClient(cl *firestore.Client, collection string, cursors *cursor.Codec) *FirestoreCVRepo {
+	if cursors == nil {
+		cursors = cursor.NewCodec(nil)
+	}
+	return &FirestoreCVRepo{cl: cl, coll: collection, cursors: cursors}
+}
+
+// Close closes the Firestore client.
+func (r *FirestoreCVRepo) Close() error {
+	return r.cl.Close()
+}
+
+func (r *FirestoreCVRepo) Create(ctx context.Context, cv *domain.CV) error {
 	_, err := r.cl.Collection(r.coll).Doc(cv.ID).Create(ctx, cv)
-	return err
+	if status.Code(err) == codes.AlreadyExists {
+		return fmt.Errorf("%w: %s", port.ErrCVAlreadyExists, cv.ID)
+	}
+	return fromGRPC(err)
+}
+
+// Update writes the fields of cv with Doc.Update rather than Set, so that an
+// unknown ID fails with ErrCVNotFound instead of creating the document, and
+// leaves RefCount to AddRef.
+func (r *FirestoreCVRepo) Update(ctx context.Context, cv *domain.CV) error {
+	_, err := r.cl.Collection(r.coll).Doc(cv.ID).Update(ctx, cvUpdates(cv))
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, cv.ID)
+	}
+	return fromGRPC(err)
 }
 
-func (r *FirestoreCVRepo) Update(cv *domain.CV) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	_, err := r.cl.Collection(r.coll).Doc(cv.ID).Set(ctx, cv)
-	return err
+// UpdateIfStatus reads and writes the CV in one transaction, which Firestore
+// retries when the document changes in between.
+func (r *FirestoreCVRepo) UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error {
+	ref := r.cl.Collection(r.coll).Doc(cv.ID)
+	err := r.cl.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var stored domain.CV
+		if err := doc.DataTo(&stored); err != nil {
+			return err
+		}
+		if stored.Status != from {
+			return fmt.Errorf("%w: %s is %s, not %s", port.ErrCVStatusChanged, cv.ID, stored.Status, from)
+		}
+		return tx.Update(ref, cvUpdates(cv))
+	})
+	if errors.Is(err, port.ErrCVStatusChanged) {
+		return err
+	}
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, cv.ID)
+	}
+	return fromGRPC(err)
+}
+
+// cvUpdates lists every field of cv but its ID, which names the document and
+// never changes, and RefCount, which only AddRef changes.
+func cvUpdates(cv *domain.CV) []firestore.Update {
+	return []firestore.Update{
+		{Path: "OwnerID", Value: cv.OwnerID},
+		{Path: "FileName", Value: cv.FileName},
+		{Path: "MimeType", Value: cv.MimeType},
+		{Path: "DetectedMimeType", Value: cv.DetectedMimeType},
+		{Path: "Size", Value: cv.Size},
+		{Path: "GCSPath", Value: cv.GCSPath},
+		{Path: "MD5", Value: cv.MD5},
+		{Path: "CRC32C", Value: cv.CRC32C},
+		{Path: "DuplicateOf", Value: cv.DuplicateOf},
+		{Path: "Status", Value: cv.Status},
+		{Path: "RejectReason", Value: cv.RejectReason},
+		{Path: "CreatedAt", Value: cv.CreatedAt},
+		{Path: "UpdatedAt", Value: cv.UpdatedAt},
+		{Path: "UploadExpiresAt", Value: cv.UploadExpiresAt},
+		{Path: "UploadSessionURI", Value: cv.UploadSessionURI},
+	}
 }
 
-func (r *FirestoreCVRepo) FindByID(id string) (*domain.CV, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (r *FirestoreCVRepo) FindByID(ctx context.Context, id string) (*domain.CV, error) {
 	doc, err := r.cl.Collection(r.coll).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
 	if err != nil {
-		return nil, err
+		return nil, fromGRPC(err)
 	}
 	var cv domain.CV
 	if err := doc.DataTo(&cv); err != nil {
@@ -62,11 +137,76 @@ func (r *FirestoreCVRepo) FindByID(id string) (*domain.CV, error) {
 	return &cv, nil
 }
 
-func (r *FirestoreCVRepo) List(limit int, cursor string) ([]domain.CV, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	q := r.cl.Collection(r.coll).OrderBy("CreatedAt", firestore.Desc).Limit(limit)
-	it := q.Documents(ctx)
+// Composite indexes (collection "cvs", scope collection) required by List.
+// Every listing orders by its sort field and then __name__ in the same
+// direction; equality filters come first and range filters last:
+//
+//	OwnerID ASC, <Sort> <Dir>, __name__ <Dir>
+//	OwnerID ASC, Status ASC, <Sort> <Dir>, __name__ <Dir>
+//	OwnerID ASC, MimeType ASC, <Sort> <Dir>, __name__ <Dir>
+//	OwnerID ASC, Status ASC, MimeType ASC, <Sort> <Dir>, __name__ <Dir>
+//	Status ASC, <Sort> <Dir>, __name__ <Dir>                (listings across owners, e.g. the reaper)
+//	FileName ASC, <Sort> <Dir>, __name__ <Dir>              (name prefix)
+//	CreatedAt ASC, <Sort> <Dir>, __name__ <Dir>             (created range, Sort != CreatedAt)
+//	Size ASC, <Sort> <Dir>, __name__ <Dir>                  (size range, Sort != Size)
+//
+// with <Sort> in CreatedAt, UpdatedAt, Size and <Dir> in ASC, DESC. Range
+// filters combine with the equality prefixes above (e.g. OwnerID ASC, Status
+// ASC, FileName ASC, CreatedAt DESC, __name__ DESC). Unfiltered listings only
+// need the automatic single-field indexes. Missing indexes surface as a
+// FailedPrecondition error whose message links to the console to create them.
+//
+// FindByContent additionally needs
+//
+//	MD5 ASC, CRC32C ASC, Size ASC, DuplicateOf ASC, Status ASC, CreatedAt ASC, __name__ ASC
+//
+// with OwnerID ASC after Size when duplicates are scoped to owners.
+
+var firestoreSortFields = map[port.CVSortField]string{
+	port.CVSortCreatedAt: "CreatedAt",
+	port.CVSortUpdatedAt: "UpdatedAt",
+	port.CVSortSize:      "Size",
+}
+
+// List pages through the CVs matching q. Ordering on the sort field and then
+// the document ID gives a total order, and StartAfter on that key keeps pages
+// stable while new CVs are inserted.
+func (r *FirestoreCVRepo) List(ctx context.Context, q port.CVQuery) ([]domain.CV, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidQuery, err)
+	}
+	q = q.Normalized()
+
+	dir := firestore.Desc
+	if q.Order == port.SortAsc {
+		dir = firestore.Asc
+	}
+	sortField := firestoreSortFields[q.SortBy]
+
+	fq := applyFilter(r.cl.Collection(r.coll).Query, q.Filter).
+		OrderBy(sortField, dir).
+		OrderBy(firestore.DocumentID, dir)
+	if q.Cursor != "" {
+		pos, err := r.cursors.Decode(q.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidCursor, err)
+		}
+		if pos.Scope != q.Scope() {
+			return nil, "", fmt.Errorf("%w: issued for another query", port.ErrInvalidCursor)
+		}
+		var key any = pos.Time
+		if q.SortBy == port.CVSortSize {
+			key = pos.Int
+		}
+		fq = fq.StartAfter(key, pos.ID)
+	}
+	if q.Limit > 0 {
+		// Fetch one extra document to learn whether another page exists.
+		fq = fq.Limit(q.Limit + 1)
+	}
+
+	it := fq.Documents(ctx)
+	defer it.Stop()
 	var out []domain.CV
 	for {
 		doc, err := it.Next()
@@ -74,7 +214,7 @@ func (r *FirestoreCVRepo) List(limit int, cursor string) ([]domain.CV, string, e
 			break
 		}
 		if err != nil {
-			return nil, "", err
+			return nil, "", fromGRPC(err)
 		}
 		var cv domain.CV
 		if err := doc.DataTo(&cv); err != nil {
@@ -82,6 +222,111 @@ func (r *FirestoreCVRepo) List(limit int, cursor string) ([]domain.CV, string, e
 		}
 		out = append(out, cv)
 	}
-	// Simplified: no real cursor
-	return out, "", nil
+	if q.Limit <= 0 || len(out) <= q.Limit {
+		return out, "", nil
+	}
+	out = out[:q.Limit]
+	return out, r.cursors.Encode(q.PositionOf(out[q.Limit-1])), nil
+}
+
+func applyFilter(fq firestore.Query, f port.CVFilter) firestore.Query {
+	if f.OwnerID != "" {
+		fq = fq.Where("OwnerID", "==", f.OwnerID)
+	}
+	if f.Status != "" {
+		fq = fq.Where("Status", "==", string(f.Status))
+	}
+	if f.MimeType != "" {
+		fq = fq.Where("MimeType", "==", f.MimeType)
+	}
+	if !f.CreatedFrom.IsZero() {
+		fq = fq.Where("CreatedAt", ">=", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		fq = fq.Where("CreatedAt", "<", f.CreatedTo)
+	}
+	if f.MinSize > 0 {
+		fq = fq.Where("Size", ">=", f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		fq = fq.Where("Size", "<=", f.MaxSize)
+	}
+	if f.FileNamePrefix != "" {
+		// Prefix match as a range: every string starting with the prefix sorts
+		// between the prefix and the prefix followed by the highest code point.
+		fq = fq.Where("FileName", ">=", f.FileNamePrefix).
+			Where("FileName", "<", f.FileNamePrefix+"\U0010FFFF")
+	}
+	return fq
+}
+
+// contentStatuses are the statuses of CVs FindByContent may return; see
+// domain.CVStatus.HasAcceptedContent.
+var contentStatuses = []string{
+	string(domain.CVStatusUploaded),
+	string(domain.CVStatusScanning),
+	string(domain.CVStatusProcessing),
+	string(domain.CVStatusReady),
+}
+
+func (r *FirestoreCVRepo) FindByContent(ctx context.Context, q port.ContentQuery) (*domain.CV, error) {
+	fq := r.cl.Collection(r.coll).
+		Where("MD5", "==", q.MD5).
+		Where("CRC32C", "==", q.CRC32C).
+		Where("Size", "==", q.Size)
+	if q.ScopeToOwner {
+		fq = fq.Where("OwnerID", "==", q.OwnerID)
+	}
+	fq = fq.Where("DuplicateOf", "==", "").
+		Where("Status", "in", contentStatuses).
+		OrderBy("CreatedAt", firestore.Asc).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Limit(1)
+
+	docs, err := fq.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fromGRPC(err)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w: no cv with md5 %s", port.ErrCVNotFound, q.MD5)
+	}
+	var cv domain.CV
+	if err := docs[0].DataTo(&cv); err != nil {
+		return nil, err
+	}
+	return &cv, nil
+}
+
+// AddRef runs in a transaction so concurrent links and unlinks of the same
+// canonical CV are not lost.
+func (r *FirestoreCVRepo) AddRef(ctx context.Context, id string, delta int) (int, error) {
+	ref := r.cl.Collection(r.coll).Doc(id)
+	var count int
+	err := r.cl.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var cv domain.CV
+		if err := doc.DataTo(&cv); err != nil {
+			return err
+		}
+		count = cv.RefCount + delta
+		return tx.Update(ref, []firestore.Update{{Path: "RefCount", Value: count}})
+	})
+	if status.Code(err) == codes.NotFound {
+		return 0, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return 0, fromGRPC(err)
+	}
+	return count, nil
+}
+
+func (r *FirestoreCVRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.cl.Collection(r.coll).Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	return fromGRPC(err)
 }
@@ -0,0 +1,35 @@
+package gcp_test
+
+import (
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+
+	"cv-platform/internal/adapter/gcp"
+	"cv-platform/internal/port"
+	"cv-platform/internal/port/porttest"
+)
+
+// TestFirestoreCVRepoConformance needs the Firestore emulator and skips
+// without FIRESTORE_EMULATOR_HOST; CI runs it in the firestore job. Locally:
+//
+//	gcloud emulators firestore start --host-port=localhost:8200
+//	FIRESTORE_EMULATOR_HOST=localhost:8200 go test ./internal/adapter/gcp
+//
+// Each subtest gets its own collection.
+func TestFirestoreCVRepoConformance(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set")
+	}
+	cl, err := firestore.NewClient(t.Context(), "cv-platform-test")
+	if err != nil {
+		t.Fatalf("firestore.NewClient: %v", err)
+	}
+	t.Cleanup(func() { cl.Close() })
+
+	porttest.RunCVRepositoryConformance(t, func(t *testing.T) port.CVRepository {
+		return gcp.NewFirestoreCVRepoFromClient(cl, "cvs-"+uuid.NewString(), nil)
+	})
+}
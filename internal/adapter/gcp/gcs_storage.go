@@ -3,11 +3,18 @@ package gcp
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/resumable"
 
 	logger "cv-platform/internal/log"
 
@@ -21,9 +28,33 @@ type GCSStorage struct {
 	bucket      string
 	signerEmail string
 	privateKey  []byte
+	hostname    string
+	insecure    bool
 }
 
-func NewGCSStorage(ctx context.Context, bucket string, credsJSON []byte) (*GCSStorage, error) {
+// GCSOption customises a GCSStorage.
+type GCSOption func(*GCSStorage)
+
+// WithSigner sets the service account used to sign URLs. Without it, signing
+// credentials are detected from the client's credentials.
+func WithSigner(email string, privateKey []byte) GCSOption {
+	return func(g *GCSStorage) {
+		g.signerEmail = email
+		g.privateKey = privateKey
+	}
+}
+
+// WithSignedURLHost makes signed URLs point at host instead of
+// storage.googleapis.com, over plain http when insecure is set. Useful for
+// emulators and fake servers.
+func WithSignedURLHost(host string, insecure bool) GCSOption {
+	return func(g *GCSStorage) {
+		g.hostname = host
+		g.insecure = insecure
+	}
+}
+
+func NewGCSStorage(ctx context.Context, bucket string, credsJSON []byte, opts ...GCSOption) (*GCSStorage, error) {
 	var (
 		cl  *storage.Client
 		err error
@@ -37,27 +68,237 @@ func NewGCSStorage(ctx context.Context, bucket string, credsJSON []byte) (*GCSSt
 		logger.L().Error("failed to create gcs client", zap.Error(err))
 		return nil, err
 	}
-	return &GCSStorage{client: cl, bucket: bucket}, nil
+	return NewGCSStorageFromClient(cl, bucket, opts...), nil
+}
+
+// NewGCSStorageFromClient builds a GCSStorage on an existing client.
+func NewGCSStorageFromClient(cl *storage.Client, bucket string, opts ...GCSOption) *GCSStorage {
+	g := &GCSStorage{client: cl, bucket: bucket}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Close closes the storage client.
+func (g *GCSStorage) Close() error {
+	return g.client.Close()
 }
 
-func (g *GCSStorage) SignedURL(object string, opts port.SignedURLOptions) (string, error) {
-	return storage.SignedURL(g.bucket, object, &storage.SignedURLOptions{
-		Scheme:      storage.SigningSchemeV4,
-		Method:      http.MethodPut,
-		Expires:     opts.ExpiredAt,
-		ContentType: opts.ContentType,
+func (g *GCSStorage) SignedURL(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = http.MethodPut
+	}
+	// Content-MD5 and X-Goog-Hash become signed headers; GCS verifies the
+	// uploaded bytes against them.
+	var headers []string
+	if opts.Checksums.CRC32C != "" {
+		headers = append(headers, "x-goog-hash:"+checksum.HashHeader("", opts.Checksums.CRC32C))
+	}
+	var query url.Values
+	if opts.ContentDisposition != "" {
+		query = url.Values{"response-content-disposition": {opts.ContentDisposition}}
+	}
+	return g.client.Bucket(g.bucket).SignedURL(object, &storage.SignedURLOptions{
+		GoogleAccessID:  g.signerEmail,
+		PrivateKey:      g.privateKey,
+		Scheme:          storage.SigningSchemeV4,
+		Method:          method,
+		Expires:         opts.ExpiredAt,
+		ContentType:     opts.ContentType,
+		MD5:             opts.Checksums.MD5,
+		Headers:         headers,
+		QueryParameters: query,
+		Hostname:        g.hostname,
+		Insecure:        g.insecure,
 	})
 }
 
-func (g *GCSStorage) Head(object string) (bool, int64, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// SignedPostPolicy signs a V4 POST policy. Besides the exact key and content
+// type, it carries a content-length-range condition when opts.MaxSize is set,
+// so GCS itself refuses oversized uploads.
+func (g *GCSStorage) SignedPostPolicy(ctx context.Context, object string, opts port.SignedURLOptions) (*port.PostPolicy, error) {
+	var conds []storage.PostPolicyV4Condition
+	if opts.MaxSize > 0 {
+		conds = append(conds, storage.ConditionContentLengthRange(uint64(max(opts.MinSize, 0)), uint64(opts.MaxSize)))
+	}
+	p, err := g.client.Bucket(g.bucket).GenerateSignedPostPolicyV4(object, &storage.PostPolicyV4Options{
+		GoogleAccessID: g.signerEmail,
+		PrivateKey:     g.privateKey,
+		Expires:        opts.ExpiredAt,
+		Hostname:       g.hostname,
+		Insecure:       g.insecure,
+		Fields: &storage.PolicyV4Fields{
+			ContentType:         opts.ContentType,
+			StatusCodeOnSuccess: http.StatusNoContent,
+		},
+		Conditions: conds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &port.PostPolicy{URL: p.URL, Fields: p.Fields}, nil
+}
+
+// StartResumable initiates an XML API resumable session: it signs a POST
+// carrying "x-goog-resumable: start" and sends it itself, so the client only
+// ever sees the session URI. The URI needs no further authentication and GCS
+// keeps it valid for a week. When opts.MaxSize is set, the session carries an
+// x-goog-content-length-range, so GCS itself refuses oversized uploads.
+func (g *GCSStorage) StartResumable(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	signed := []string{"x-goog-resumable:start"}
+	var lengthRange string
+	if opts.MaxSize > 0 {
+		lengthRange = fmt.Sprintf("%d,%d", max(opts.MinSize, 0), opts.MaxSize)
+		signed = append(signed, "x-goog-content-length-range:"+lengthRange)
+	}
+	u, err := g.client.Bucket(g.bucket).SignedURL(object, &storage.SignedURLOptions{
+		GoogleAccessID: g.signerEmail,
+		PrivateKey:     g.privateKey,
+		Scheme:         storage.SigningSchemeV4,
+		Method:         http.MethodPost,
+		Expires:        opts.ExpiredAt,
+		ContentType:    opts.ContentType,
+		Headers:        signed,
+		Hostname:       g.hostname,
+		Insecure:       g.insecure,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Goog-Resumable", "start")
+	if lengthRange != "" {
+		req.Header.Set("X-Goog-Content-Length-Range", lengthRange)
+	}
+	if opts.ContentType != "" {
+		req.Header.Set("Content-Type", opts.ContentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fromContext(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return "", withStatus(resp.StatusCode, fmt.Errorf("gcs: start resumable upload of %s: status %d", object, resp.StatusCode))
+	}
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return "", fmt.Errorf("gcs: start resumable upload of %s: no session uri", object)
+	}
+	return loc, nil
+}
+
+// ResumableStatus queries the session with an empty PUT, as clients do.
+func (g *GCSStorage) ResumableStatus(ctx context.Context, sessionURI string) (port.UploadStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, http.NoBody)
+	if err != nil {
+		return port.UploadStatus{}, err
+	}
+	req.Header.Set("Content-Range", "bytes */*")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return port.UploadStatus{}, fromContext(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case resumable.StatusIncomplete:
+		return port.UploadStatus{Received: resumable.ParseRangeHeader(resp.Header.Get("Range"))}, nil
+	case http.StatusOK, http.StatusCreated:
+		n, _ := strconv.ParseInt(resp.Header.Get("X-Goog-Stored-Content-Length"), 10, 64)
+		return port.UploadStatus{Received: n, Complete: true}, nil
+	case http.StatusNotFound, http.StatusGone, 499:
+		return port.UploadStatus{}, fmt.Errorf("%w: %s", port.ErrSessionNotFound, sessionURI)
+	default:
+		return port.UploadStatus{}, withStatus(resp.StatusCode, fmt.Errorf("gcs: resumable upload status: status %d", resp.StatusCode))
+	}
+}
+
+// NewWriter streams the object to GCS. The upload is bound to a context that
+// Abort cancels, which makes GCS discard it.
+func (g *GCSStorage) NewWriter(ctx context.Context, object, contentType string) (port.ObjectWriter, error) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	w := g.client.Bucket(g.bucket).Object(object).NewWriter(ctx)
+	w.ContentType = contentType
+	return &cancelWriter{Writer: w, cancel: cancel}, nil
+}
+
+func (g *GCSStorage) Head(ctx context.Context, object string) (port.ObjectAttrs, bool, error) {
 	attrs, err := g.client.Bucket(g.bucket).Object(object).Attrs(ctx)
 	if err != nil {
 		if errors.Is(err, storage.ErrObjectNotExist) {
-			return false, 0, "", nil
+			return port.ObjectAttrs{}, false, nil
+		}
+		return port.ObjectAttrs{}, false, fromGCS(err)
+	}
+	res := port.ObjectAttrs{
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Checksums:   port.Checksums{CRC32C: checksum.EncodeCRC32C(attrs.CRC32C)},
+	}
+	// Composite objects have no MD5.
+	if len(attrs.MD5) > 0 {
+		res.Checksums.MD5 = base64.StdEncoding.EncodeToString(attrs.MD5)
+	}
+	return res, true, nil
+}
+
+// Read streams the range from GCS. The returned reader is bound to a context
+// derived from ctx that is cancelled on Close.
+func (g *GCSStorage) Read(ctx context.Context, object string, offset, length int64) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	r, err := g.client.Bucket(g.bucket).Object(object).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		cancel()
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", port.ErrObjectNotFound, object)
 		}
-		return false, 0, "", err
+		return nil, fromGCS(err)
+	}
+	return &cancelReader{Reader: r, cancel: cancel}, nil
+}
+
+func (g *GCSStorage) Delete(ctx context.Context, object string) error {
+	err := g.client.Bucket(g.bucket).Object(object).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	}
+	return fromGCS(err)
+}
+
+type cancelReader struct {
+	*storage.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Close() error {
+	defer r.cancel()
+	return r.Reader.Close()
+}
+
+type cancelWriter struct {
+	*storage.Writer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Close() error {
+	defer w.cancel()
+	return fromGCS(w.Writer.Close())
+}
+
+func (w *cancelWriter) Abort() error {
+	w.cancel()
+	// Close reports the cancellation, which is what was asked for.
+	if err := w.Writer.Close(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
 	}
-	return true, attrs.Size, attrs.ContentType, nil
+	return nil
 }
@@ -0,0 +1,31 @@
+package gcp_test
+
+import (
+	"net/http"
+	"testing"
+
+	"cv-platform/internal/adapter/gcp"
+	"cv-platform/internal/adapter/gcp/gcstest"
+	"cv-platform/internal/port/porttest"
+)
+
+func TestGCSStorageConformance(t *testing.T) {
+	porttest.RunBlobStorageConformance(t, func(t *testing.T) porttest.BlobStorageHarness {
+		srv := gcstest.NewServer("cvs")
+		t.Cleanup(srv.Close)
+		cl, err := srv.Client(t.Context())
+		if err != nil {
+			t.Fatalf("gcstest client: %v", err)
+		}
+		st := gcp.NewGCSStorageFromClient(cl, "cvs",
+			gcp.WithSigner(srv.AccessID, srv.PrivateKey),
+			gcp.WithSignedURLHost(srv.Host(), true))
+		return porttest.BlobStorageHarness{
+			Storage: st,
+			Seed: func(t *testing.T, object string, data []byte, contentType string) {
+				srv.Put(object, data, contentType)
+			},
+			Client: http.DefaultClient,
+		}
+	})
+}
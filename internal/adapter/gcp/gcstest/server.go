@@ -0,0 +1,621 @@
+// Package gcstest provides an in-process fake of the parts of Google Cloud
+// Storage used by gcp.GCSStorage: the JSON metadata API and multipart uploads,
+// XML object reads, V4 signed URLs, V4 POST policies and XML API resumable
+// uploads. Signed requests are verified exactly like
+// GCS does, so a URL signed for the wrong method, content type or after its
+// expiry is rejected, as is a form upload breaking its policy conditions or an
+// upload whose content does not match its Content-MD5 or X-Goog-Hash headers.
+// Unsigned XML reads are treated as coming from the (unauthenticated) client
+// returned by Server.Client.
+package gcstest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/resumable"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// AccessID is the service account the fake server trusts for signed URLs.
+const AccessID = "fake-signer@cv-platform-test.iam.gserviceaccount.com"
+
+// Object is a stored object. MD5 and CRC32C are base64 encoded digests of
+// Data, as GCS reports them.
+type Object struct {
+	Data        []byte
+	ContentType string
+	MD5         string
+	CRC32C      string
+	Updated     time.Time
+}
+
+// Server is a fake GCS endpoint for a single bucket.
+type Server struct {
+	Bucket string
+	// AccessID and PrivateKey (PEM) must be used to sign URLs for this server.
+	AccessID   string
+	PrivateKey []byte
+
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	now func() time.Time
+
+	mu       sync.RWMutex
+	objects  map[string]Object
+	sessions map[string]*session
+}
+
+// session is a resumable upload started through a signed POST carrying
+// "x-goog-resumable: start".
+type session struct {
+	name        string
+	contentType string
+	// minSize and maxSize come from x-goog-content-length-range; maxSize 0
+	// means unbounded.
+	minSize, maxSize int64
+	data             []byte
+	complete         bool
+}
+
+// NewServer starts a fake GCS server for bucket. Call Close when done.
+func NewServer(bucket string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("gcstest: generate key: %v", err))
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	s := &Server{
+		Bucket:     bucket,
+		AccessID:   AccessID,
+		PrivateKey: pemKey,
+		key:        key,
+		now:        time.Now,
+		objects:    make(map[string]Object),
+		sessions:   make(map[string]*session),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() { s.srv.Close() }
+
+// URL is the base URL of the server, e.g. http://127.0.0.1:1234.
+func (s *Server) URL() string { return s.srv.URL }
+
+// Host is the host:port of the server, to be used as signed URL hostname.
+func (s *Server) Host() string { return strings.TrimPrefix(s.srv.URL, "http://") }
+
+// Client returns an unauthenticated storage client talking to the server.
+func (s *Server) Client(ctx context.Context) (*storage.Client, error) {
+	return storage.NewClient(ctx,
+		option.WithEndpoint(s.srv.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+	)
+}
+
+// Put stores an object directly, bypassing signed URLs.
+func (s *Server) Put(name string, data []byte, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	md5Sum, crc32cSum := checksum.Sum(data)
+	s.objects[name] = Object{
+		Data:        append([]byte(nil), data...),
+		ContentType: contentType,
+		MD5:         md5Sum,
+		CRC32C:      crc32cSum,
+		Updated:     s.now(),
+	}
+}
+
+// Get returns the object stored under name.
+func (s *Server) Get(name string) (Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[name]
+	return obj, ok
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+		s.serveJSON(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/upload/storage/v1/") {
+		s.serveUpload(w, r)
+		return
+	}
+	s.serveSigned(w, r)
+}
+
+// serveJSON implements objects.get and objects.delete of the JSON API.
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request) {
+	prefix := "/storage/v1/b/" + s.Bucket + "/o/"
+	if !strings.HasPrefix(r.URL.EscapedPath(), prefix) {
+		writeJSONError(w, http.StatusNotImplemented, "unsupported request")
+		return
+	}
+	name, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), prefix))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "bad object name")
+		return
+	}
+	obj, ok := s.Get(name)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "No such object: "+s.Bucket+"/"+name)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, name)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		writeJSONError(w, http.StatusNotImplemented, "unsupported request")
+		return
+	}
+	s.writeObject(w, name, obj)
+}
+
+// serveUpload implements objects.insert with uploadType=multipart, as used by
+// storage.Writer for objects smaller than its chunk size.
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.EscapedPath() != "/upload/storage/v1/b/"+s.Bucket+"/o" ||
+		r.URL.Query().Get("uploadType") != "multipart" {
+		writeJSONError(w, http.StatusNotImplemented, "unsupported upload")
+		return
+	}
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		writeJSONError(w, http.StatusBadRequest, "expected a multipart/related body")
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	var meta struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+	}
+	part, err := mr.NextPart()
+	if err == nil {
+		err = json.NewDecoder(part).Decode(&meta)
+	}
+	if err == nil {
+		part, err = mr.NextPart()
+	}
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(part)
+	}
+	if err != nil || meta.Name == "" {
+		writeJSONError(w, http.StatusBadRequest, "bad multipart upload")
+		return
+	}
+	if meta.ContentType == "" {
+		meta.ContentType = part.Header.Get("Content-Type")
+	}
+	s.Put(meta.Name, data, meta.ContentType)
+	obj, _ := s.Get(meta.Name)
+	s.writeObject(w, meta.Name, obj)
+}
+
+// writeObject writes the JSON API resource of obj.
+func (s *Server) writeObject(w http.ResponseWriter, name string, obj Object) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"kind":        "storage#object",
+		"bucket":      s.Bucket,
+		"name":        name,
+		"size":        strconv.Itoa(len(obj.Data)),
+		"contentType": obj.ContentType,
+		"md5Hash":     obj.MD5,
+		"crc32c":      obj.CRC32C,
+		"updated":     obj.Updated.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// serveSigned handles requests made to V4 signed URLs (path style).
+func (s *Server) serveSigned(w http.ResponseWriter, r *http.Request) {
+	prefix := "/" + s.Bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeXMLError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+	if name == "" && r.Method == http.MethodPost {
+		s.servePost(w, r)
+		return
+	}
+	if r.URL.Query().Has("upload_id") {
+		s.serveResumable(w, r)
+		return
+	}
+	if !r.URL.Query().Has("X-Goog-Algorithm") && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		s.serveRead(w, r, name)
+		return
+	}
+	if code, reason := s.verify(r); code != 0 {
+		writeXMLError(w, code, reason)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeXMLError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		if !digestsMatch(r.Header, data) {
+			writeXMLError(w, http.StatusBadRequest, "BadDigest")
+			return
+		}
+		s.Put(name, data, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPost:
+		if r.Header.Get("X-Goog-Resumable") != "start" {
+			writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		sess := &session{name: name, contentType: r.Header.Get("Content-Type")}
+		if v := r.Header.Get("X-Goog-Content-Length-Range"); v != "" {
+			lo, hi, _ := strings.Cut(v, ",")
+			var err1, err2 error
+			sess.minSize, err1 = strconv.ParseInt(strings.TrimSpace(lo), 10, 64)
+			sess.maxSize, err2 = strconv.ParseInt(strings.TrimSpace(hi), 10, 64)
+			if err1 != nil || err2 != nil || sess.minSize > sess.maxSize {
+				writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+				return
+			}
+		}
+		id := rand.Text()
+		s.mu.Lock()
+		s.sessions[id] = sess
+		s.mu.Unlock()
+		w.Header().Set("Location", s.srv.URL+(&url.URL{Path: r.URL.Path, RawQuery: "upload_id=" + id}).String())
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		obj, ok := s.Get(name)
+		if !ok {
+			writeXMLError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.Data)))
+		if v := r.URL.Query().Get("response-content-disposition"); v != "" {
+			w.Header().Set("Content-Disposition", v)
+		}
+		_, _ = w.Write(obj.Data)
+	default:
+		writeXMLError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// serveResumable handles chunks and status queries sent to a session URI.
+// Like GCS, it answers 308 with the persisted range while the upload is
+// incomplete and 200 once the object exists.
+func (s *Server) serveResumable(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		s.mu.Lock()
+		delete(s.sessions, r.URL.Query().Get("upload_id"))
+		s.mu.Unlock()
+		w.WriteHeader(499)
+		return
+	}
+	if r.Method != http.MethodPut {
+		writeXMLError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+		return
+	}
+	cr, err := resumable.ParseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[r.URL.Query().Get("upload_id")]
+	if !ok {
+		writeXMLError(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	if !sess.complete {
+		if sess.maxSize > 0 && (cr.Total > sess.maxSize || cr.HasData && cr.Last >= sess.maxSize) {
+			writeXMLError(w, http.StatusBadRequest, "EntityTooLarge")
+			return
+		}
+		buf := bytes.NewBuffer(sess.data)
+		received, err := cr.Append(buf, r.Body, int64(len(sess.data)))
+		sess.data = buf.Bytes()
+		if err != nil {
+			writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		if !cr.Complete(received) {
+			if rng := resumable.RangeHeader(received); rng != "" {
+				w.Header().Set("Range", rng)
+			}
+			w.WriteHeader(resumable.StatusIncomplete)
+			return
+		}
+		if received < sess.minSize {
+			writeXMLError(w, http.StatusBadRequest, "EntityTooSmall")
+			return
+		}
+		md5Sum, crc32cSum := checksum.Sum(sess.data)
+		s.objects[sess.name] = Object{
+			Data:        sess.data,
+			ContentType: sess.contentType,
+			MD5:         md5Sum,
+			CRC32C:      crc32cSum,
+			Updated:     s.now(),
+		}
+		sess.complete = true
+	}
+	w.Header().Set("X-Goog-Stored-Content-Length", strconv.Itoa(len(sess.data)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveRead serves a client read, honouring Range headers like the XML API.
+func (s *Server) serveRead(w http.ResponseWriter, r *http.Request, name string) {
+	obj, ok := s.Get(name)
+	if !ok {
+		writeXMLError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("X-Goog-Generation", strconv.FormatInt(obj.Updated.UnixMicro(), 10))
+	http.ServeContent(w, r, "", obj.Updated, bytes.NewReader(obj.Data))
+}
+
+// verify checks a V4 signed request. It returns a non-zero status and an
+// error code when the request must be rejected.
+func (s *Server) verify(r *http.Request) (int, string) {
+	q := r.URL.Query()
+	if q.Get("X-Goog-Algorithm") != "GOOG4-RSA-SHA256" {
+		return http.StatusBadRequest, "MissingSecurityHeader"
+	}
+	credential := q.Get("X-Goog-Credential")
+	if !strings.HasPrefix(credential, s.AccessID+"/") {
+		return http.StatusForbidden, "AccessDenied"
+	}
+	date, err := time.Parse("20060102T150405Z", q.Get("X-Goog-Date"))
+	if err != nil {
+		return http.StatusBadRequest, "AuthorizationQueryParametersError"
+	}
+	expires, err := strconv.Atoi(q.Get("X-Goog-Expires"))
+	if err != nil {
+		return http.StatusBadRequest, "AuthorizationQueryParametersError"
+	}
+	if !s.now().Before(date.Add(time.Duration(expires) * time.Second)) {
+		return http.StatusBadRequest, "ExpiredToken"
+	}
+	sig, err := hex.DecodeString(q.Get("X-Goog-Signature"))
+	if err != nil {
+		return http.StatusBadRequest, "InvalidSignature"
+	}
+
+	signedHeaders := q.Get("X-Goog-SignedHeaders")
+	canonicalQuery := url.Values{}
+	for k, v := range q {
+		if k != "X-Goog-Signature" {
+			canonicalQuery[k] = v
+		}
+	}
+
+	var headers []string
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var v string
+		if name == "host" {
+			v = hostname(r.Host)
+		} else {
+			v = r.Header.Get(name)
+		}
+		headers = append(headers, name+":"+strings.Join(strings.Fields(v), " "))
+	}
+	sort.Strings(headers)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s\n", r.Method)
+	fmt.Fprintf(buf, "%s\n", r.URL.EscapedPath())
+	fmt.Fprintf(buf, "%s\n", strings.ReplaceAll(canonicalQuery.Encode(), "+", "%20"))
+	fmt.Fprintf(buf, "%s\n\n", strings.Join(headers, "\n"))
+	fmt.Fprintf(buf, "%s\n", signedHeaders)
+	fmt.Fprint(buf, "UNSIGNED-PAYLOAD")
+
+	reqHash := sha256.Sum256(buf.Bytes())
+	scope := strings.TrimPrefix(credential, s.AccessID+"/")
+	toSign := fmt.Sprintf("GOOG4-RSA-SHA256\n%s\n%s\n%s", q.Get("X-Goog-Date"), scope, hex.EncodeToString(reqHash[:]))
+	sum := sha256.Sum256([]byte(toSign))
+	if err := rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		return http.StatusForbidden, "SignatureDoesNotMatch"
+	}
+	return 0, ""
+}
+
+// servePost handles a multipart form upload made with a V4 POST policy.
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+	fields := map[string]string{}
+	var data []byte
+	for {
+		part, err := mr.NextPart()
+		if err != nil { // including io.EOF: the file part is mandatory
+			writeXMLError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		b, err := io.ReadAll(part)
+		if err != nil {
+			writeXMLError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		if part.FormName() == "file" {
+			data = b
+			break
+		}
+		fields[strings.ToLower(part.FormName())] = string(b)
+	}
+
+	if code, reason := s.verifyPolicy(fields, int64(len(data))); code != 0 {
+		writeXMLError(w, code, reason)
+		return
+	}
+	s.Put(fields["key"], data, fields["content-type"])
+
+	code := http.StatusNoContent
+	if v, err := strconv.Atoi(fields["success_action_status"]); err == nil {
+		code = v
+	}
+	w.WriteHeader(code)
+}
+
+// verifyPolicy checks the signature and the conditions of a POST policy
+// against the submitted form fields and file size. Like GCS, it requires every
+// submitted field to be covered by a condition.
+func (s *Server) verifyPolicy(fields map[string]string, size int64) (int, string) {
+	if fields["x-goog-algorithm"] != "GOOG4-RSA-SHA256" {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	if !strings.HasPrefix(fields["x-goog-credential"], s.AccessID+"/") {
+		return http.StatusForbidden, "AccessDenied"
+	}
+	sig, err := hex.DecodeString(fields["x-goog-signature"])
+	if err != nil {
+		return http.StatusBadRequest, "InvalidSignature"
+	}
+	sum := sha256.Sum256([]byte(fields["policy"]))
+	if err := rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		return http.StatusForbidden, "SignatureDoesNotMatch"
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(fields["policy"])
+	if err != nil {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	var policy struct {
+		Expiration time.Time         `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	if !s.now().Before(policy.Expiration) {
+		return http.StatusBadRequest, "ExpiredToken"
+	}
+
+	covered := map[string]bool{"policy": true, "x-goog-signature": true, "file": true}
+	for _, c := range policy.Conditions {
+		var exact map[string]string
+		if json.Unmarshal(c, &exact) == nil {
+			for k, v := range exact {
+				got := fields[strings.ToLower(k)]
+				if k == "bucket" {
+					got = s.Bucket
+				}
+				if got != v {
+					return http.StatusForbidden, "AccessDenied"
+				}
+				covered[strings.ToLower(k)] = true
+			}
+			continue
+		}
+		var op []any
+		if err := json.Unmarshal(c, &op); err != nil || len(op) != 3 {
+			return http.StatusBadRequest, "InvalidPolicyDocument"
+		}
+		switch name, _ := op[0].(string); name {
+		case "content-length-range":
+			lo, _ := op[1].(float64)
+			hi, _ := op[2].(float64)
+			if size < int64(lo) {
+				return http.StatusBadRequest, "EntityTooSmall"
+			}
+			if size > int64(hi) {
+				return http.StatusBadRequest, "EntityTooLarge"
+			}
+		case "eq", "starts-with":
+			field, _ := op[1].(string)
+			want, _ := op[2].(string)
+			field = strings.ToLower(strings.TrimPrefix(field, "$"))
+			got := fields[field]
+			if name == "eq" && got != want || name == "starts-with" && !strings.HasPrefix(got, want) {
+				return http.StatusForbidden, "AccessDenied"
+			}
+			covered[field] = true
+		default:
+			return http.StatusBadRequest, "InvalidPolicyDocument"
+		}
+	}
+	for k := range fields {
+		if !covered[k] && !strings.HasPrefix(k, "x-ignore-") {
+			return http.StatusForbidden, "AccessDenied"
+		}
+	}
+	return 0, ""
+}
+
+// digestsMatch checks data against the Content-MD5 and X-Goog-Hash headers
+// of an upload, when present.
+func digestsMatch(h http.Header, data []byte) bool {
+	md5Sum, crc32cSum := checksum.Sum(data)
+	hashMD5, hashCRC32C := checksum.ParseHashHeader(h.Values(checksum.HeaderHash))
+	for _, want := range []string{h.Get(checksum.HeaderMD5), hashMD5} {
+		if want != "" && want != md5Sum {
+			return false
+		}
+	}
+	return hashCRC32C == "" || hashCRC32C == crc32cSum
+}
+
+func hostname(hostport string) string {
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		return h
+	}
+	return hostport
+}
+
+func writeJSONError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": code, "message": msg},
+	})
+}
+
+func writeXMLError(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "<?xml version='1.0' encoding='UTF-8'?><Error><Code>%s</Code></Error>", reason)
+}
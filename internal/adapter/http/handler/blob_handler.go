@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/localfs"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/resumable"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFormFieldLen caps non-file form fields of POST policy uploads.
+const maxFormFieldLen = 16 << 10
+
+// BlobStore is a storage serving its own signed URLs through BlobHandler, as
+// localfs.Storage does.
+type BlobStore interface {
+	// Verify checks a signed PUT or GET request for object.
+	Verify(method, object string, h http.Header, q url.Values) error
+	// VerifyPost checks the fields of a signed POST policy form.
+	VerifyPost(fields map[string]string) (*localfs.PostConditions, error)
+	// PutChecked stores object unless it does not match want.
+	PutChecked(object string, r io.Reader, contentType string, want port.Checksums) (int64, error)
+	// PutWithin stores object unless its size is out of [minSize, maxSize].
+	PutWithin(object string, r io.Reader, contentType string, minSize, maxSize int64) (int64, error)
+	// WriteChunk appends a chunk to a resumable upload session.
+	WriteChunk(object, uploadID, contentRange string, body io.Reader) (port.UploadStatus, error)
+	// Open opens object for reading.
+	Open(object string) (*os.File, *localfs.ObjectInfo, error)
+}
+
+// BlobHandler serves the signed upload/download URLs issued by a BlobStore.
+type BlobHandler struct {
+	store BlobStore
+}
+
+func NewBlobHandler(store BlobStore) *BlobHandler {
+	return &BlobHandler{store: store}
+}
+
+func (h *BlobHandler) Upload(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	key := c.Param("key")
+	ctype := c.GetHeader("Content-Type")
+
+	if id := c.Query(localfs.ParamUploadID); id != "" {
+		h.uploadChunk(c, key, id)
+		return
+	}
+
+	if err := h.store.Verify(http.MethodPut, key, c.Request.Header, c.Request.URL.Query()); err != nil {
+		log.Warnf("rejected blob upload: key=%s, err=%v", key, err)
+		response.RespondErr(c, err)
+		return
+	}
+
+	body := c.Request.Body
+	if max, err := strconv.ParseInt(c.Query(localfs.ParamMaxSize), 10, 64); err == nil && max > 0 {
+		body = http.MaxBytesReader(c.Writer, body, max)
+	}
+	n, err := h.store.PutChecked(key, body, ctype, localfs.HeaderChecksums(c.Request.Header))
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		err = fmt.Errorf("%w: at most %d bytes", localfs.ErrTooLarge, tooLarge.Limit)
+	}
+	if err != nil {
+		log.Errorf("failed to store blob %s: %v", key, err)
+		respondBlobErr(c, err)
+		return
+	}
+
+	log.Infof("blob stored: key=%s, size=%d, type=%s", key, n, ctype)
+	c.Status(http.StatusOK)
+}
+
+// uploadChunk serves a PUT to a resumable session URI: it answers 308 with the
+// received Range until the announced total has arrived, then 200.
+func (h *BlobHandler) uploadChunk(c *gin.Context, key, uploadID string) {
+	log := middleware.SimpleLoggerFromContext(c)
+
+	st, err := h.store.WriteChunk(key, uploadID, c.GetHeader("Content-Range"), c.Request.Body)
+	if err != nil {
+		log.Warnf("failed to write chunk of blob %s: %v", key, err)
+		respondBlobErr(c, err)
+		return
+	}
+	if st.Complete {
+		log.Infof("blob stored: key=%s, size=%d", key, st.Received)
+		c.Status(http.StatusOK)
+		return
+	}
+	if r := resumable.RangeHeader(st.Received); r != "" {
+		c.Header("Range", r)
+	}
+	c.Status(resumable.StatusIncomplete)
+}
+
+// PostUpload handles multipart form uploads made with a POST policy. Policy
+// fields must precede the "file" part, as with GCS.
+func (h *BlobHandler) PostUpload(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		response.RespondBadRequest(c, "expected a multipart/form-data body")
+		return
+	}
+	fields := map[string]string{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			response.RespondBadRequest(c, "missing file part")
+			return
+		}
+		if err != nil {
+			response.RespondBadRequest(c, err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			v, err := io.ReadAll(io.LimitReader(part, maxFormFieldLen))
+			if err != nil {
+				response.RespondBadRequest(c, err.Error())
+				return
+			}
+			fields[strings.ToLower(part.FormName())] = string(v)
+			continue
+		}
+
+		pc, err := h.store.VerifyPost(fields)
+		if err != nil {
+			log.Warnf("rejected blob form upload: key=%s, err=%v", fields[localfs.FieldKey], err)
+			response.RespondErr(c, err)
+			return
+		}
+		ctype := fields[localfs.FieldContentType]
+		n, err := h.store.PutWithin(pc.Key, part, ctype, pc.MinSize, pc.MaxSize)
+		if err != nil {
+			log.Warnf("failed to store blob %s: %v", pc.Key, err)
+			response.RespondErr(c, err)
+			return
+		}
+		log.Infof("blob stored: key=%s, size=%d, type=%s", pc.Key, n, ctype)
+		c.Status(http.StatusNoContent)
+		return
+	}
+}
+
+func (h *BlobHandler) Download(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	key := c.Param("key")
+
+	if err := h.store.Verify(http.MethodGet, key, nil, c.Request.URL.Query()); err != nil {
+		log.Warnf("rejected blob download: key=%s, err=%v", key, err)
+		response.RespondErr(c, err)
+		return
+	}
+
+	f, info, err := h.store.Open(key)
+	if err != nil {
+		log.Warnf("failed to open blob %s: %v", key, err)
+		response.RespondErr(c, err)
+		return
+	}
+	defer f.Close()
+
+	ctype := info.ContentType
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	c.Header("Content-Type", ctype)
+	c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	if v := c.Query(localfs.ParamContentDisposition); v != "" {
+		c.Header("Content-Disposition", v)
+	}
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, f); err != nil {
+		log.Errorf("failed to stream blob %s: %v", key, err)
+	}
+}
+
+// respondBlobErr answers uploads past their signed maximum size with 413 and
+// other errors by their kind.
+func respondBlobErr(c *gin.Context, err error) {
+	if errors.Is(err, localfs.ErrTooLarge) {
+		response.RespondError(c, http.StatusRequestEntityTooLarge, response.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	response.RespondErr(c, err)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/usecase"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CVDownloadHandler struct {
+	uc *usecase.CVDownloadUC
+}
+
+func NewCVDownloadHandler(uc *usecase.CVDownloadUC) *CVDownloadHandler {
+	return &CVDownloadHandler{uc: uc}
+}
+
+// Download redirects to a signed GET URL of the CV or streams it, as chosen
+// by the mode query parameter (redirect or stream) or the configured default.
+func (h *CVDownloadHandler) Download(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	id := c.Param("id")
+
+	mode := usecase.DownloadMode(strings.ToLower(c.Query("mode")))
+	if mode != "" && !mode.Valid() {
+		response.RespondValidationErr(c, "invalid download request",
+			response.FieldError{Field: "mode", Message: "must be redirect or stream"})
+		return
+	}
+
+	res, err := h.uc.Download(c.Request.Context(), usecase.DownloadCmd{
+		ID:          id,
+		RequesterID: middleware.OwnerIDFromContext(c),
+		Mode:        mode,
+	})
+	if err != nil {
+		respondErr(c, err, "failed to download cv %s", id)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	if res.Mode == usecase.DownloadRedirect {
+		c.Redirect(http.StatusFound, res.URL)
+		return
+	}
+
+	defer res.Body.Close()
+	c.Header("Content-Type", res.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(res.Size, 10))
+	c.Header("Content-Disposition", res.ContentDisposition)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, res.Body); err != nil {
+		log.Errorf("failed to stream cv %s: %v", id, err)
+	}
+}
@@ -0,0 +1,64 @@
+package handler_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+func TestDownload(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		req        request
+		wantStatus int
+	}{
+		{name: "default mode", path: "/api/v1/cvs/cv-1/download", wantStatus: http.StatusFound},
+		{name: "redirect", path: "/api/v1/cvs/cv-1/download?mode=redirect", wantStatus: http.StatusFound},
+		{name: "stream", path: "/api/v1/cvs/cv-1/download?mode=STREAM", wantStatus: http.StatusOK},
+		{name: "unknown mode", path: "/api/v1/cvs/cv-1/download?mode=inline", wantStatus: http.StatusBadRequest},
+		{name: "anonymous", path: "/api/v1/cvs/cv-1/download", req: request{Anonymous: true}, wantStatus: http.StatusUnauthorized},
+		{name: "other owner", path: "/api/v1/cvs/cv-1/download", req: request{As: "bob"}, wantStatus: http.StatusForbidden},
+		{name: "pending", path: "/api/v1/cvs/cv-pending/download", wantStatus: http.StatusConflict},
+		{name: "unknown cv", path: "/api/v1/cvs/nope/download", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			cv := s.uploaded(t, "cv-1")
+			s.seed(t, domain.CV{ID: "cv-pending", GCSPath: "cv/cv-pending.pdf", Status: domain.CVStatusPending})
+
+			tt.req.Method, tt.req.Path = http.MethodGet, tt.path
+			w := s.do(t, tt.req)
+			wantStatus(t, w, tt.wantStatus)
+
+			switch tt.wantStatus {
+			case http.StatusFound:
+				if loc := w.Header().Get("Location"); !strings.Contains(loc, cv.GCSPath) {
+					t.Fatalf("Location: got %q, want a signed URL of %s", loc, cv.GCSPath)
+				}
+			case http.StatusOK:
+				if got := w.Body.String(); got != string(pdf) {
+					t.Fatalf("body: got %q, want %q", got, pdf)
+				}
+				for header, want := range map[string]string{
+					"Content-Type":           "application/pdf",
+					"Content-Length":         strconv.Itoa(len(pdf)),
+					"Content-Disposition":    "attachment; filename=cv-1.pdf",
+					"X-Content-Type-Options": "nosniff",
+				} {
+					if got := w.Header().Get(header); got != want {
+						t.Errorf("%s: got %q, want %q", header, got, want)
+					}
+				}
+			}
+			if w.Code < http.StatusBadRequest && w.Header().Get("Cache-Control") != "private, no-store" {
+				t.Fatalf("Cache-Control: got %q, want private, no-store", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
@@ -19,15 +19,26 @@ func NewCVHandler(uc *usecase.CVUploadUC) *CVHandler {
 }
 
 type startReq struct {
-	FileName string `json:"file_name" biding:"required"`
-	MimeType string `json:"mime_type" biding:"required"`
+	FileName string `json:"file_name" binding:"required"`
+	MimeType string `json:"mime_type" binding:"required"`
+	Size     int64  `json:"size" binding:"min=0"`
+	// UploadMethod selects a signed PUT URL (default), a POST policy form or
+	// a resumable session.
+	UploadMethod string `json:"upload_method" binding:"omitempty,oneof=PUT POST RESUMABLE put post resumable"`
+	// MD5 and CRC32C are optional digests of the file, base64 or hex.
+	MD5    string `json:"md5"`
+	CRC32C string `json:"crc32c"`
 }
 
 type startResp struct {
-	ID        string    `json:"id"`
-	ObjectKey string    `json:"object_key"`
-	SignedURL string    `json:"signed_url"`
-	ExpiredAt time.Time `json:"expired_at"`
+	ID         string            `json:"id"`
+	ObjectKey  string            `json:"object_key"`
+	Method     string            `json:"method"`
+	SignedURL  string            `json:"signed_url"`
+	FormFields map[string]string `json:"form_fields,omitempty"`
+	SessionURI string            `json:"session_uri,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	ExpiredAt  time.Time         `json:"expired_at"`
 }
 
 func (h *CVHandler) StartUpload(c *gin.Context) {
@@ -38,7 +49,7 @@ func (h *CVHandler) StartUpload(c *gin.Context) {
 	var req startReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Warnf("validation failed: %v", err)
-		response.RespondValidationErr(c, err.Error())
+		response.RespondValidationErr(c, "invalid upload request", bindingFieldErrors(err)...)
 		return
 	}
 
@@ -47,18 +58,26 @@ func (h *CVHandler) StartUpload(c *gin.Context) {
 	res, err := h.uc.StartUpload(c.Request.Context(), usecase.StartUploadCmd{
 		FileName: req.FileName,
 		MimeType: req.MimeType,
+		Size:     req.Size,
+		Method:   req.UploadMethod,
+		OwnerID:  middleware.OwnerIDFromContext(c),
+		MD5:      req.MD5,
+		CRC32C:   req.CRC32C,
 	})
 	if err != nil {
-		log.Errorf("failed to start upload for file %s: %v", req.FileName, err)
-		response.RespondInternalErr(c, err.Error())
+		respondErr(c, err, "failed to start upload for file %s", req.FileName)
 		return
 	}
 
 	resp := startResp{
-		ID:        res.ID,
-		ObjectKey: res.ObjectKey,
-		SignedURL: res.SignedURL,
-		ExpiredAt: res.ExpiredAt,
+		ID:         res.ID,
+		ObjectKey:  res.ObjectKey,
+		Method:     res.Method,
+		SignedURL:  res.SignedURL,
+		FormFields: res.FormFields,
+		SessionURI: res.SessionURI,
+		Headers:    res.Headers,
+		ExpiredAt:  res.ExpiredAt,
 	}
 
 	log.Infof("upload started successfully: id=%s, expires_at=%v", res.ID, res.ExpiredAt)
@@ -67,11 +86,15 @@ func (h *CVHandler) StartUpload(c *gin.Context) {
 }
 
 type completeResp struct {
-	ID       string `json:"id"`
-	Status   string `json:"status"`
-	Size     int64  `json:"size"`
-	MimeType string `json:"mime_type"`
-	GCSPath  string `json:"gcs_path"`
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	Size             int64  `json:"size"`
+	MimeType         string `json:"mime_type"`
+	DetectedMimeType string `json:"detected_mime_type"`
+	GCSPath          string `json:"gcs_path"`
+	MD5              string `json:"md5"`
+	CRC32C           string `json:"crc32c"`
+	DuplicateOf      string `json:"duplicate_of,omitempty"`
 }
 
 func (h *CVHandler) CompleteUpload(c *gin.Context) {
@@ -80,22 +103,54 @@ func (h *CVHandler) CompleteUpload(c *gin.Context) {
 
 	log.Infof("completing upload request for id: %s", id)
 
-	cv, err := h.uc.CompleteUpload(c.Request.Context(), usecase.CompleteUploadCmd{ID: id})
+	cv, err := h.uc.CompleteUpload(c.Request.Context(), usecase.CompleteUploadCmd{
+		ID:          id,
+		RequesterID: middleware.OwnerIDFromContext(c),
+	})
 	if err != nil {
-		log.Errorf("failed to complete upload for id %s: %v", id, err)
-		response.RespondBadRequest(c, err.Error())
+		respondErr(c, err, "failed to complete upload for id %s", id)
 		return
 	}
 
 	resp := completeResp{
-		ID:       cv.ID,
-		Status:   string(cv.Status),
-		Size:     cv.Size,
-		MimeType: cv.MimeType,
-		GCSPath:  cv.GCSPath,
+		ID:               cv.ID,
+		Status:           string(cv.Status),
+		Size:             cv.Size,
+		MimeType:         cv.MimeType,
+		DetectedMimeType: cv.DetectedMimeType,
+		GCSPath:          cv.GCSPath,
+		MD5:              cv.MD5,
+		CRC32C:           cv.CRC32C,
+		DuplicateOf:      cv.DuplicateOf,
 	}
 
 	log.Infof("upload completed successfully: id=%s, status=%s, size=%d", cv.ID, cv.Status, cv.Size)
 
 	response.RespondSuccess(c, http.StatusOK, resp)
 }
+
+type uploadStatusResp struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	Resumable bool   `json:"resumable"`
+	Received  int64  `json:"received"`
+	Complete  bool   `json:"complete"`
+}
+
+func (h *CVHandler) UploadStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	p, err := h.uc.UploadStatus(c.Request.Context(), id, middleware.OwnerIDFromContext(c))
+	if err != nil {
+		respondErr(c, err, "failed to get upload status for id %s", id)
+		return
+	}
+
+	response.RespondSuccess(c, http.StatusOK, uploadStatusResp{
+		ID:        p.ID,
+		Status:    string(p.Status),
+		Resumable: p.Resumable,
+		Received:  p.Received,
+		Complete:  p.Complete,
+	})
+}
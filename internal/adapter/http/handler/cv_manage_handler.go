@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CVManageHandler struct {
+	uc *usecase.CVManageUC
+}
+
+func NewCVManageHandler(uc *usecase.CVManageUC) *CVManageHandler {
+	return &CVManageHandler{uc: uc}
+}
+
+// cvDetailResp is the full metadata of a single CV.
+type cvDetailResp struct {
+	cvResp
+	RefCount        int        `json:"ref_count"`
+	UploadExpiresAt *time.Time `json:"upload_expires_at,omitempty"`
+}
+
+func (h *CVManageHandler) GetCV(c *gin.Context) {
+	id := c.Param("id")
+
+	cv, err := h.uc.Get(c.Request.Context(), usecase.GetCVCmd{
+		ID:          id,
+		RequesterID: middleware.OwnerIDFromContext(c),
+	})
+	if err != nil {
+		respondErr(c, err, "failed to get cv %s", id)
+		return
+	}
+
+	resp := cvDetailResp{cvResp: toCVResp(*cv), RefCount: cv.RefCount}
+	if cv.Status == domain.CVStatusPending && !cv.UploadExpiresAt.IsZero() {
+		resp.UploadExpiresAt = &cv.UploadExpiresAt
+	}
+	response.RespondSuccess(c, http.StatusOK, resp)
+}
+
+// DeleteCV soft-deletes a CV, or purges its record as well with purge=true.
+func (h *CVManageHandler) DeleteCV(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	id := c.Param("id")
+
+	var purge bool
+	if v := c.Query("purge"); v != "" {
+		var err error
+		if purge, err = strconv.ParseBool(v); err != nil {
+			response.RespondValidationErr(c, "invalid delete request",
+				response.FieldError{Field: "purge", Message: "must be a boolean"})
+			return
+		}
+	}
+
+	cv, err := h.uc.Delete(c.Request.Context(), usecase.DeleteCVCmd{
+		ID:          id,
+		RequesterID: middleware.OwnerIDFromContext(c),
+		Purge:       purge,
+	})
+	if err != nil {
+		respondErr(c, err, "failed to delete cv %s", id)
+		return
+	}
+
+	log.Infof("cv deleted: id=%s, status=%s, purged=%t", id, cv.Status, purge)
+	c.Status(http.StatusNoContent)
+}
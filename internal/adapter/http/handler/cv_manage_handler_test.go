@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+// cvDetail is the data of GET /cvs/:id.
+type cvDetail struct {
+	ID              string     `json:"id"`
+	FileName        string     `json:"file_name"`
+	Status          string     `json:"status"`
+	RefCount        int        `json:"ref_count"`
+	UploadExpiresAt *time.Time `json:"upload_expires_at"`
+}
+
+func TestGetCV(t *testing.T) {
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	tests := []struct {
+		name       string
+		id         string
+		req        request
+		wantStatus int
+		want       cvDetail
+	}{
+		{
+			name:       "uploaded",
+			id:         "cv-1",
+			wantStatus: http.StatusOK,
+			want:       cvDetail{ID: "cv-1", FileName: "cv-1.pdf", Status: "uploaded", RefCount: 1},
+		},
+		{
+			name:       "pending",
+			id:         "cv-pending",
+			wantStatus: http.StatusOK,
+			want:       cvDetail{ID: "cv-pending", FileName: "cv.pdf", Status: "pending", UploadExpiresAt: &expires},
+		},
+		{name: "anonymous", id: "cv-1", req: request{Anonymous: true}, wantStatus: http.StatusUnauthorized},
+		{name: "other owner", id: "cv-1", req: request{As: "bob"}, wantStatus: http.StatusForbidden},
+		{name: "unknown cv", id: "nope", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			cv := s.uploaded(t, "cv-1")
+			if _, err := s.repo.AddRef(t.Context(), cv.ID, 1); err != nil {
+				t.Fatalf("AddRef: %v", err)
+			}
+			s.seed(t, domain.CV{ID: "cv-pending", FileName: "cv.pdf", Status: domain.CVStatusPending, UploadExpiresAt: expires})
+
+			tt.req.Method, tt.req.Path = http.MethodGet, "/api/v1/cvs/"+tt.id
+			w := s.do(t, tt.req)
+			if wantStatus(t, w, tt.wantStatus); w.Code != http.StatusOK {
+				return
+			}
+			var got cvDetail
+			if resp := decode(t, w, &got); !resp.Success {
+				t.Fatalf("success: got false, want true")
+			}
+			if got.ID != tt.want.ID || got.FileName != tt.want.FileName || got.Status != tt.want.Status || got.RefCount != tt.want.RefCount ||
+				(got.UploadExpiresAt == nil) != (tt.want.UploadExpiresAt == nil) ||
+				got.UploadExpiresAt != nil && !got.UploadExpiresAt.Equal(*tt.want.UploadExpiresAt) {
+				t.Fatalf("GET: got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteCV(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		req        request
+		wantStatus int
+		// wantCV is the status the CV is left in, "" when it is gone.
+		wantCV domain.CVStatus
+	}{
+		{name: "soft delete", id: "cv-1", wantStatus: http.StatusNoContent, wantCV: domain.CVStatusDeleted},
+		{name: "purge", id: "cv-1", query: "?purge=true", wantStatus: http.StatusNoContent},
+		{name: "purge referenced", id: "cv-canonical", query: "?purge=1", wantStatus: http.StatusConflict, wantCV: domain.CVStatusUploaded},
+		{name: "invalid purge", id: "cv-1", query: "?purge=yes", wantStatus: http.StatusBadRequest, wantCV: domain.CVStatusUploaded},
+		{name: "anonymous", id: "cv-1", req: request{Anonymous: true}, wantStatus: http.StatusUnauthorized, wantCV: domain.CVStatusUploaded},
+		{name: "other owner", id: "cv-1", req: request{As: "bob"}, wantStatus: http.StatusForbidden, wantCV: domain.CVStatusUploaded},
+		{name: "unknown cv", id: "nope", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			s.uploaded(t, "cv-1")
+			canonical := s.uploaded(t, "cv-canonical")
+			if _, err := s.repo.AddRef(t.Context(), canonical.ID, 1); err != nil {
+				t.Fatalf("AddRef: %v", err)
+			}
+
+			tt.req.Method, tt.req.Path = http.MethodDelete, "/api/v1/cvs/"+tt.id+tt.query
+			wantStatus(t, s.do(t, tt.req), tt.wantStatus)
+
+			got, err := s.repo.FindByID(t.Context(), tt.id)
+			switch {
+			case tt.wantCV == "" && err == nil:
+				t.Fatalf("cv %s: got %s, want it gone", tt.id, got.Status)
+			case tt.wantCV != "" && (err != nil || got.Status != tt.wantCV):
+				t.Fatalf("cv %s: got %v, %v, want %s", tt.id, got, err, tt.wantCV)
+			}
+		})
+	}
+
+	t.Run("twice", func(t *testing.T) {
+		s := newServer(t, usecase.DefaultUploadPolicy())
+		cv := s.uploaded(t, "cv-1")
+		for range 2 {
+			wantStatus(t, s.do(t, request{Method: http.MethodDelete, Path: "/api/v1/cvs/" + cv.ID}), http.StatusNoContent)
+		}
+		if _, ok := s.storage.Get(cv.GCSPath); ok {
+			t.Fatalf("object %s kept", cv.GCSPath)
+		}
+	})
+}
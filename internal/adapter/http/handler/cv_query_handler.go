@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/internal/usecase"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CVQueryHandler struct {
+	uc *usecase.CVQueryUC
+}
+
+func NewCVQueryHandler(uc *usecase.CVQueryUC) *CVQueryHandler {
+	return &CVQueryHandler{uc: uc}
+}
+
+type listReq struct {
+	Limit       int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor      string    `form:"cursor"`
+	Status      string    `form:"status"`
+	MimeType    string    `form:"mime_type"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinSize     int64     `form:"min_size" binding:"omitempty,min=0"`
+	MaxSize     int64     `form:"max_size" binding:"omitempty,min=0"`
+	NamePrefix  string    `form:"name_prefix"`
+	Sort        string    `form:"sort" binding:"omitempty,oneof=created_at updated_at size"`
+	Order       string    `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+type cvResp struct {
+	ID               string    `json:"id"`
+	OwnerID          string    `json:"owner_id,omitempty"`
+	FileName         string    `json:"file_name"`
+	MimeType         string    `json:"mime_type"`
+	DetectedMimeType string    `json:"detected_mime_type,omitempty"`
+	Size             int64     `json:"size"`
+	GCSPath          string    `json:"gcs_path"`
+	MD5              string    `json:"md5,omitempty"`
+	CRC32C           string    `json:"crc32c,omitempty"`
+	DuplicateOf      string    `json:"duplicate_of,omitempty"`
+	Status           string    `json:"status"`
+	RejectReason     string    `json:"reject_reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type listResp struct {
+	Items      []cvResp `json:"items"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (h *CVQueryHandler) ListCVs(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+
+	var req listReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Warnf("validation failed: %v", err)
+		response.RespondValidationErr(c, "invalid list request", bindingFieldErrors(err)...)
+		return
+	}
+
+	res, err := h.uc.ListCVs(c.Request.Context(), usecase.ListCVsCmd{
+		RequesterID: middleware.OwnerIDFromContext(c),
+		Filter: port.CVFilter{
+			Status:         domain.CVStatus(req.Status),
+			MimeType:       req.MimeType,
+			CreatedFrom:    req.CreatedFrom,
+			CreatedTo:      req.CreatedTo,
+			MinSize:        req.MinSize,
+			MaxSize:        req.MaxSize,
+			FileNamePrefix: req.NamePrefix,
+		},
+		SortBy: port.CVSortField(req.Sort),
+		Order:  port.SortOrder(req.Order),
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		respondErr(c, err, "failed to list cvs")
+		return
+	}
+
+	resp := listResp{Items: make([]cvResp, 0, len(res.Items)), NextCursor: res.NextCursor}
+	for _, cv := range res.Items {
+		resp.Items = append(resp.Items, toCVResp(cv))
+	}
+
+	response.RespondSuccess(c, http.StatusOK, resp)
+}
+
+func toCVResp(cv domain.CV) cvResp {
+	return cvResp{
+		ID:               cv.ID,
+		OwnerID:          cv.OwnerID,
+		FileName:         cv.FileName,
+		MimeType:         cv.MimeType,
+		DetectedMimeType: cv.DetectedMimeType,
+		Size:             cv.Size,
+		GCSPath:          cv.GCSPath,
+		MD5:              cv.MD5,
+		CRC32C:           cv.CRC32C,
+		DuplicateOf:      cv.DuplicateOf,
+		Status:           string(cv.Status),
+		RejectReason:     cv.RejectReason,
+		CreatedAt:        cv.CreatedAt,
+		UpdatedAt:        cv.UpdatedAt,
+	}
+}
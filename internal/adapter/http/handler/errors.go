@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/domain"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondErr logs what failed and responds with response.RespondErr. Errors
+// the caller can fix are logged as warnings, the others as errors.
+func respondErr(c *gin.Context, err error, format string, args ...any) {
+	log := middleware.SimpleLoggerFromContext(c)
+	msg := fmt.Sprintf(format, args...)
+	switch domain.KindOf(err) {
+	case nil, domain.ErrUnavailable, domain.ErrPreconditionFailed:
+		log.Errorf("%s: %v", msg, err)
+	default:
+		log.Warnf("%s: %v", msg, err)
+	}
+	response.RespondErr(c, err)
+}
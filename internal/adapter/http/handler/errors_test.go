@@ -0,0 +1,126 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+// TestErrorResponses checks the status, error code and fields the endpoints
+// answer use case errors with.
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare sets up the server, e.g. injects failures.
+		prepare    func(t *testing.T, s *server)
+		req        request
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{
+			name:       "malformed request",
+			req:        request{Method: http.MethodPost, Path: "/api/v1/cvs/upload", Body: `{"mime_type": "application/pdf", "size": -1}`},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   response.ErrorCodeValidationFailed,
+			wantFields: []string{"file_name", "size"},
+		},
+		{
+			name:       "policy violation",
+			req:        request{Method: http.MethodPost, Path: "/api/v1/cvs/upload", Body: `{"file_name": "cv.exe", "mime_type": "application/pdf"}`},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   response.ErrorCodeValidationFailed,
+			wantFields: []string{"file_name"},
+		},
+		{
+			name:       "unauthenticated",
+			req:        request{Method: http.MethodPost, Path: "/api/v1/cvs/upload", Anonymous: true, Body: `{"file_name": "cv.pdf", "mime_type": "application/pdf"}`},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   response.ErrorCodeUnauthorized,
+		},
+		{
+			name:       "forbidden",
+			req:        request{Method: http.MethodGet, Path: "/api/v1/cvs/cv-1/upload-status", As: "bob"},
+			wantStatus: http.StatusForbidden,
+			wantCode:   response.ErrorCodeForbidden,
+		},
+		{
+			name:       "not found",
+			req:        request{Method: http.MethodPut, Path: "/api/v1/cvs/nope"},
+			wantStatus: http.StatusNotFound,
+			wantCode:   response.ErrorCodeNotFound,
+		},
+		{
+			name:       "illegal transition",
+			req:        request{Method: http.MethodPut, Path: "/api/v1/cvs/cv-1"},
+			wantStatus: http.StatusConflict,
+			wantCode:   response.ErrorCodeConflict,
+		},
+		{
+			name:       "upload incomplete",
+			req:        request{Method: http.MethodPut, Path: "/api/v1/cvs/cv-pending"},
+			wantStatus: http.StatusConflict,
+			wantCode:   response.ErrorCodeConflict,
+		},
+		{
+			name: "content rejected",
+			prepare: func(t *testing.T, s *server) {
+				s.storage.Put("cv/cv-pending.pdf", []byte("plain text, not a pdf\n"), "application/pdf")
+			},
+			req:        request{Method: http.MethodPut, Path: "/api/v1/cvs/cv-pending"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   response.ErrorCodeValidationFailed,
+			wantFields: []string{"content"},
+		},
+		{
+			name: "unavailable",
+			prepare: func(t *testing.T, s *server) {
+				s.repo.FailOn(memory.OpFindByID, domain.WithKind(domain.ErrUnavailable, errors.New("database down")))
+			},
+			req:        request{Method: http.MethodGet, Path: "/api/v1/cvs/cv-1"},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   response.ErrorCodeUnavailable,
+		},
+		{
+			name: "unclassified",
+			prepare: func(t *testing.T, s *server) {
+				s.storage.FailOn(memory.OpSignedURL, errors.New("signing key missing"))
+			},
+			req:        request{Method: http.MethodGet, Path: "/api/v1/cvs/cv-1/download"},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   response.ErrorCodeInternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			s.uploaded(t, "cv-1")
+			s.seed(t, domain.CV{ID: "cv-pending", FileName: "cv.pdf", GCSPath: "cv/cv-pending.pdf", MimeType: "application/pdf", Status: domain.CVStatusPending})
+			if tt.prepare != nil {
+				tt.prepare(t, s)
+			}
+
+			w := s.do(t, tt.req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status: got %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			resp := decode(t, w, nil)
+			if resp.Success || resp.Error == nil || resp.Error.Code != tt.wantCode || resp.Error.Message == "" {
+				t.Fatalf("response: got %+v, want error %s", resp, tt.wantCode)
+			}
+			var fields []string
+			for _, f := range resp.Error.Fields {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Fatalf("fields: got %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
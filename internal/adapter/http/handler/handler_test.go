@@ -0,0 +1,155 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	h "cv-platform/internal/adapter/http"
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+// owner is the authenticated caller of the tests.
+const owner = "alice"
+
+// pdf is content sniffed as application/pdf.
+var pdf = []byte("%PDF-1.7\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+
+// server is the API on the in-memory adapters.
+type server struct {
+	storage *memory.BlobStorage
+	repo    *memory.CVRepository
+	uploads *usecase.CVUploadUC
+	router  *gin.Engine
+}
+
+func newServer(t *testing.T, policy usecase.UploadPolicy) *server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := &server{storage: memory.NewBlobStorage(), repo: memory.NewCVRepository()}
+	s.uploads = usecase.NewCVUploadUC(s.storage, s.repo, policy)
+	s.route(usecase.NewProxiedUploadUC(s.uploads))
+	return s
+}
+
+// route serves the API with tus as the tus use case, e.g. to simulate a
+// restart of the instance holding the uploads.
+func (s *server) route(tus *usecase.ProxiedUploadUC) {
+	s.router = h.NewRouter(h.Deps{
+		Upload:   s.uploads,
+		Query:    usecase.NewCVQueryUC(s.repo),
+		Download: usecase.NewCVDownloadUC(s.storage, s.repo, usecase.DownloadRedirect),
+		Manage:   usecase.NewCVManageUC(s.storage, s.repo),
+		Tus:      tus,
+	})
+}
+
+// request is an API request of owner, of As when set, or of nobody with
+// Anonymous.
+type request struct {
+	Method    string
+	Path      string
+	As        string
+	Anonymous bool
+	Header    map[string]string
+	Body      string
+}
+
+func (s *server) do(t *testing.T, r request) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(r.Method, r.Path, strings.NewReader(r.Body))
+	switch {
+	case r.Anonymous:
+	case r.As != "":
+		req.Header.Set(middleware.HeaderOwnerID, r.As)
+	default:
+		req.Header.Set(middleware.HeaderOwnerID, owner)
+	}
+	for k, v := range r.Header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// seed stores cv as is, owned by owner unless set.
+func (s *server) seed(t *testing.T, cv domain.CV) *domain.CV {
+	t.Helper()
+	if cv.OwnerID == "" {
+		cv.OwnerID = owner
+	}
+	if cv.CreatedAt.IsZero() {
+		cv.CreatedAt = time.Now()
+	}
+	if err := s.repo.Create(t.Context(), &cv); err != nil {
+		t.Fatalf("Create %s: %v", cv.ID, err)
+	}
+	return &cv
+}
+
+// uploaded stores pdf as the uploaded CV id of owner.
+func (s *server) uploaded(t *testing.T, id string) *domain.CV {
+	t.Helper()
+	cv := s.seed(t, domain.CV{
+		ID: id, FileName: id + ".pdf", GCSPath: "cv/" + id + ".pdf", MimeType: "application/pdf",
+		DetectedMimeType: "application/pdf", Size: int64(len(pdf)), Status: domain.CVStatusUploaded,
+	})
+	s.storage.Put(cv.GCSPath, pdf, "application/pdf")
+	return cv
+}
+
+// find loads CV id, which must exist.
+func (s *server) find(t *testing.T, id string) *domain.CV {
+	t.Helper()
+	cv, err := s.repo.FindByID(t.Context(), id)
+	if err != nil {
+		t.Fatalf("FindByID %s: %v", id, err)
+	}
+	return cv
+}
+
+// decode decodes the API response of w, whose data goes to data if not nil.
+func decode(t *testing.T, w *httptest.ResponseRecorder, data any) response.APIResponse {
+	t.Helper()
+	var resp struct {
+		response.APIResponse
+		Data json.RawMessage `json:"data"`
+	}
+	body, _ := io.ReadAll(w.Body)
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	if data != nil {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("decode data %s: %v", resp.Data, err)
+		}
+	}
+	return resp.APIResponse
+}
+
+// wantStatus fails unless w has status, and returns its error code.
+func wantStatus(t *testing.T, w *httptest.ResponseRecorder, status int) string {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status: got %d (%s), want %d", w.Code, w.Body, status)
+	}
+	if w.Code < http.StatusBadRequest || w.Body.Len() == 0 {
+		return ""
+	}
+	resp := decode(t, w, nil)
+	if resp.Error == nil {
+		return ""
+	}
+	return resp.Error.Code
+}
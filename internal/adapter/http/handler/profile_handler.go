@@ -49,8 +49,7 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		Phone: req.Phone,
 	})
 	if err != nil {
-		log.Errorf("failed to get profile for phone %s: %v", req.Phone, err)
-		response.RespondInternalErr(c, err.Error())
+		respondErr(c, err, "failed to get profile for phone %s", req.Phone)
 		return
 	}
 
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/http/middleware"
+	"cv-platform/internal/adapter/response"
+	"cv-platform/internal/usecase"
+	"cv-platform/pkg/tus"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TusHandler serves the tus 1.0 protocol (core, creation, termination and
+// checksum extensions) on top of usecase.ProxiedUploadUC. Upload URLs are
+// basePath followed by the CV ID.
+//
+// Upload offsets are not persisted: they live in the memory of the instance
+// that created the upload, so every request of an upload must reach that
+// instance. After a restart the upload is unknown (404) and tus clients start
+// a new one; the pending CV left behind is expired by the reaper once its
+// upload window is over.
+type TusHandler struct {
+	uc       *usecase.ProxiedUploadUC
+	basePath string
+}
+
+func NewTusHandler(uc *usecase.ProxiedUploadUC, basePath string) *TusHandler {
+	return &TusHandler{uc: uc, basePath: strings.TrimSuffix(basePath, "/") + "/"}
+}
+
+// Upload-Metadata keys read on creation. filename and filetype are those sent
+// by tus-js-client and Uppy. The owner is the authenticated caller, never
+// taken from the metadata.
+const (
+	tusMetaFileName = "filename"
+	tusMetaFileType = "filetype"
+	tusMetaMD5      = "md5"
+	tusMetaCRC32C   = "crc32c"
+)
+
+// Protocol checks the Tus-Resumable header of every request but OPTIONS and
+// sets it on every response.
+func (h *TusHandler) Protocol(c *gin.Context) {
+	c.Header(tus.HeaderResumable, tus.Version)
+	if c.Request.Method != http.MethodOptions && c.GetHeader(tus.HeaderResumable) != tus.Version {
+		c.Header(tus.HeaderVersion, tus.Version)
+		response.RespondError(c, http.StatusPreconditionFailed, response.ErrorCodePreconditionFailed, "unsupported tus version")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (h *TusHandler) Options(c *gin.Context) {
+	c.Header(tus.HeaderVersion, tus.Version)
+	c.Header(tus.HeaderExtension, "creation,termination,checksum")
+	c.Header(tus.HeaderChecksumAlgorithm, tus.Algorithms)
+	if max := h.uc.MaxSize(); max > 0 {
+		c.Header(tus.HeaderMaxSize, strconv.FormatInt(max, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) Create(c *gin.Context) {
+	if c.GetHeader(tus.HeaderUploadDeferLength) != "" {
+		response.RespondBadRequest(c, "deferred upload length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader(tus.HeaderUploadLength), 10, 64)
+	if err != nil || length < 0 {
+		response.RespondBadRequest(c, "missing or invalid Upload-Length")
+		return
+	}
+	if max := h.uc.MaxSize(); max > 0 && length > max {
+		response.RespondError(c, http.StatusRequestEntityTooLarge, response.ErrorCodeInvalidRequest,
+			"upload length exceeds Tus-Max-Size")
+		return
+	}
+	meta, err := tus.ParseMetadata(c.GetHeader(tus.HeaderUploadMetadata))
+	if err != nil {
+		response.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	p, err := h.uc.Create(c.Request.Context(), usecase.StartUploadCmd{
+		FileName: meta[tusMetaFileName],
+		MimeType: meta[tusMetaFileType],
+		Size:     length,
+		OwnerID:  middleware.OwnerIDFromContext(c),
+		MD5:      meta[tusMetaMD5],
+		CRC32C:   meta[tusMetaCRC32C],
+	})
+	if p == nil {
+		respondErr(c, err, "failed to create tus upload for file %s", meta[tusMetaFileName])
+		return
+	}
+
+	// Empty uploads are finalized right away, which may fail.
+	c.Header("Location", h.basePath+p.ID)
+	if err != nil {
+		h.respondErr(c, p.ID, err)
+		return
+	}
+	c.Header(tus.HeaderUploadOffset, strconv.FormatInt(p.Offset, 10))
+	c.Status(http.StatusCreated)
+}
+
+func (h *TusHandler) Head(c *gin.Context) {
+	p, err := h.uc.Status(c.Request.Context(), c.Param("id"), middleware.OwnerIDFromContext(c))
+	if err != nil {
+		h.respondErr(c, c.Param("id"), err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header(tus.HeaderUploadOffset, strconv.FormatInt(p.Offset, 10))
+	c.Header(tus.HeaderUploadLength, strconv.FormatInt(p.Length, 10))
+	c.Status(http.StatusOK)
+}
+
+func (h *TusHandler) Patch(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	id := c.Param("id")
+
+	if ctype := c.GetHeader("Content-Type"); ctype != tus.ContentTypeOffset {
+		response.RespondError(c, http.StatusUnsupportedMediaType, response.ErrorCodeInvalidRequest,
+			"Content-Type must be "+tus.ContentTypeOffset)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader(tus.HeaderUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		response.RespondBadRequest(c, "missing or invalid Upload-Offset")
+		return
+	}
+	cmd := usecase.AppendChunkCmd{
+		ID:          id,
+		RequesterID: middleware.OwnerIDFromContext(c),
+		Offset:      offset,
+		Body:        c.Request.Body,
+	}
+	if v := c.GetHeader(tus.HeaderUploadChecksum); v != "" {
+		if cmd.Digest, cmd.Sum, err = tus.ParseChecksum(v); err != nil {
+			response.RespondBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	p, err := h.uc.Append(c.Request.Context(), cmd)
+	if p != nil {
+		c.Header(tus.HeaderUploadOffset, strconv.FormatInt(p.Offset, 10))
+	}
+	if err != nil {
+		h.respondErr(c, id, err)
+		return
+	}
+	if p.CV != nil {
+		log.Infof("tus upload completed: id=%s, status=%s, size=%d", id, p.CV.Status, p.CV.Size)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) Delete(c *gin.Context) {
+	log := middleware.SimpleLoggerFromContext(c)
+	id := c.Param("id")
+
+	if err := h.uc.Terminate(c.Request.Context(), id, middleware.OwnerIDFromContext(c)); err != nil {
+		h.respondErr(c, id, err)
+		return
+	}
+	log.Infof("tus upload terminated: id=%s", id)
+	c.Status(http.StatusNoContent)
+}
+
+// Override serves POST requests to an upload URL carrying
+// X-HTTP-Method-Override, for clients behind proxies that drop PATCH and
+// DELETE.
+func (h *TusHandler) Override(c *gin.Context) {
+	switch strings.ToUpper(c.GetHeader(tus.HeaderXHTTPMethodOverride)) {
+	case http.MethodPatch:
+		h.Patch(c)
+	case http.MethodDelete:
+		h.Delete(c)
+	case http.MethodHead:
+		h.Head(c)
+	default:
+		response.RespondError(c, http.StatusMethodNotAllowed, response.ErrorCodeInvalidRequest, "method not allowed")
+	}
+}
+
+// respondErr maps errors of ProxiedUploadUC, including those of the
+// finalization run by the request completing an upload. Checksum mismatches
+// get the status the checksum extension defines.
+func (h *TusHandler) respondErr(c *gin.Context, id string, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrChunkChecksumMismatch):
+		middleware.SimpleLoggerFromContext(c).Warnf("tus chunk for %s rejected: %v", id, err)
+		response.RespondError(c, tus.StatusChecksumMismatch, response.ErrorCodeChecksumMismatch, err.Error())
+		return
+	case errors.Is(err, usecase.ErrChunkTooLarge):
+		middleware.SimpleLoggerFromContext(c).Warnf("tus chunk for %s rejected: %v", id, err)
+		response.RespondError(c, http.StatusRequestEntityTooLarge, response.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	respondErr(c, err, "tus request for %s failed", id)
+}
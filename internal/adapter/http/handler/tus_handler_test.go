@@ -0,0 +1,319 @@
+package handler_test
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+	"cv-platform/pkg/tus"
+)
+
+const tusPath = "/api/v1/cvs/tus/"
+
+// tusMetadata is the Upload-Metadata of cv.pdf.
+var tusMetadata = "filename " + base64.StdEncoding.EncodeToString([]byte("cv.pdf")) +
+	",filetype " + base64.StdEncoding.EncodeToString([]byte("application/pdf"))
+
+// tusRequest is a tus request with the protocol version set.
+func tusRequest(method, path string, header map[string]string, body string) request {
+	h := map[string]string{tus.HeaderResumable: tus.Version}
+	for k, v := range header {
+		h[k] = v
+	}
+	return request{Method: method, Path: path, Header: h, Body: body}
+}
+
+// create creates a tus upload of length bytes and returns its URL.
+func (s *server) create(t *testing.T, length int) string {
+	t.Helper()
+	w := s.do(t, tusRequest(http.MethodPost, tusPath, map[string]string{
+		tus.HeaderUploadLength:   strconv.Itoa(length),
+		tus.HeaderUploadMetadata: tusMetadata,
+	}, ""))
+	wantStatus(t, w, http.StatusCreated)
+	return w.Header().Get("Location")
+}
+
+// patch sends chunk at offset.
+func patch(url string, offset int, chunk string, header map[string]string) request {
+	h := map[string]string{
+		"Content-Type":         tus.ContentTypeOffset,
+		tus.HeaderUploadOffset: strconv.Itoa(offset),
+	}
+	for k, v := range header {
+		h[k] = v
+	}
+	return tusRequest(http.MethodPatch, url, h, chunk)
+}
+
+func sha1Checksum(chunk string) string {
+	sum := sha1.Sum([]byte(chunk))
+	return "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestTusOptions(t *testing.T) {
+	s := newServer(t, usecase.DefaultUploadPolicy())
+	// OPTIONS needs no Tus-Resumable.
+	w := s.do(t, request{Method: http.MethodOptions, Path: tusPath})
+	wantStatus(t, w, http.StatusNoContent)
+	for header, want := range map[string]string{
+		tus.HeaderResumable:         tus.Version,
+		tus.HeaderVersion:           tus.Version,
+		tus.HeaderExtension:         "creation,termination,checksum",
+		tus.HeaderChecksumAlgorithm: tus.Algorithms,
+		tus.HeaderMaxSize:           strconv.Itoa(10 << 20),
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s: got %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestTusUpload(t *testing.T) {
+	s := newServer(t, usecase.DefaultUploadPolicy())
+	url := s.create(t, len(pdf))
+	if !strings.HasPrefix(url, tusPath) {
+		t.Fatalf("Location: got %q, want under %s", url, tusPath)
+	}
+	id := strings.TrimPrefix(url, tusPath)
+
+	half := len(pdf) / 2
+	w := s.do(t, patch(url, 0, string(pdf[:half]), nil))
+	wantStatus(t, w, http.StatusNoContent)
+	if got := w.Header().Get(tus.HeaderUploadOffset); got != strconv.Itoa(half) {
+		t.Fatalf("Upload-Offset after first chunk: got %s, want %d", got, half)
+	}
+
+	w = s.do(t, tusRequest(http.MethodHead, url, nil, ""))
+	wantStatus(t, w, http.StatusOK)
+	if got := w.Header().Get(tus.HeaderUploadOffset); got != strconv.Itoa(half) {
+		t.Fatalf("HEAD Upload-Offset: got %s, want %d", got, half)
+	}
+	if got := w.Header().Get(tus.HeaderUploadLength); got != strconv.Itoa(len(pdf)) {
+		t.Fatalf("HEAD Upload-Length: got %s, want %d", got, len(pdf))
+	}
+
+	w = s.do(t, patch(url, half, string(pdf[half:]), map[string]string{tus.HeaderUploadChecksum: sha1Checksum(string(pdf[half:]))}))
+	wantStatus(t, w, http.StatusNoContent)
+	if cv := s.find(t, id); cv.Status != domain.CVStatusUploaded || cv.Size != int64(len(pdf)) {
+		t.Fatalf("cv: got %s of %d bytes, want %s of %d", cv.Status, cv.Size, domain.CVStatusUploaded, len(pdf))
+	}
+	// A finished upload is gone.
+	wantStatus(t, s.do(t, tusRequest(http.MethodHead, url, nil, "")), http.StatusNotFound)
+}
+
+func TestTusPatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        func(url string) request
+		wantStatus int
+	}{
+		{
+			name:       "offset mismatch",
+			req:        func(url string) request { return patch(url, 5, "%PDF", nil) },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "checksum mismatch",
+			req: func(url string) request {
+				return patch(url, 0, "%PDF", map[string]string{tus.HeaderUploadChecksum: sha1Checksum("other")})
+			},
+			wantStatus: tus.StatusChecksumMismatch,
+		},
+		{
+			name: "unknown algorithm",
+			req: func(url string) request {
+				return patch(url, 0, "%PDF", map[string]string{tus.HeaderUploadChecksum: "crc32 AAAAAA=="})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong content type",
+			req: func(url string) request {
+				return patch(url, 0, "%PDF", map[string]string{"Content-Type": "application/pdf"})
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "no offset",
+			req: func(url string) request {
+				return patch(url, 0, "%PDF", map[string]string{tus.HeaderUploadOffset: ""})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "other owner",
+			req: func(url string) request {
+				r := patch(url, 0, "%PDF", nil)
+				r.As = "bob"
+				return r
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "unsupported version",
+			req: func(url string) request {
+				return patch(url, 0, "%PDF", map[string]string{tus.HeaderResumable: "0.2.2"})
+			},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "unknown upload",
+			req:        func(url string) request { return patch(tusPath+"nope", 0, "%PDF", nil) },
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			url := s.create(t, len(pdf))
+
+			wantStatus(t, s.do(t, tt.req(url)), tt.wantStatus)
+
+			// Nothing of the refused chunk was appended.
+			w := s.do(t, tusRequest(http.MethodHead, url, nil, ""))
+			wantStatus(t, w, http.StatusOK)
+			if got := w.Header().Get(tus.HeaderUploadOffset); got != "0" {
+				t.Fatalf("Upload-Offset: got %s, want 0", got)
+			}
+		})
+	}
+}
+
+func TestTusCreateErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "no length", header: map[string]string{tus.HeaderUploadMetadata: tusMetadata}, wantStatus: http.StatusBadRequest},
+		{
+			name:       "deferred length",
+			header:     map[string]string{tus.HeaderUploadDeferLength: "1", tus.HeaderUploadMetadata: tusMetadata},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "too large",
+			header:     map[string]string{tus.HeaderUploadLength: strconv.Itoa(10<<20 + 1), tus.HeaderUploadMetadata: tusMetadata},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "malformed metadata",
+			header:     map[string]string{tus.HeaderUploadLength: "10", tus.HeaderUploadMetadata: "filename !!!"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "policy violation",
+			header: map[string]string{
+				tus.HeaderUploadLength:   "10",
+				tus.HeaderUploadMetadata: "filename " + base64.StdEncoding.EncodeToString([]byte("cv.exe")),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unsupported version",
+			header:     map[string]string{tus.HeaderResumable: "0.2.2", tus.HeaderUploadLength: "10", tus.HeaderUploadMetadata: tusMetadata},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			w := s.do(t, tusRequest(http.MethodPost, tusPath, tt.header, ""))
+			wantStatus(t, w, tt.wantStatus)
+			if tt.wantStatus == http.StatusPreconditionFailed && w.Header().Get(tus.HeaderVersion) != tus.Version {
+				t.Fatalf("Tus-Version: got %q, want %q", w.Header().Get(tus.HeaderVersion), tus.Version)
+			}
+		})
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		s := newServer(t, usecase.DefaultUploadPolicy())
+		r := tusRequest(http.MethodPost, tusPath, map[string]string{tus.HeaderUploadLength: "10", tus.HeaderUploadMetadata: tusMetadata}, "")
+		r.Anonymous = true
+		wantStatus(t, s.do(t, r), http.StatusUnauthorized)
+	})
+}
+
+// TestTusRejectedContent checks that the request completing an upload gets
+// the result of its finalization.
+func TestTusRejectedContent(t *testing.T) {
+	s := newServer(t, usecase.DefaultUploadPolicy())
+	content := "plain text, not a pdf\n"
+	url := s.create(t, len(content))
+
+	code := wantStatus(t, s.do(t, patch(url, 0, content, nil)), http.StatusUnprocessableEntity)
+	if code != "VALIDATION_FAILED" {
+		t.Fatalf("error code: got %s, want VALIDATION_FAILED", code)
+	}
+	if cv := s.find(t, strings.TrimPrefix(url, tusPath)); cv.Status != domain.CVStatusRejected {
+		t.Fatalf("status: got %s, want %s", cv.Status, domain.CVStatusRejected)
+	}
+}
+
+func TestTusTerminate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func(url string) request
+	}{
+		{name: "DELETE", req: func(url string) request { return tusRequest(http.MethodDelete, url, nil, "") }},
+		{
+			name: "method override",
+			req: func(url string) request {
+				return tusRequest(http.MethodPost, url, map[string]string{tus.HeaderXHTTPMethodOverride: "DELETE"}, "")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, usecase.DefaultUploadPolicy())
+			url := s.create(t, len(pdf))
+			wantStatus(t, s.do(t, patch(url, 0, "%PDF", nil)), http.StatusNoContent)
+
+			wantStatus(t, s.do(t, tt.req(url)), http.StatusNoContent)
+			if cv := s.find(t, strings.TrimPrefix(url, tusPath)); cv.Status != domain.CVStatusDeleted {
+				t.Fatalf("status: got %s, want %s", cv.Status, domain.CVStatusDeleted)
+			}
+			wantStatus(t, s.do(t, tusRequest(http.MethodHead, url, nil, "")), http.StatusNotFound)
+			wantStatus(t, s.do(t, tt.req(url)), http.StatusNotFound)
+		})
+	}
+}
+
+func TestTusMethodOverride(t *testing.T) {
+	s := newServer(t, usecase.DefaultUploadPolicy())
+	url := s.create(t, len(pdf))
+
+	r := patch(url, 0, "%PDF", map[string]string{tus.HeaderXHTTPMethodOverride: "PATCH"})
+	r.Method = http.MethodPost
+	w := s.do(t, r)
+	wantStatus(t, w, http.StatusNoContent)
+	if got := w.Header().Get(tus.HeaderUploadOffset); got != "4" {
+		t.Fatalf("Upload-Offset: got %s, want 4", got)
+	}
+
+	r = tusRequest(http.MethodPost, url, map[string]string{tus.HeaderXHTTPMethodOverride: "PUT"}, "")
+	wantStatus(t, s.do(t, r), http.StatusMethodNotAllowed)
+}
+
+// TestTusRestart checks that uploads do not survive the instance holding
+// them: tus clients see 404 and start over, while the CV stays pending for
+// the reaper.
+func TestTusRestart(t *testing.T) {
+	s := newServer(t, usecase.DefaultUploadPolicy())
+	url := s.create(t, len(pdf))
+	wantStatus(t, s.do(t, patch(url, 0, "%PDF", nil)), http.StatusNoContent)
+
+	s.route(usecase.NewProxiedUploadUC(s.uploads))
+
+	wantStatus(t, s.do(t, tusRequest(http.MethodHead, url, nil, "")), http.StatusNotFound)
+	wantStatus(t, s.do(t, patch(url, 4, string(pdf[4:]), nil)), http.StatusNotFound)
+	if cv := s.find(t, strings.TrimPrefix(url, tusPath)); cv.Status != domain.CVStatusPending {
+		t.Fatalf("status: got %s, want %s", cv.Status, domain.CVStatusPending)
+	}
+}
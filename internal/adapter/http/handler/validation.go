@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"cv-platform/internal/adapter/response"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseRequestFieldNames makes binding errors name fields after their json,
+// form or uri tag (e.g. file_name) instead of the Go struct field.
+func UseRequestFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return f.Name
+	})
+}
+
+// bindingFieldErrors turns request binding errors into per-field details.
+func bindingFieldErrors(err error) []response.FieldError {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	fields := make([]response.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		msg := "failed the " + fe.Tag() + " rule"
+		if fe.Param() != "" {
+			msg += " (" + fe.Param() + ")"
+		}
+		if fe.Tag() == "required" {
+			msg = "is required"
+		}
+		fields = append(fields, response.FieldError{Field: fe.Field(), Message: msg})
+	}
+	return fields
+}
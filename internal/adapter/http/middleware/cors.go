@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"cv-platform/pkg/tus"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers browser clients read: the tus
+// protocol, resumable upload progress and download file names.
+var corsExposedHeaders = strings.Join([]string{
+	"Location", "Range", "Content-Disposition",
+	tus.HeaderResumable, tus.HeaderVersion, tus.HeaderExtension, tus.HeaderMaxSize,
+	tus.HeaderChecksumAlgorithm, tus.HeaderUploadOffset, tus.HeaderUploadLength,
+}, ", ")
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight response.
+const corsMaxAge = "600"
+
+// CORS lets browsers on the allowed origins call the API; "*" allows any
+// origin. Preflight requests are answered here, before routing, so they
+// reach no handler.
+func CORS(allowedOrigins []string) gin.HandlerFunc {
+	anyOrigin := slices.Contains(allowedOrigins, "*")
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		if !anyOrigin && !slices.Contains(allowedOrigins, origin) {
+			// Without the CORS headers the browser refuses the response.
+			c.Next()
+			return
+		}
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+			if h := c.GetHeader("Access-Control-Request-Headers"); h != "" {
+				c.Header("Access-Control-Allow-Headers", h)
+			}
+			c.Header("Access-Control-Max-Age", corsMaxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderOwnerID carries the ID of the authenticated caller (the profile or
+// tenant CVs are owned by). The API does not authenticate callers itself: the
+// header must be set by the authenticating gateway in front of it, which has
+// to strip any value sent by clients.
+const HeaderOwnerID = "X-Owner-ID"
+
+// OwnerIDFromContext returns the authenticated caller of the request, or ""
+// for anonymous requests.
+func OwnerIDFromContext(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(HeaderOwnerID))
+}
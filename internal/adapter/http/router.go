@@ -8,22 +8,71 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(cvUC *usecase.CVUploadUC, profileUC *usecase.ProfileStoreUC) *gin.Engine {
+// Deps are what the routes are served by.
+type Deps struct {
+	Upload   *usecase.CVUploadUC
+	Query    *usecase.CVQueryUC
+	Profile  *usecase.ProfileStoreUC
+	Download *usecase.CVDownloadUC
+	Manage   *usecase.CVManageUC
+	// BlobStore is optional: when set, the routes backing its self-served
+	// signed URLs are mounted under /api/v1/blobs.
+	BlobStore handler.BlobStore
+	// Tus is optional: when set, the tus upload server is mounted under
+	// /api/v1/cvs/tus/.
+	Tus *usecase.ProxiedUploadUC
+	// CORSOrigins are the browser origins that may call the API
+	// cross-origin.
+	CORSOrigins []string
+}
+
+// NewRouter wires the HTTP routes.
+func NewRouter(d Deps) *gin.Engine {
+	handler.UseRequestFieldNames()
 	router := gin.New()
 
 	// Add middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestLogging())
+	if len(d.CORSOrigins) > 0 {
+		router.Use(middleware.CORS(d.CORSOrigins))
+	}
 
 	api := router.Group("/api/v1")
 	cvApi := api.Group("/cvs")
 	{
-		cvApi.POST("/upload", handler.NewCVHandler(cvUC).StartUpload)
-		cvApi.PUT("/:id", handler.NewCVHandler(cvUC).CompleteUpload)
+		cvApi.GET("", handler.NewCVQueryHandler(d.Query).ListCVs)
+		cvApi.POST("/upload", handler.NewCVHandler(d.Upload).StartUpload)
+		cvApi.GET("/:id", handler.NewCVManageHandler(d.Manage).GetCV)
+		cvApi.PUT("/:id", handler.NewCVHandler(d.Upload).CompleteUpload)
+		cvApi.DELETE("/:id", handler.NewCVManageHandler(d.Manage).DeleteCV)
+		cvApi.GET("/:id/upload-status", handler.NewCVHandler(d.Upload).UploadStatus)
+		cvApi.GET("/:id/download", handler.NewCVDownloadHandler(d.Download).Download)
+	}
+	if d.Tus != nil {
+		tus := handler.NewTusHandler(d.Tus, "/api/v1/cvs/tus/")
+		tusApi := cvApi.Group("/tus", tus.Protocol)
+		{
+			tusApi.OPTIONS("/", tus.Options)
+			tusApi.POST("/", tus.Create)
+			tusApi.OPTIONS("/:id", tus.Options)
+			tusApi.HEAD("/:id", tus.Head)
+			tusApi.PATCH("/:id", tus.Patch)
+			tusApi.DELETE("/:id", tus.Delete)
+			tusApi.POST("/:id", tus.Override)
+		}
 	}
 	profileApi := api.Group("/profiles")
 	{
-		profileApi.GET("/:id", handler.NewProfileHandler(profileUC).GetProfile)
+		profileApi.GET("/:id", handler.NewProfileHandler(d.Profile).GetProfile)
+	}
+	if d.BlobStore != nil {
+		blobApi := api.Group("/blobs")
+		{
+			blobApi.POST("", handler.NewBlobHandler(d.BlobStore).PostUpload)
+			blobApi.PUT("/*key", handler.NewBlobHandler(d.BlobStore).Upload)
+			blobApi.GET("/*key", handler.NewBlobHandler(d.BlobStore).Download)
+		}
 	}
 	return router
 }
@@ -0,0 +1,239 @@
+package localfs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/resumable"
+)
+
+// ParamUploadID is the query parameter identifying the resumable session of
+// a session URI. Session URIs are bearer capabilities, like on GCS.
+const ParamUploadID = "upload_id"
+
+var (
+	ErrSessionNotFound = fmt.Errorf("localfs: %w", port.ErrSessionNotFound)
+	ErrInvalidRange    = domain.WithKind(domain.ErrValidation, fmt.Errorf("localfs: %w", resumable.ErrInvalidRange))
+)
+
+// sessionMeta is the state of a resumable session; its content so far is kept
+// next to it in a .part file. MaxSize 0 means unbounded.
+type sessionMeta struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type,omitempty"`
+	MaxSize     int64  `json:"max_size,omitempty"`
+	Expires     int64  `json:"expires"`
+	Complete    bool   `json:"complete,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// StartResumable opens a session whose URI is the object URL with an
+// upload_id query parameter; chunks are served by WriteChunk. Chunks reaching
+// past opts.MaxSize are refused with ErrTooLarge.
+func (s *Storage) StartResumable(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if err := os.WriteFile(s.sessionPath(id, ".part"), nil, 0o644); err != nil {
+		return "", err
+	}
+	meta := sessionMeta{Key: key, ContentType: opts.ContentType, MaxSize: opts.MaxSize, Expires: opts.ExpiredAt.Unix()}
+	if err := s.writeSession(id, meta); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + escapeKey(key) + "?" + url.Values{ParamUploadID: {id}}.Encode(), nil
+}
+
+func (s *Storage) ResumableStatus(ctx context.Context, sessionURI string) (port.UploadStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return port.UploadStatus{}, err
+	}
+	u, err := url.Parse(sessionURI)
+	if err != nil {
+		return port.UploadStatus{}, ErrSessionNotFound
+	}
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	_, status, err := s.loadSession(u.Query().Get(ParamUploadID))
+	return status, err
+}
+
+// WriteChunk applies a chunk (or, without data, a status query) described by
+// the Content-Range header value contentRange to the session uploadID of
+// object. Once the announced total is received the object is stored, with the
+// content type the session was started with.
+//
+// The chunk is staged in a temporary file before s.sessionMu is taken, so a
+// slow client holds up neither other sessions nor status queries.
+func (s *Storage) WriteChunk(object, uploadID, contentRange string, body io.Reader) (port.UploadStatus, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return port.UploadStatus{}, err
+	}
+	cr, err := resumable.ParseContentRange(contentRange)
+	if err != nil {
+		return port.UploadStatus{}, ErrInvalidRange
+	}
+
+	s.sessionMu.Lock()
+	meta, status, err := s.loadSession(uploadID)
+	s.sessionMu.Unlock()
+	if err != nil {
+		return status, err
+	}
+	if meta.Key != key {
+		return status, ErrSessionNotFound
+	}
+	if status.Complete {
+		return status, nil
+	}
+	if meta.MaxSize > 0 && (cr.Total > meta.MaxSize || cr.HasData && cr.Last >= meta.MaxSize) {
+		return status, fmt.Errorf("%w: at most %d bytes", ErrTooLarge, meta.MaxSize)
+	}
+
+	var chunk io.Reader = http.NoBody
+	var readErr error
+	if cr.HasData {
+		var f *os.File
+		if f, readErr = s.stageChunk(cr, body); f == nil {
+			return status, readErr
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		chunk = f
+	}
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	meta, status, err = s.loadSession(uploadID)
+	if err != nil {
+		return status, err
+	}
+	if status.Complete {
+		return status, nil
+	}
+
+	f, err := os.OpenFile(s.sessionPath(uploadID, ".part"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return status, err
+	}
+	received, err := cr.Append(f, chunk, status.Received)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	status.Received = received
+	if readErr != nil {
+		// The bytes that arrived are kept; the client resumes after them.
+		return status, readErr
+	}
+	if errors.Is(err, resumable.ErrInvalidRange) || errors.Is(err, resumable.ErrGap) {
+		return status, fmt.Errorf("%w: %v", ErrInvalidRange, err)
+	}
+	if err != nil || !cr.Complete(received) {
+		return status, err
+	}
+
+	part, err := os.Open(s.sessionPath(uploadID, ".part"))
+	if err != nil {
+		return status, err
+	}
+	_, err = s.put(key, part, meta.ContentType, nil)
+	part.Close()
+	if err != nil {
+		return status, err
+	}
+	meta.Complete, meta.Size = true, received
+	if err := s.writeSession(uploadID, meta); err != nil {
+		return status, err
+	}
+	_ = os.Remove(s.sessionPath(uploadID, ".part"))
+	status.Complete = true
+	return status, nil
+}
+
+// stageChunk copies the data of cr from body to a temporary file, rewound for
+// reading. When body fails or ends early, the file holds what was read and the
+// read error is returned with it; a nil file means nothing could be staged.
+func (s *Storage) stageChunk(cr resumable.ContentRange, body io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Join(s.root, "sessions"), ".chunk-*")
+	if err != nil {
+		return nil, err
+	}
+	_, readErr := io.CopyN(f, body, cr.Len())
+	if errors.Is(readErr, io.EOF) {
+		// A short body is reported by ContentRange.Append.
+		readErr = nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, readErr
+}
+
+// loadSession reads a session; s.sessionMu must be held. Expired incomplete
+// sessions are removed.
+func (s *Storage) loadSession(id string) (sessionMeta, port.UploadStatus, error) {
+	var meta sessionMeta
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return meta, port.UploadStatus{}, ErrSessionNotFound
+	}
+	b, err := os.ReadFile(s.sessionPath(id, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, port.UploadStatus{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return meta, port.UploadStatus{}, err
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return meta, port.UploadStatus{}, err
+	}
+	if meta.Complete {
+		return meta, port.UploadStatus{Received: meta.Size, Complete: true}, nil
+	}
+	if !s.now().Before(time.Unix(meta.Expires, 0)) {
+		_ = os.Remove(s.sessionPath(id, ".part"))
+		_ = os.Remove(s.sessionPath(id, ".json"))
+		return meta, port.UploadStatus{}, ErrSessionNotFound
+	}
+	fi, err := os.Stat(s.sessionPath(id, ".part"))
+	if err != nil {
+		return meta, port.UploadStatus{}, err
+	}
+	return meta, port.UploadStatus{Received: fi.Size()}, nil
+}
+
+func (s *Storage) writeSession(id string, meta sessionMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.sessionPath(id, ".json"), b, 0o644)
+}
+
+func (s *Storage) sessionPath(id, ext string) string {
+	return filepath.Join(s.root, "sessions", id+ext)
+}
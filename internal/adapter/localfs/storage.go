@@ -0,0 +1,579 @@
+package localfs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+)
+
+// Query parameters carried by the signed URLs issued by Storage.
+const (
+	paramMethod      = "X-Method"
+	paramContentType = "X-Content-Type"
+	paramMD5         = "X-Content-MD5"
+	paramCRC32C      = "X-CRC32C"
+	paramExpires     = "X-Expires"
+	// ParamMaxSize is the largest body a PUT URL accepts, when bounded.
+	ParamMaxSize = "X-Max-Size"
+	// ParamContentDisposition is the Content-Disposition a GET URL is served
+	// with, named as on GCS.
+	ParamContentDisposition = "response-content-disposition"
+	paramSignature          = "X-Signature"
+)
+
+// Form fields of the POST policies issued by Storage.
+const (
+	FieldKey         = "key"
+	FieldContentType = "content-type"
+	FieldPolicy      = "policy"
+	FieldSignature   = "x-signature"
+)
+
+var (
+	ErrInvalidKey          = domain.NewError(domain.ErrValidation, "localfs: invalid object key")
+	ErrInvalidSignature    = domain.NewError(domain.ErrPermissionDenied, "localfs: invalid signature")
+	ErrURLExpired          = domain.NewError(domain.ErrPermissionDenied, "localfs: signed url expired")
+	ErrMethodMismatch      = domain.NewError(domain.ErrPermissionDenied, "localfs: method does not match signed url")
+	ErrContentTypeMismatch = domain.NewError(domain.ErrPermissionDenied, "localfs: content type does not match signed url")
+	ErrObjectNotFound      = fmt.Errorf("localfs: %w", port.ErrObjectNotFound)
+	ErrKeyMismatch         = domain.NewError(domain.ErrPermissionDenied, "localfs: key does not match signed policy")
+	ErrSizeOutOfRange      = domain.NewError(domain.ErrValidation, "localfs: size outside the signed policy range")
+	ErrChecksumMismatch    = domain.NewError(domain.ErrPermissionDenied, "localfs: checksum headers do not match signed url")
+	ErrBadDigest           = domain.NewError(domain.ErrValidation, "localfs: content does not match its checksum headers")
+	// ErrTooLarge is returned for uploads past the maximum size of their
+	// signed URL or resumable session; the blob routes answer it with 413.
+	ErrTooLarge = domain.NewError(domain.ErrValidation, "localfs: upload exceeds the signed maximum size")
+)
+
+// Storage is a port.BlobStorage that keeps objects under a local directory.
+// Signed URLs point back at the API itself (see handler.BlobHandler), so the
+// upload flow works end to end without any cloud bucket.
+type Storage struct {
+	root    string
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+
+	// sessionMu serialises the session file updates of resumable uploads.
+	sessionMu sync.Mutex
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+	Checksums   port.Checksums
+	ModTime     time.Time
+}
+
+// PostConditions are the constraints of a verified POST policy.
+type PostConditions struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type,omitempty"`
+	MinSize     int64  `json:"min_size,omitempty"`
+	MaxSize     int64  `json:"max_size,omitempty"`
+	Expires     int64  `json:"expires"`
+}
+
+type objectMeta struct {
+	ContentType string `json:"content_type"`
+	MD5         string `json:"md5,omitempty"`
+	CRC32C      string `json:"crc32c,omitempty"`
+}
+
+// NewStorage creates a Storage rooted at dir. baseURL is the public URL under
+// which the blob routes are mounted (e.g. http://localhost:8080/api/v1/blobs).
+// When secret is empty a random key is generated, which means signed URLs do
+// not survive a restart.
+func NewStorage(dir, baseURL string, secret []byte) (*Storage, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("localfs: root directory is required")
+	}
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return nil, fmt.Errorf("localfs: invalid base url %q", baseURL)
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range []string{"objects", "meta", "sessions"} {
+		if err := os.MkdirAll(filepath.Join(root, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &Storage{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+		now:     time.Now,
+	}, nil
+}
+
+func (s *Storage) SignedURL(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return "", err
+	}
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = "PUT"
+	}
+	if method != "PUT" && method != "GET" {
+		return "", fmt.Errorf("localfs: unsupported method %q", opts.Method)
+	}
+	exp := strconv.FormatInt(opts.ExpiredAt.Unix(), 10)
+
+	q := url.Values{}
+	q.Set(paramMethod, method)
+	if opts.ContentType != "" {
+		q.Set(paramContentType, opts.ContentType)
+	}
+	if opts.Checksums.MD5 != "" {
+		q.Set(paramMD5, opts.Checksums.MD5)
+	}
+	if opts.Checksums.CRC32C != "" {
+		q.Set(paramCRC32C, opts.Checksums.CRC32C)
+	}
+	if opts.ContentDisposition != "" {
+		q.Set(ParamContentDisposition, opts.ContentDisposition)
+	}
+	var maxSize string
+	if method == "PUT" && opts.MaxSize > 0 {
+		maxSize = strconv.FormatInt(opts.MaxSize, 10)
+		q.Set(ParamMaxSize, maxSize)
+	}
+	q.Set(paramExpires, exp)
+	q.Set(paramSignature, s.sign(method, key, opts.ContentType, exp, opts.Checksums, opts.ContentDisposition, maxSize))
+
+	return s.baseURL + "/" + escapeKey(key) + "?" + q.Encode(), nil
+}
+
+// SignedPostPolicy issues a form upload posted to the blob routes root. The
+// conditions travel base64 encoded in the "policy" field, signed like URLs.
+func (s *Storage) SignedPostPolicy(ctx context.Context, object string, opts port.SignedURLOptions) (*port.PostPolicy, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(PostConditions{
+		Key:         key,
+		ContentType: opts.ContentType,
+		MinSize:     opts.MinSize,
+		MaxSize:     opts.MaxSize,
+		Expires:     opts.ExpiredAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	policy := base64.StdEncoding.EncodeToString(b)
+
+	fields := map[string]string{
+		FieldKey:       key,
+		FieldPolicy:    policy,
+		FieldSignature: s.signPolicy(policy),
+	}
+	if opts.ContentType != "" {
+		fields[FieldContentType] = opts.ContentType
+	}
+	return &port.PostPolicy{URL: s.baseURL, Fields: fields}, nil
+}
+
+func (s *Storage) Head(ctx context.Context, object string) (port.ObjectAttrs, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return port.ObjectAttrs{}, false, err
+	}
+	info, err := s.Stat(object)
+	if errors.Is(err, ErrObjectNotFound) {
+		return port.ObjectAttrs{}, false, nil
+	}
+	if err != nil {
+		return port.ObjectAttrs{}, false, err
+	}
+	return port.ObjectAttrs{Size: info.Size, ContentType: info.ContentType, Checksums: info.Checksums}, true, nil
+}
+
+// Verify checks that a request for object carrying headers h and query q
+// matches a URL previously issued by SignedURL: the signature must be valid,
+// the URL must not be expired, and the method, Content-Type and checksum
+// headers must be the ones it was signed for. Content-Type and checksums are
+// only compared when the URL was signed with them. The body size is left to
+// the caller, bounded by the signed ParamMaxSize.
+func (s *Storage) Verify(method, object string, h http.Header, q url.Values) error {
+	key, err := cleanKey(object)
+	if err != nil {
+		return err
+	}
+	signedMethod := q.Get(paramMethod)
+	signedType := q.Get(paramContentType)
+	signedSums := port.Checksums{MD5: q.Get(paramMD5), CRC32C: q.Get(paramCRC32C)}
+	exp := q.Get(paramExpires)
+
+	want := s.sign(signedMethod, key, signedType, exp, signedSums, q.Get(ParamContentDisposition), q.Get(ParamMaxSize))
+	if !hmac.Equal([]byte(want), []byte(q.Get(paramSignature))) {
+		return ErrInvalidSignature
+	}
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(expUnix, 0)) {
+		return ErrURLExpired
+	}
+	if !strings.EqualFold(method, signedMethod) {
+		return ErrMethodMismatch
+	}
+	if signedType != "" && !strings.EqualFold(strings.TrimSpace(h.Get("Content-Type")), signedType) {
+		return ErrContentTypeMismatch
+	}
+	sent := HeaderChecksums(h)
+	if signedSums.MD5 != "" && sent.MD5 != signedSums.MD5 || signedSums.CRC32C != "" && sent.CRC32C != signedSums.CRC32C {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// HeaderChecksums returns the checksums an upload request claims in its
+// Content-MD5 and X-Goog-Hash headers.
+func HeaderChecksums(h http.Header) port.Checksums {
+	hashMD5, hashCRC32C := checksum.ParseHashHeader(h.Values(checksum.HeaderHash))
+	sums := port.Checksums{MD5: h.Get(checksum.HeaderMD5), CRC32C: hashCRC32C}
+	if sums.MD5 == "" {
+		sums.MD5 = hashMD5
+	}
+	return sums
+}
+
+// VerifyPost checks the form fields of a POST policy upload: the policy must
+// carry a valid signature, must not be expired, and the key and content type
+// fields must be the ones it was signed for. The size range is enforced by
+// PutWithin.
+func (s *Storage) VerifyPost(fields map[string]string) (*PostConditions, error) {
+	policy := fields[FieldPolicy]
+	if !hmac.Equal([]byte(s.signPolicy(policy)), []byte(fields[FieldSignature])) {
+		return nil, ErrInvalidSignature
+	}
+	b, err := base64.StdEncoding.DecodeString(policy)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var pc PostConditions
+	if err := json.Unmarshal(b, &pc); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(pc.Expires, 0)) {
+		return nil, ErrURLExpired
+	}
+	if fields[FieldKey] != pc.Key {
+		return nil, ErrKeyMismatch
+	}
+	if pc.ContentType != "" && !strings.EqualFold(strings.TrimSpace(fields[FieldContentType]), pc.ContentType) {
+		return nil, ErrContentTypeMismatch
+	}
+	return &pc, nil
+}
+
+// Put stores the content of r under object, replacing any existing object.
+// The write is atomic: readers never observe a partially written file.
+func (s *Storage) Put(object string, r io.Reader, contentType string) (int64, error) {
+	return s.put(object, r, contentType, nil)
+}
+
+// PutChecked is Put for uploads that must match the given checksums. Content
+// with other digests is discarded with ErrBadDigest.
+func (s *Storage) PutChecked(object string, r io.Reader, contentType string, want port.Checksums) (int64, error) {
+	return s.put(object, r, contentType, func(_ int64, got port.Checksums) error {
+		if want.MD5 != "" && want.MD5 != got.MD5 || want.CRC32C != "" && want.CRC32C != got.CRC32C {
+			return ErrBadDigest
+		}
+		return nil
+	})
+}
+
+// PutWithin is Put for uploads whose size must lie in [minSize, maxSize]
+// (maxSize 0 means unbounded). Out of range content is discarded with
+// ErrSizeOutOfRange; reading stops just past maxSize.
+func (s *Storage) PutWithin(object string, r io.Reader, contentType string, minSize, maxSize int64) (int64, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	return s.put(object, r, contentType, func(n int64, _ port.Checksums) error {
+		if n < minSize || maxSize > 0 && n > maxSize {
+			return ErrSizeOutOfRange
+		}
+		return nil
+	})
+}
+
+// NewWriter streams an object into place through Put: nothing is visible
+// until Close, and Abort removes the partial file.
+func (s *Storage) NewWriter(ctx context.Context, object, contentType string) (port.ObjectWriter, error) {
+	if _, err := cleanKey(object); err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	w := &writer{pw: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := s.put(object, pr, contentType, nil)
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+var errWriterAborted = errors.New("localfs: write aborted")
+
+type writer struct {
+	pw   *io.PipeWriter
+	done chan error
+	// err is the result of the put, once received from done.
+	err      error
+	finished bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *writer) Close() error {
+	w.pw.Close()
+	return w.wait()
+}
+
+func (w *writer) Abort() error {
+	w.pw.CloseWithError(errWriterAborted)
+	if err := w.wait(); !errors.Is(err, errWriterAborted) {
+		return err
+	}
+	return nil
+}
+
+func (w *writer) wait() error {
+	if !w.finished {
+		w.err, w.finished = <-w.done, true
+	}
+	return w.err
+}
+
+func (s *Storage) put(object string, r io.Reader, contentType string, check func(n int64, sums port.Checksums) error) (int64, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return 0, err
+	}
+	dst := s.objectPath(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := checksum.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	sums := port.Checksums{MD5: hash.MD5(), CRC32C: hash.CRC32C()}
+	if check != nil {
+		if err := check(n, sums); err != nil {
+			return n, err
+		}
+	}
+	if err := s.writeMeta(key, objectMeta{ContentType: contentType, MD5: sums.MD5, CRC32C: sums.CRC32C}); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Storage) Read(ctx context.Context, object string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, _, err := s.Open(object)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, object string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key, err := cleanKey(object)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.objectPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Open returns a reader for object together with its metadata.
+func (s *Storage) Open(object string) (*os.File, *ObjectInfo, error) {
+	info, err := s.Stat(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, _ := cleanKey(object)
+	f, err := os.Open(s.objectPath(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// Stat returns the metadata of object, or ErrObjectNotFound.
+func (s *Storage) Stat(object string) (*ObjectInfo, error) {
+	key, err := cleanKey(object)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(s.objectPath(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, ErrObjectNotFound
+	}
+	meta, err := s.readMeta(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Size:        fi.Size(),
+		ContentType: meta.ContentType,
+		Checksums:   port.Checksums{MD5: meta.MD5, CRC32C: meta.CRC32C},
+		ModTime:     fi.ModTime(),
+	}, nil
+}
+
+func (s *Storage) sign(method, key, contentType, exp string, sums port.Checksums, disposition, maxSize string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.ToUpper(method) + "\n" + key + "\n" + contentType + "\n" + exp + "\n" + sums.MD5 + "\n" + sums.CRC32C))
+	// Appended only when set, so URLs signed without them stay valid.
+	if disposition != "" {
+		mac.Write([]byte("\n" + disposition))
+	}
+	if maxSize != "" {
+		mac.Write([]byte("\nmax-size:" + maxSize))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Storage) signPolicy(policy string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("POST\n" + policy))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Storage) objectPath(key string) string {
+	return filepath.Join(s.root, "objects", filepath.FromSlash(key))
+}
+
+func (s *Storage) metaPath(key string) string {
+	return filepath.Join(s.root, "meta", filepath.FromSlash(key)+".json")
+}
+
+func (s *Storage) writeMeta(key string, m objectMeta) error {
+	p := s.metaPath(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+func (s *Storage) readMeta(key string) (objectMeta, error) {
+	var m objectMeta
+	b, err := os.ReadFile(s.metaPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// cleanKey normalises an object key and rejects anything that could escape the
+// storage root.
+func cleanKey(object string) (string, error) {
+	key := strings.TrimPrefix(object, "/")
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	if path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,154 @@
+package localfs_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	h "cv-platform/internal/adapter/http"
+	"cv-platform/internal/adapter/localfs"
+	"cv-platform/internal/port"
+	"cv-platform/internal/port/porttest"
+)
+
+// TestStorageConformance serves the signed URLs with the API's blob routes, as
+// in production.
+func TestStorageConformance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	porttest.RunBlobStorageConformance(t, func(t *testing.T) porttest.BlobStorageHarness {
+		srv := httptest.NewServer(nil)
+		t.Cleanup(srv.Close)
+		s, err := localfs.NewStorage(t.TempDir(), srv.URL+"/api/v1/blobs", nil)
+		if err != nil {
+			t.Fatalf("NewStorage: %v", err)
+		}
+		srv.Config.Handler = h.NewRouter(h.Deps{BlobStore: s})
+		return porttest.BlobStorageHarness{
+			Storage: s,
+			Seed: func(t *testing.T, object string, data []byte, contentType string) {
+				if _, err := s.Put(object, bytes.NewReader(data), contentType); err != nil {
+					t.Fatalf("Put %s: %v", object, err)
+				}
+			},
+			Client: srv.Client(),
+		}
+	})
+}
+
+// TestWriteChunkWaitsOnlyForItsSession checks that a chunk whose body is slow
+// to arrive holds up neither other sessions nor status queries.
+func TestWriteChunkWaitsOnlyForItsSession(t *testing.T) {
+	s, err := localfs.NewStorage(t.TempDir(), "http://localhost/api/v1/blobs", nil)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	start := func(key string) (uri, id string) {
+		t.Helper()
+		uri, err := s.StartResumable(t.Context(), key, port.SignedURLOptions{ExpiredAt: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("StartResumable: %v", err)
+		}
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatalf("parse session uri: %v", err)
+		}
+		return uri, u.Query().Get(localfs.ParamUploadID)
+	}
+	slowURI, slowID := start("cv/slow.pdf")
+	_, fastID := start("cv/fast.pdf")
+
+	body, feed := io.Pipe()
+	slow := make(chan error, 1)
+	go func() {
+		_, err := s.WriteChunk("cv/slow.pdf", slowID, "bytes 0-7/8", body)
+		slow <- err
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := s.WriteChunk("cv/fast.pdf", fastID, "bytes 0-3/4", bytes.NewReader([]byte("fast"))); err != nil {
+			t.Errorf("WriteChunk of another session: %v", err)
+		}
+		if _, err := s.ResumableStatus(t.Context(), slowURI); err != nil {
+			t.Errorf("ResumableStatus of the slow session: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow chunk blocked other sessions")
+	}
+
+	feed.Write([]byte("%PDF-1.7"))
+	feed.Close()
+	if err := <-slow; err != nil {
+		t.Fatalf("slow WriteChunk: %v", err)
+	}
+	if info, err := s.Stat("cv/slow.pdf"); err != nil || info.Size != 8 {
+		t.Fatalf("Stat of the slow upload: got %+v, %v", info, err)
+	}
+}
+
+// TestSignedPutEnforcesMaxSize checks that a PUT URL signed with a MaxSize
+// refuses larger bodies and that the bound cannot be stripped from the URL.
+func TestSignedPutEnforcesMaxSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	s, err := localfs.NewStorage(t.TempDir(), srv.URL+"/api/v1/blobs", nil)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	srv.Config.Handler = h.NewRouter(h.Deps{BlobStore: s})
+
+	uri, err := s.SignedURL(t.Context(), "cv/bounded.pdf", port.SignedURLOptions{
+		Method: "PUT", ExpiredAt: time.Now().Add(time.Hour), MaxSize: 8,
+	})
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	unbounded, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse signed url: %v", err)
+	}
+	q := unbounded.Query()
+	q.Del(localfs.ParamMaxSize)
+	unbounded.RawQuery = q.Encode()
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+		want int
+	}{
+		{"within the bound", uri, "%PDF-1.7", http.StatusOK},
+		{"past the bound", uri, "%PDF-1.7 and more", http.StatusRequestEntityTooLarge},
+		{"bound stripped", unbounded.String(), "%PDF-1.7 and more", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, tt.url, bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("PUT: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status: got %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+	if info, err := s.Stat("cv/bounded.pdf"); err != nil || info.Size != 8 {
+		t.Fatalf("Stat: got %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+
+	"github.com/google/uuid"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	sums        port.Checksums
+}
+
+// postPolicy is the state behind a policy token issued by SignedPostPolicy.
+type postPolicy struct {
+	key              string
+	contentType      string
+	minSize, maxSize int64
+	expires          time.Time
+}
+
+// session is a resumable upload issued by StartResumable.
+type session struct {
+	key         string
+	contentType string
+	maxSize     int64
+	data        []byte
+	complete    bool
+	expires     time.Time
+}
+
+// BlobStorage is a thread-safe in-memory port.BlobStorage. Its signed URLs use
+// the memory:// scheme and are served by the client returned from Client;
+// tests can also seed content directly with Put.
+type BlobStorage struct {
+	faults
+
+	mu       sync.RWMutex
+	objects  map[string]object
+	policies map[string]postPolicy
+	sessions map[string]*session
+}
+
+func NewBlobStorage() *BlobStorage {
+	return &BlobStorage{
+		objects:  make(map[string]object),
+		policies: make(map[string]postPolicy),
+		sessions: make(map[string]*session),
+	}
+}
+
+func (s *BlobStorage) SignedURL(ctx context.Context, objectPath string, opts port.SignedURLOptions) (string, error) {
+	if err := s.check(ctx, OpSignedURL); err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("method", opts.Method)
+	if opts.ContentType != "" {
+		q.Set("content_type", opts.ContentType)
+	}
+	if opts.Checksums.MD5 != "" {
+		q.Set("md5", opts.Checksums.MD5)
+	}
+	if opts.Checksums.CRC32C != "" {
+		q.Set("crc32c", opts.Checksums.CRC32C)
+	}
+	if opts.ContentDisposition != "" {
+		q.Set("response-content-disposition", opts.ContentDisposition)
+	}
+	q.Set("expires", strconv.FormatInt(opts.ExpiredAt.Unix(), 10))
+	u := url.URL{Scheme: "memory", Path: "/" + objectPath, RawQuery: q.Encode()}
+	return u.String(), nil
+}
+
+// SignedPostPolicy issues a form upload to memory:///. The "policy" field is
+// an opaque token; the conditions it stands for are kept by the storage.
+func (s *BlobStorage) SignedPostPolicy(ctx context.Context, objectPath string, opts port.SignedURLOptions) (*port.PostPolicy, error) {
+	if err := s.check(ctx, OpSignedPostPolicy); err != nil {
+		return nil, err
+	}
+	token := uuid.NewString()
+	s.mu.Lock()
+	s.policies[token] = postPolicy{
+		key:         objectPath,
+		contentType: opts.ContentType,
+		minSize:     opts.MinSize,
+		maxSize:     opts.MaxSize,
+		expires:     opts.ExpiredAt,
+	}
+	s.mu.Unlock()
+
+	fields := map[string]string{"key": objectPath, "policy": token}
+	if opts.ContentType != "" {
+		fields["content-type"] = opts.ContentType
+	}
+	return &port.PostPolicy{URL: "memory:///", Fields: fields}, nil
+}
+
+// StartResumable opens a session served at memory:///<key>?upload_id=<id>.
+func (s *BlobStorage) StartResumable(ctx context.Context, objectPath string, opts port.SignedURLOptions) (string, error) {
+	if err := s.check(ctx, OpStartResumable); err != nil {
+		return "", err
+	}
+	id := uuid.NewString()
+	s.mu.Lock()
+	s.sessions[id] = &session{key: objectPath, contentType: opts.ContentType, maxSize: opts.MaxSize, expires: opts.ExpiredAt}
+	s.mu.Unlock()
+	u := url.URL{Scheme: "memory", Path: "/" + objectPath, RawQuery: url.Values{"upload_id": {id}}.Encode()}
+	return u.String(), nil
+}
+
+func (s *BlobStorage) ResumableStatus(ctx context.Context, sessionURI string) (port.UploadStatus, error) {
+	if err := s.check(ctx, OpResumableStatus); err != nil {
+		return port.UploadStatus{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.session(sessionURI)
+	if !ok {
+		return port.UploadStatus{}, fmt.Errorf("%w: %s", port.ErrSessionNotFound, sessionURI)
+	}
+	return port.UploadStatus{Received: int64(len(sess.data)), Complete: sess.complete}, nil
+}
+
+// session looks up a live session by URI; s.mu must be held.
+func (s *BlobStorage) session(sessionURI string) (*session, bool) {
+	u, err := url.Parse(sessionURI)
+	if err != nil {
+		return nil, false
+	}
+	sess, ok := s.sessions[u.Query().Get("upload_id")]
+	if !ok || !sess.complete && !time.Now().Before(sess.expires) {
+		return nil, false
+	}
+	return sess, true
+}
+
+// NewWriter buffers the content and stores it on Close, failing with the
+// error injected for OpCloseWriter, if any.
+func (s *BlobStorage) NewWriter(ctx context.Context, objectPath, contentType string) (port.ObjectWriter, error) {
+	if err := s.check(ctx, OpNewWriter); err != nil {
+		return nil, err
+	}
+	return &writer{s: s, ctx: context.WithoutCancel(ctx), key: objectPath, contentType: contentType}, nil
+}
+
+var errWriterClosed = errors.New("memory: writer already closed")
+
+type writer struct {
+	s           *BlobStorage
+	ctx         context.Context
+	key         string
+	contentType string
+	buf         bytes.Buffer
+	done        bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, errWriterClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	if w.done {
+		return errWriterClosed
+	}
+	w.done = true
+	if err := w.s.check(w.ctx, OpCloseWriter); err != nil {
+		return err
+	}
+	w.s.Put(w.key, w.buf.Bytes(), w.contentType)
+	return nil
+}
+
+func (w *writer) Abort() error {
+	w.done = true
+	w.buf.Reset()
+	return nil
+}
+
+func (s *BlobStorage) Head(ctx context.Context, objectPath string) (port.ObjectAttrs, bool, error) {
+	if err := s.check(ctx, OpHead); err != nil {
+		return port.ObjectAttrs{}, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[objectPath]
+	if !ok {
+		return port.ObjectAttrs{}, false, nil
+	}
+	return port.ObjectAttrs{Size: int64(len(obj.data)), ContentType: obj.contentType, Checksums: obj.sums}, true, nil
+}
+
+func (s *BlobStorage) Read(ctx context.Context, objectPath string, offset, length int64) (io.ReadCloser, error) {
+	if err := s.check(ctx, OpRead); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[objectPath]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", port.ErrObjectNotFound, objectPath)
+	}
+	data := obj.data[min(max(offset, 0), int64(len(obj.data))):]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return io.NopCloser(bytes.NewReader(append([]byte(nil), data...))), nil
+}
+
+func (s *BlobStorage) Delete(ctx context.Context, objectPath string) error {
+	if err := s.check(ctx, OpDelete); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, objectPath)
+	return nil
+}
+
+// Put stores data under objectPath as if a client had uploaded it through a
+// signed URL.
+func (s *BlobStorage) Put(objectPath string, data []byte, contentType string) {
+	md5Sum, crc32cSum := checksum.Sum(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[objectPath] = object{
+		data:        append([]byte(nil), data...),
+		contentType: contentType,
+		sums:        port.Checksums{MD5: md5Sum, CRC32C: crc32cSum},
+	}
+}
+
+// Get returns a copy of the object stored under objectPath.
+func (s *BlobStorage) Get(objectPath string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[objectPath]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), obj.data...), true
+}
+
+var _ port.BlobStorage = (*BlobStorage)(nil)
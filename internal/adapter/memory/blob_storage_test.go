@@ -0,0 +1,21 @@
+package memory_test
+
+import (
+	"testing"
+
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/port/porttest"
+)
+
+func TestBlobStorageConformance(t *testing.T) {
+	porttest.RunBlobStorageConformance(t, func(t *testing.T) porttest.BlobStorageHarness {
+		s := memory.NewBlobStorage()
+		return porttest.BlobStorageHarness{
+			Storage: s,
+			Seed: func(t *testing.T, object string, data []byte, contentType string) {
+				s.Put(object, data, contentType)
+			},
+			Client: s.Client(),
+		}
+	})
+}
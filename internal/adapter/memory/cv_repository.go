@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/cursor"
+)
+
+// CVRepository is a thread-safe in-memory port.CVRepository. List evaluates
+// filters with port.CVFilter.Matches and orders with port.CVQuery.Compare.
+type CVRepository struct {
+	faults
+
+	cursors *cursor.Codec
+
+	mu  sync.RWMutex
+	cvs map[string]domain.CV
+}
+
+// NewCVRepository returns an empty repository. Its cursors are signed with a
+// per-instance random key.
+func NewCVRepository() *CVRepository {
+	return &CVRepository{cvs: make(map[string]domain.CV), cursors: cursor.NewCodec(nil)}
+}
+
+var _ port.CVRepository = (*CVRepository)(nil)
+
+func (r *CVRepository) Create(ctx context.Context, cv *domain.CV) error {
+	if err := r.check(ctx, OpCreate); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cvs[cv.ID]; ok {
+		return fmt.Errorf("%w: %s", port.ErrCVAlreadyExists, cv.ID)
+	}
+	r.cvs[cv.ID] = *cv
+	return nil
+}
+
+func (r *CVRepository) Update(ctx context.Context, cv *domain.CV) error {
+	if err := r.check(ctx, OpUpdate); err != nil {
+		return err
+	}
+	return r.update(cv, nil)
+}
+
+func (r *CVRepository) UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error {
+	if err := r.check(ctx, OpUpdateIfStatus); err != nil {
+		return err
+	}
+	return r.update(cv, &from)
+}
+
+// update replaces the stored copy of cv, keeping its RefCount. With a non-nil
+// from, the stored copy must be in that status.
+func (r *CVRepository) update(cv *domain.CV, from *domain.CVStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.cvs[cv.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, cv.ID)
+	}
+	if from != nil && stored.Status != *from {
+		return fmt.Errorf("%w: %s is %s, not %s", port.ErrCVStatusChanged, cv.ID, stored.Status, *from)
+	}
+	updated := *cv
+	updated.RefCount = stored.RefCount
+	r.cvs[cv.ID] = updated
+	return nil
+}
+
+func (r *CVRepository) FindByID(ctx context.Context, id string) (*domain.CV, error) {
+	if err := r.check(ctx, OpFindByID); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cv, ok := r.cvs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	return &cv, nil
+}
+
+func (r *CVRepository) List(ctx context.Context, q port.CVQuery) ([]domain.CV, string, error) {
+	if err := r.check(ctx, OpList); err != nil {
+		return nil, "", err
+	}
+	if err := q.Validate(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidQuery, err)
+	}
+	q = q.Normalized()
+
+	var (
+		after    cursor.Position
+		hasAfter bool
+	)
+	if q.Cursor != "" {
+		pos, err := r.cursors.Decode(q.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidCursor, err)
+		}
+		if pos.Scope != q.Scope() {
+			return nil, "", fmt.Errorf("%w: issued for another query", port.ErrInvalidCursor)
+		}
+		after, hasAfter = pos, true
+	}
+
+	r.mu.RLock()
+	matched := make([]domain.CV, 0, len(r.cvs))
+	for _, cv := range r.cvs {
+		if !q.Filter.Matches(cv) {
+			continue
+		}
+		if hasAfter && q.Compare(after, q.PositionOf(cv)) >= 0 {
+			continue
+		}
+		matched = append(matched, cv)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return q.Compare(q.PositionOf(matched[i]), q.PositionOf(matched[j])) < 0
+	})
+
+	if q.Limit <= 0 || len(matched) <= q.Limit {
+		return matched, "", nil
+	}
+	page := matched[:q.Limit]
+	return page, r.cursors.Encode(q.PositionOf(page[len(page)-1])), nil
+}
+
+func (r *CVRepository) FindByContent(ctx context.Context, q port.ContentQuery) (*domain.CV, error) {
+	if err := r.check(ctx, OpFindByContent); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var found *domain.CV
+	for _, cv := range r.cvs {
+		if !q.Matches(cv) {
+			continue
+		}
+		if found == nil || cv.CreatedAt.Before(found.CreatedAt) ||
+			cv.CreatedAt.Equal(found.CreatedAt) && cv.ID < found.ID {
+			found = &cv
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: no cv with md5 %s", port.ErrCVNotFound, q.MD5)
+	}
+	return found, nil
+}
+
+func (r *CVRepository) AddRef(ctx context.Context, id string, delta int) (int, error) {
+	if err := r.check(ctx, OpAddRef); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cv, ok := r.cvs[id]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	cv.RefCount += delta
+	r.cvs[id] = cv
+	return cv.RefCount, nil
+}
+
+func (r *CVRepository) Delete(ctx context.Context, id string) error {
+	if err := r.check(ctx, OpDelete); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cvs[id]; !ok {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	delete(r.cvs, id)
+	return nil
+}
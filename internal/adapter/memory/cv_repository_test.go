@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/port"
+	"cv-platform/internal/port/porttest"
+)
+
+func TestCVRepositoryConformance(t *testing.T) {
+	porttest.RunCVRepositoryConformance(t, func(t *testing.T) port.CVRepository {
+		return memory.NewCVRepository()
+	})
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Op names an operation of the in-memory adapters for failure injection.
+type Op string
+
+const (
+	OpCreate           Op = "Create"
+	OpUpdate           Op = "Update"
+	OpUpdateIfStatus   Op = "UpdateIfStatus"
+	OpFindByID         Op = "FindByID"
+	OpList             Op = "List"
+	OpFindByContent    Op = "FindByContent"
+	OpAddRef           Op = "AddRef"
+	OpSignedURL        Op = "SignedURL"
+	OpSignedPostPolicy Op = "SignedPostPolicy"
+	OpStartResumable   Op = "StartResumable"
+	OpResumableStatus  Op = "ResumableStatus"
+	OpNewWriter        Op = "NewWriter"
+	OpCloseWriter      Op = "CloseWriter"
+	OpHead             Op = "Head"
+	OpRead             Op = "Read"
+	OpDelete           Op = "Delete"
+)
+
+// faults holds the injected failures of an adapter. It is embedded so that
+// FailOn and ClearFailures are available on every in-memory adapter.
+type faults struct {
+	mu     sync.Mutex
+	errs   map[Op]error
+	delays map[Op]time.Duration
+}
+
+// FailOn makes every subsequent call of op return err until ClearFailures is
+// called. Passing a nil err removes the failure for op.
+func (f *faults) FailOn(op Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.errs == nil {
+		f.errs = make(map[Op]error)
+	}
+	if err == nil {
+		delete(f.errs, op)
+		return
+	}
+	f.errs[op] = err
+}
+
+// DelayOn makes every subsequent call of op wait for d, or until its context
+// is done, until ClearFailures is called. Passing 0 removes the delay.
+func (f *faults) DelayOn(op Op, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.delays == nil {
+		f.delays = make(map[Op]time.Duration)
+	}
+	if d <= 0 {
+		delete(f.delays, op)
+		return
+	}
+	f.delays[op] = d
+}
+
+// ClearFailures removes all injected failures and delays.
+func (f *faults) ClearFailures() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs = nil
+	f.delays = nil
+}
+
+// check returns the failure injected for op, after its delay, or the error
+// of ctx once it is done.
+func (f *faults) check(ctx context.Context, op Op) error {
+	f.mu.Lock()
+	err, delay := f.errs[op], f.delays[op]
+	f.mu.Unlock()
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+		case <-t.C:
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
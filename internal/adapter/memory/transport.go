@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/resumable"
+)
+
+// Client returns an http.Client that serves the memory:// URLs issued by
+// SignedURL, SignedPostPolicy and StartResumable, enforcing the signed method,
+// content type, checksums, size range and expiry, so code uploading through signed URLs can be
+// exercised without a network.
+func (s *BlobStorage) Client() *http.Client {
+	return &http.Client{Transport: transport{s: s}}
+}
+
+type transport struct {
+	s *BlobStorage
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "memory" {
+		return nil, fmt.Errorf("memory: unsupported url scheme %q", req.URL.Scheme)
+	}
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	if req.Method == http.MethodPost && req.URL.Path == "/" {
+		return t.postForm(req)
+	}
+	q := req.URL.Query()
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	if q.Has("upload_id") {
+		return t.resumable(req)
+	}
+
+	exp, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	switch {
+	case err != nil:
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	case !time.Now().Before(time.Unix(exp, 0)):
+		return respond(req, http.StatusForbidden, nil, ""), nil
+	case !strings.EqualFold(req.Method, q.Get("method")):
+		return respond(req, http.StatusForbidden, nil, ""), nil
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		ctype := req.Header.Get("Content-Type")
+		if want := q.Get("content_type"); want != "" && !strings.EqualFold(ctype, want) {
+			return respond(req, http.StatusForbidden, nil, ""), nil
+		}
+		// Signed checksums must be sent; sent checksums must match the data.
+		gotMD5 := req.Header.Get(checksum.HeaderMD5)
+		_, gotCRC32C := checksum.ParseHashHeader(req.Header.Values(checksum.HeaderHash))
+		if want := q.Get("md5"); want != "" && gotMD5 != want {
+			return respond(req, http.StatusForbidden, nil, ""), nil
+		}
+		if want := q.Get("crc32c"); want != "" && gotCRC32C != want {
+			return respond(req, http.StatusForbidden, nil, ""), nil
+		}
+		var data []byte
+		if req.Body != nil {
+			if data, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+		}
+		md5Sum, crc32cSum := checksum.Sum(data)
+		if gotMD5 != "" && gotMD5 != md5Sum || gotCRC32C != "" && gotCRC32C != crc32cSum {
+			return respond(req, http.StatusBadRequest, nil, ""), nil
+		}
+		t.s.Put(key, data, ctype)
+		return respond(req, http.StatusOK, nil, ""), nil
+	case http.MethodGet:
+		t.s.mu.RLock()
+		obj, ok := t.s.objects[key]
+		t.s.mu.RUnlock()
+		if !ok {
+			return respond(req, http.StatusNotFound, nil, ""), nil
+		}
+		resp := respond(req, http.StatusOK, obj.data, obj.contentType)
+		if v := q.Get("response-content-disposition"); v != "" {
+			resp.Header.Set("Content-Disposition", v)
+		}
+		return resp, nil
+	default:
+		return respond(req, http.StatusMethodNotAllowed, nil, ""), nil
+	}
+}
+
+// postForm handles a multipart upload made with a SignedPostPolicy form.
+func (t transport) postForm(req *http.Request) (*http.Response, error) {
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	}
+	fields := map[string]string{}
+	var data []byte
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return respond(req, http.StatusBadRequest, nil, ""), nil
+		}
+		b, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			data = b
+			break
+		}
+		fields[strings.ToLower(part.FormName())] = string(b)
+	}
+
+	t.s.mu.RLock()
+	p, ok := t.s.policies[fields["policy"]]
+	t.s.mu.RUnlock()
+	switch {
+	case !ok || data == nil:
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	case !time.Now().Before(p.expires),
+		fields["key"] != p.key,
+		p.contentType != "" && !strings.EqualFold(fields["content-type"], p.contentType):
+		return respond(req, http.StatusForbidden, nil, ""), nil
+	case int64(len(data)) < p.minSize, p.maxSize > 0 && int64(len(data)) > p.maxSize:
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	}
+	t.s.Put(p.key, data, fields["content-type"])
+	return respond(req, http.StatusNoContent, nil, ""), nil
+}
+
+// resumable handles a chunk or status query sent to a session URI.
+func (t transport) resumable(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPut {
+		return respond(req, http.StatusMethodNotAllowed, nil, ""), nil
+	}
+	cr, err := resumable.ParseContentRange(req.Header.Get("Content-Range"))
+	if err != nil {
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	}
+	var body io.Reader = http.NoBody
+	if req.Body != nil {
+		body = req.Body
+	}
+
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	sess, ok := t.s.session(req.URL.String())
+	switch {
+	case !ok:
+		return respond(req, http.StatusNotFound, nil, ""), nil
+	case sess.complete:
+		return respond(req, http.StatusOK, nil, ""), nil
+	case sess.maxSize > 0 && (cr.Total > sess.maxSize || cr.HasData && cr.Last >= sess.maxSize):
+		return respond(req, http.StatusRequestEntityTooLarge, nil, ""), nil
+	}
+	buf := bytes.NewBuffer(sess.data)
+	received, err := cr.Append(buf, body, int64(len(sess.data)))
+	sess.data = buf.Bytes()
+	if err != nil {
+		return respond(req, http.StatusBadRequest, nil, ""), nil
+	}
+	if !cr.Complete(received) {
+		resp := respond(req, resumable.StatusIncomplete, nil, "")
+		if r := resumable.RangeHeader(received); r != "" {
+			resp.Header.Set("Range", r)
+		}
+		return resp, nil
+	}
+	md5Sum, crc32cSum := checksum.Sum(sess.data)
+	t.s.objects[sess.key] = object{
+		data:        sess.data,
+		contentType: sess.contentType,
+		sums:        port.Checksums{MD5: md5Sum, CRC32C: crc32cSum},
+	}
+	sess.complete = true
+	return respond(req, http.StatusOK, nil, ""), nil
+}
+
+func respond(req *http.Request, code int, body []byte, ctype string) *http.Response {
+	h := http.Header{}
+	if ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
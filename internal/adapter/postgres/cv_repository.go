@@ -0,0 +1,337 @@
+// Package postgres implements port.CVRepository on PostgreSQL. The schema is
+// created by the embedded migrations (see Migrate), which must have been
+// applied before the repository is used.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/cursor"
+)
+
+// Open connects to the database at dsn, a postgres:// URL or key=value
+// connection string, and checks that it is reachable.
+func Open(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fromPG(err)
+	}
+	return pool, nil
+}
+
+type CVRepository struct {
+	pool    *pgxpool.Pool
+	cursors *cursor.Codec
+}
+
+// NewCVRepository builds a repository on pool. cursors signs the List
+// cursors; a nil codec uses a random key, so cursors do not survive a
+// restart.
+func NewCVRepository(pool *pgxpool.Pool, cursors *cursor.Codec) *CVRepository {
+	if cursors == nil {
+		cursors = cursor.NewCodec(nil)
+	}
+	return &CVRepository{pool: pool, cursors: cursors}
+}
+
+const cvColumns = `id, owner_id, file_name, mime_type, detected_mime_type, size, gcs_path,
+	md5, crc32c, duplicate_of, ref_count, status, reject_reason,
+	created_at, updated_at, upload_expires_at, upload_session_uri`
+
+func (r *CVRepository) Create(ctx context.Context, cv *domain.CV) error {
+	_, err := r.pool.Exec(ctx, `INSERT INTO cvs (`+cvColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		cvArgs(cv)...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "cvs_pkey" {
+		return fmt.Errorf("%w: %s", port.ErrCVAlreadyExists, cv.ID)
+	}
+	return fromPG(err)
+}
+
+// Update replaces every field of the stored CV but ref_count, which only
+// AddRef changes. It returns ErrCVNotFound when no CV has the ID.
+func (r *CVRepository) Update(ctx context.Context, cv *domain.CV) error {
+	tag, err := r.pool.Exec(ctx, updateCV+` WHERE id = $1`, updateArgs(cv)...)
+	if err != nil {
+		return fromPG(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, cv.ID)
+	}
+	return nil
+}
+
+// UpdateIfStatus is Update guarded by the stored status in the same
+// statement. When no row matches, a lookup tells a missing CV from one whose
+// status changed.
+func (r *CVRepository) UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error {
+	tag, err := r.pool.Exec(ctx, updateCV+` WHERE id = $1 AND status = $17`,
+		append(updateArgs(cv), string(from))...)
+	if err != nil {
+		return fromPG(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return r.statusChanged(ctx, cv.ID, from)
+	}
+	return nil
+}
+
+// updateCV sets the columns of updateArgs; callers append the WHERE clause.
+const updateCV = `UPDATE cvs SET
+	owner_id = $2, file_name = $3, mime_type = $4, detected_mime_type = $5, size = $6, gcs_path = $7,
+	md5 = $8, crc32c = $9, duplicate_of = $10, status = $11, reject_reason = $12,
+	created_at = $13, updated_at = $14, upload_expires_at = $15, upload_session_uri = $16`
+
+// statusChanged is the error of a conditional update of CV id that matched
+// no row.
+func (r *CVRepository) statusChanged(ctx context.Context, id string, from domain.CVStatus) error {
+	var status string
+	err := r.pool.QueryRow(ctx, `SELECT status FROM cvs WHERE id = $1`, id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return fromPG(err)
+	}
+	return fmt.Errorf("%w: %s is %s, not %s", port.ErrCVStatusChanged, id, status, from)
+}
+
+func (r *CVRepository) FindByID(ctx context.Context, id string) (*domain.CV, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+cvColumns+` FROM cvs WHERE id = $1`, id)
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	cv, err := pgx.CollectExactlyOneRow(rows, scanCV)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	return &cv, nil
+}
+
+var sortColumns = map[port.CVSortField]string{
+	port.CVSortCreatedAt: "created_at",
+	port.CVSortUpdatedAt: "updated_at",
+	port.CVSortSize:      "size",
+}
+
+// List pages through the CVs matching q with keyset pagination: the row
+// comparison (sort column, id) against the cursor position walks the
+// (column, id) indexes, so deep pages cost the same as the first one and stay
+// stable while new CVs are inserted.
+func (r *CVRepository) List(ctx context.Context, q port.CVQuery) ([]domain.CV, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidQuery, err)
+	}
+	q = q.Normalized()
+
+	col := sortColumns[q.SortBy]
+	dir, after := "DESC", "<"
+	if q.Order == port.SortAsc {
+		dir, after = "ASC", ">"
+	}
+
+	w := filterWhere(q.Filter)
+	if q.Cursor != "" {
+		pos, err := r.cursors.Decode(q.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidCursor, err)
+		}
+		if pos.Scope != q.Scope() {
+			return nil, "", fmt.Errorf("%w: issued for another query", port.ErrInvalidCursor)
+		}
+		var key any = pos.Time
+		if q.SortBy == port.CVSortSize {
+			key = pos.Int
+		}
+		w.add(fmt.Sprintf("(%s, id) %s (%s, %s)", col, after, w.arg(key), w.arg(pos.ID)))
+	}
+
+	sql := `SELECT ` + cvColumns + ` FROM cvs` + w.String() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", col, dir, dir)
+	if q.Limit > 0 {
+		// Fetch one extra row to learn whether another page exists.
+		sql += " LIMIT " + w.arg(q.Limit+1)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, w.args...)
+	if err != nil {
+		return nil, "", fromPG(err)
+	}
+	out, err := pgx.CollectRows(rows, scanCV)
+	if err != nil {
+		return nil, "", fromPG(err)
+	}
+	if q.Limit <= 0 || len(out) <= q.Limit {
+		return out, "", nil
+	}
+	out = out[:q.Limit]
+	return out, r.cursors.Encode(q.PositionOf(out[q.Limit-1])), nil
+}
+
+func filterWhere(f port.CVFilter) *where {
+	w := &where{}
+	if f.OwnerID != "" {
+		w.add("owner_id = " + w.arg(f.OwnerID))
+	}
+	if f.Status != "" {
+		w.add("status = " + w.arg(string(f.Status)))
+	}
+	if f.MimeType != "" {
+		w.add("mime_type = " + w.arg(f.MimeType))
+	}
+	if !f.CreatedFrom.IsZero() {
+		w.add("created_at >= " + w.arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		w.add("created_at < " + w.arg(f.CreatedTo))
+	}
+	if f.MinSize > 0 {
+		w.add("size >= " + w.arg(f.MinSize))
+	}
+	if f.MaxSize > 0 {
+		w.add("size <= " + w.arg(f.MaxSize))
+	}
+	if f.FileNamePrefix != "" {
+		w.add("starts_with(file_name, " + w.arg(f.FileNamePrefix) + ")")
+	}
+	return w
+}
+
+// where builds a WHERE clause with numbered parameters.
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string) { w.conds = append(w.conds, cond) }
+
+// arg binds v and returns its placeholder.
+func (w *where) arg(v any) string {
+	w.args = append(w.args, v)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// contentStatuses are the statuses of CVs FindByContent may return; see
+// domain.CVStatus.HasAcceptedContent.
+var contentStatuses = []string{
+	string(domain.CVStatusUploaded),
+	string(domain.CVStatusScanning),
+	string(domain.CVStatusProcessing),
+	string(domain.CVStatusReady),
+}
+
+func (r *CVRepository) FindByContent(ctx context.Context, q port.ContentQuery) (*domain.CV, error) {
+	w := &where{}
+	w.add("md5 = " + w.arg(q.MD5))
+	w.add("crc32c = " + w.arg(q.CRC32C))
+	w.add("size = " + w.arg(q.Size))
+	if q.ScopeToOwner {
+		w.add("owner_id = " + w.arg(q.OwnerID))
+	}
+	w.add("duplicate_of = ''")
+	w.add("status = ANY(" + w.arg(contentStatuses) + ")")
+
+	rows, err := r.pool.Query(ctx, `SELECT `+cvColumns+` FROM cvs`+w.String()+
+		` ORDER BY created_at, id LIMIT 1`, w.args...)
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	cv, err := pgx.CollectExactlyOneRow(rows, scanCV)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no cv with md5 %s", port.ErrCVNotFound, q.MD5)
+	}
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	return &cv, nil
+}
+
+// AddRef updates the count in place, so concurrent links and unlinks of the
+// same canonical CV are not lost.
+func (r *CVRepository) AddRef(ctx context.Context, id string, delta int) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `UPDATE cvs SET ref_count = ref_count + $2 WHERE id = $1 RETURNING ref_count`,
+		id, delta).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return 0, fromPG(err)
+	}
+	return count, nil
+}
+
+func (r *CVRepository) Delete(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM cvs WHERE id = $1`, id)
+	if err != nil {
+		return fromPG(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	return nil
+}
+
+// cvArgs are the values of cvColumns for cv. A zero UploadExpiresAt is
+// stored as NULL.
+func cvArgs(cv *domain.CV) []any {
+	return []any{
+		cv.ID, cv.OwnerID, cv.FileName, cv.MimeType, cv.DetectedMimeType, cv.Size, cv.GCSPath,
+		cv.MD5, cv.CRC32C, cv.DuplicateOf, cv.RefCount, string(cv.Status), cv.RejectReason,
+		cv.CreatedAt, cv.UpdatedAt, pgtype.Timestamptz{Time: cv.UploadExpiresAt, Valid: !cv.UploadExpiresAt.IsZero()},
+		cv.UploadSessionURI,
+	}
+}
+
+// updateArgs are cvArgs without ref_count, so that Update does not overwrite
+// the count of concurrent AddRef calls with a stale one.
+func updateArgs(cv *domain.CV) []any {
+	args := cvArgs(cv)
+	return append(args[:10:10], args[11:]...)
+}
+
+func scanCV(row pgx.CollectableRow) (domain.CV, error) {
+	var (
+		cv      domain.CV
+		status  string
+		expires pgtype.Timestamptz
+	)
+	err := row.Scan(
+		&cv.ID, &cv.OwnerID, &cv.FileName, &cv.MimeType, &cv.DetectedMimeType, &cv.Size, &cv.GCSPath,
+		&cv.MD5, &cv.CRC32C, &cv.DuplicateOf, &cv.RefCount, &status, &cv.RejectReason,
+		&cv.CreatedAt, &cv.UpdatedAt, &expires, &cv.UploadSessionURI,
+	)
+	cv.Status = domain.CVStatus(status)
+	cv.CreatedAt, cv.UpdatedAt = cv.CreatedAt.UTC(), cv.UpdatedAt.UTC()
+	if expires.Valid {
+		cv.UploadExpiresAt = expires.Time.UTC()
+	}
+	return cv, err
+}
+
+var _ port.CVRepository = (*CVRepository)(nil)
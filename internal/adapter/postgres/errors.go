@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"cv-platform/internal/domain"
+)
+
+// SQLSTATE codes classified by fromPG; see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	lockNotAvailable     = "55P03"
+	undefinedTable       = "42P01"
+	undefinedColumn      = "42703"
+)
+
+// fromPG classifies a PostgreSQL error by its SQLSTATE. Connection failures
+// and server shutdowns are ErrUnavailable; schema errors, e.g. a database
+// that was not migrated, are ErrPreconditionFailed.
+func fromPG(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		var connErr *pgconn.ConnectError
+		if pgconn.Timeout(err) || errors.As(err, &connErr) || errors.Is(err, context.DeadlineExceeded) {
+			return domain.WithKind(domain.ErrUnavailable, err)
+		}
+		return err
+	}
+	var kind error
+	switch code := pgErr.Code; {
+	case code == uniqueViolation, code == serializationFailure, code == deadlockDetected, code == lockNotAvailable:
+		kind = domain.ErrConflict
+	case strings.HasPrefix(code, "22"), strings.HasPrefix(code, "23"): // data exception, integrity constraint
+		kind = domain.ErrValidation
+	case code == undefinedTable, code == undefinedColumn:
+		kind = domain.ErrPreconditionFailed
+	case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57P"): // connection, resources, shutdown
+		kind = domain.ErrUnavailable
+	default:
+		return err
+	}
+	return domain.WithKind(kind, err)
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"cv-platform/pkg/migration"
+)
+
+// migrationFiles are the schema migrations; see package migration.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the advisory lock serializing concurrent migrators, e.g.
+// several instances starting at once.
+const migrationLockKey = 0x63762d6d69677261 // "cv-migra"
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]migration.Migration, error) {
+	return migration.Load(migrationFiles, "migrations/*.sql")
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    integer PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// Migrate applies the pending migrations, each in its own transaction, and
+// returns them. It holds an advisory lock meanwhile, so concurrent calls apply
+// every migration once.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) ([]migration.Migration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return nil, fromPG(err)
+	}
+	defer func() {
+		// Unlock even when ctx is done; a failure closes the session, which
+		// releases the lock too.
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return nil, fromPG(err)
+	}
+	pending, err := pendingMigrations(ctx, conn.Conn())
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range pending {
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.SQL); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %s: %w", m, fromPG(err))
+		}
+	}
+	return pending, nil
+}
+
+// PendingMigrations returns the migrations Migrate would apply.
+func PendingMigrations(ctx context.Context, pool *pgxpool.Pool) ([]migration.Migration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	defer conn.Release()
+	return pendingMigrations(ctx, conn.Conn())
+}
+
+func pendingMigrations(ctx context.Context, conn *pgx.Conn) ([]migration.Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fromPG(err)
+	}
+	if !exists {
+		return all, nil
+	}
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	applied, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fromPG(err)
+	}
+	return migration.Pending(all, applied), nil
+}
@@ -0,0 +1,31 @@
+-- CV metadata. IDs and file names use the "C" collation so keyset pagination
+-- and prefix filters compare bytes, like the other repositories.
+CREATE TABLE cvs (
+    id                 text COLLATE "C" PRIMARY KEY,
+    owner_id           text NOT NULL DEFAULT '',
+    file_name          text COLLATE "C" NOT NULL,
+    mime_type          text NOT NULL,
+    detected_mime_type text NOT NULL DEFAULT '',
+    size               bigint NOT NULL DEFAULT 0,
+    gcs_path           text NOT NULL,
+    md5                text NOT NULL DEFAULT '',
+    crc32c             text NOT NULL DEFAULT '',
+    duplicate_of       text NOT NULL DEFAULT '',
+    ref_count          integer NOT NULL DEFAULT 0,
+    status             text NOT NULL,
+    reject_reason      text NOT NULL DEFAULT '',
+    created_at         timestamptz NOT NULL,
+    updated_at         timestamptz NOT NULL,
+    upload_expires_at  timestamptz,
+    upload_session_uri text NOT NULL DEFAULT ''
+);
+
+-- Keyset pagination: one index per sort field, ID as tie-breaker. They serve
+-- both directions.
+CREATE INDEX cvs_created_at_idx ON cvs (created_at, id);
+CREATE INDEX cvs_updated_at_idx ON cvs (updated_at, id);
+CREATE INDEX cvs_size_idx ON cvs (size, id);
+CREATE INDEX cvs_status_created_at_idx ON cvs (status, created_at, id);
+
+-- Duplicate detection only looks at canonical CVs.
+CREATE INDEX cvs_content_idx ON cvs (md5, crc32c, size, owner_id) WHERE duplicate_of = '';
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"cv-platform/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type errorStatus struct {
+	status int
+	code   string
+}
+
+// kindStatuses maps domain error kinds to their response.
+var kindStatuses = map[error]errorStatus{
+	domain.ErrNotFound:           {http.StatusNotFound, ErrorCodeNotFound},
+	domain.ErrConflict:           {http.StatusConflict, ErrorCodeConflict},
+	domain.ErrValidation:         {http.StatusBadRequest, ErrorCodeValidationFailed},
+	domain.ErrRejected:           {http.StatusUnprocessableEntity, ErrorCodeValidationFailed},
+	domain.ErrPreconditionFailed: {http.StatusPreconditionFailed, ErrorCodePreconditionFailed},
+	domain.ErrUnavailable:        {http.StatusServiceUnavailable, ErrorCodeUnavailable},
+	domain.ErrUnauthenticated:    {http.StatusUnauthorized, ErrorCodeUnauthorized},
+	domain.ErrPermissionDenied:   {http.StatusForbidden, ErrorCodeForbidden},
+}
+
+// StatusOf returns the HTTP status and error code of err, chosen by its
+// domain error kind. Errors of no kind are internal errors.
+func StatusOf(err error) (int, string) {
+	if s, ok := kindStatuses[domain.KindOf(err)]; ok {
+		return s.status, s.code
+	}
+	return http.StatusInternalServerError, ErrorCodeInternalError
+}
+
+// RespondErr creates the error response of err (see StatusOf), listing the
+// offending fields of validation errors
+func RespondErr(c *gin.Context, err error) {
+	status, code := StatusOf(err)
+	var fields []FieldError
+	for _, f := range domain.FieldsOf(err) {
+		fields = append(fields, FieldError{Field: f.Field, Message: f.Message})
+	}
+	respondFields(c, status, code, err.Error(), fields)
+}
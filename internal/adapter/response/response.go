@@ -15,18 +15,29 @@ type APIResponse struct {
 
 // APIError contains error details for failed requests
 type APIError struct {
-	Code    string `json:"code"`    // internal or business error code (e.g., INVALID_REQUEST)
-	Message string `json:"message"` // human-readable error message for client
+	Code    string       `json:"code"`             // internal or business error code (e.g., INVALID_REQUEST)
+	Message string       `json:"message"`          // human-readable error message for client
+	Fields  []FieldError `json:"fields,omitempty"` // per-field details of validation errors
+}
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`   // request field name (e.g., file_name)
+	Message string `json:"message"` // human-readable reason
 }
 
 // Common error codes
 const (
-	ErrorCodeInvalidRequest   = "INVALID_REQUEST"
-	ErrorCodeNotFound         = "NOT_FOUND"
-	ErrorCodeInternalError    = "INTERNAL_ERROR"
-	ErrorCodeValidationFailed = "VALIDATION_FAILED"
-	ErrorCodeUnauthorized     = "UNAUTHORIZED"
-	ErrorCodeForbidden        = "FORBIDDEN"
+	ErrorCodeInvalidRequest     = "INVALID_REQUEST"
+	ErrorCodeNotFound           = "NOT_FOUND"
+	ErrorCodeInternalError      = "INTERNAL_ERROR"
+	ErrorCodeValidationFailed   = "VALIDATION_FAILED"
+	ErrorCodeUnauthorized       = "UNAUTHORIZED"
+	ErrorCodeForbidden          = "FORBIDDEN"
+	ErrorCodeConflict           = "CONFLICT"
+	ErrorCodeChecksumMismatch   = "CHECKSUM_MISMATCH"
+	ErrorCodePreconditionFailed = "PRECONDITION_FAILED"
+	ErrorCodeUnavailable        = "SERVICE_UNAVAILABLE"
 )
 
 // RespondSuccess creates a successful API response
@@ -63,7 +74,19 @@ func RespondInternalErr(c *gin.Context, message string) {
 	RespondError(c, http.StatusInternalServerError, ErrorCodeInternalError, message)
 }
 
-// RespondValidationErr creates a 400 validation error response
-func RespondValidationErr(c *gin.Context, message string) {
-	RespondError(c, http.StatusBadRequest, ErrorCodeValidationFailed, message)
+// RespondValidationErr creates a 400 validation error response, optionally
+// listing the offending fields
+func RespondValidationErr(c *gin.Context, message string, fields ...FieldError) {
+	respondFields(c, http.StatusBadRequest, ErrorCodeValidationFailed, message, fields)
+}
+
+func respondFields(c *gin.Context, statusCode int, code, message string, fields []FieldError) {
+	c.JSON(statusCode, APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:    code,
+			Message: message,
+			Fields:  fields,
+		},
+	})
 }
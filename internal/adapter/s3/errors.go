@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"cv-platform/internal/domain"
+)
+
+// fromS3 classifies an S3 error by its error code, or else by the HTTP status
+// of the response. Failures of the service's own credentials stay internal
+// errors: the caller cannot fix them.
+func fromS3(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchUpload":
+			return domain.WithKind(domain.ErrNotFound, err)
+		case "BadDigest", "InvalidDigest", "EntityTooSmall", "EntityTooLarge", "InvalidArgument", "InvalidRange":
+			return domain.WithKind(domain.ErrValidation, err)
+		case "SlowDown", "ServiceUnavailable", "InternalError", "RequestTimeout":
+			return domain.WithKind(domain.ErrUnavailable, err)
+		}
+	}
+	if code := statusCode(err); code != 0 {
+		if kind := kindOfStatus(code); kind != nil {
+			return domain.WithKind(kind, err)
+		}
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return domain.WithKind(domain.ErrUnavailable, err)
+	}
+	return err
+}
+
+// statusCode is the HTTP status of the response err reports, or 0.
+func statusCode(err error) int {
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// kindOfStatus is the kind of an HTTP error status of S3, or nil.
+func kindOfStatus(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return domain.ErrNotFound
+	case code == http.StatusConflict:
+		return domain.ErrConflict
+	case code == http.StatusBadRequest:
+		return domain.ErrValidation
+	case code == http.StatusPreconditionFailed:
+		return domain.ErrPreconditionFailed
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout, code >= 500:
+		return domain.ErrUnavailable
+	}
+	return nil
+}
+
+// isNotFound tells whether err reports a missing object.
+func isNotFound(err error) bool {
+	return errors.Is(fromS3(err), domain.ErrNotFound)
+}
@@ -0,0 +1,689 @@
+// Package s3test provides an in-process fake of the parts of the S3 REST API
+// used by s3.S3Storage: object PUT, GET, HEAD and DELETE, multipart uploads,
+// SigV4 presigned URLs and SigV4 POST policies. The bucket is addressed path
+// style. Every request must be signed with the server's access key, either in
+// the Authorization header or in a presigned query string, and is verified
+// like S3 does: a URL presigned for another method or content type, or used
+// after its expiry, is rejected, as is a form upload breaking its policy
+// conditions or content that does not match its Content-MD5 or
+// x-amz-checksum-crc32c headers.
+package s3test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cv-platform/pkg/checksum"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	s3api "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Credentials and region the fake server trusts.
+const (
+	AccessKeyID     = "AKIAFAKES3TEST000000"
+	SecretAccessKey = "fake/secret/access/key/for/s3test/only"
+	Region          = "us-east-1"
+)
+
+const (
+	algorithm  = "AWS4-HMAC-SHA256"
+	timeFormat = "20060102T150405Z"
+	// maxSkew is how far the time of a header-signed request may be from
+	// the server's.
+	maxSkew = 15 * time.Minute
+)
+
+// Object is a stored object. ETag is quoted, as S3 sends it. CRC32C is the
+// base64 encoded checksum the object was uploaded with, if any.
+type Object struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+	CRC32C      string
+	Updated     time.Time
+}
+
+// Server is a fake S3 endpoint for a single bucket.
+type Server struct {
+	Bucket string
+
+	srv *httptest.Server
+	now func() time.Time
+
+	mu      sync.RWMutex
+	objects map[string]Object
+	uploads map[string]*upload
+}
+
+// upload is a multipart upload in progress.
+type upload struct {
+	key         string
+	contentType string
+	parts       map[int32]part
+}
+
+type part struct {
+	data   []byte
+	etag   string
+	crc32c string
+}
+
+// NewServer starts a fake S3 server for bucket. Call Close when done.
+func NewServer(bucket string) *Server {
+	s := &Server{
+		Bucket:  bucket,
+		now:     time.Now,
+		objects: make(map[string]Object),
+		uploads: make(map[string]*upload),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() { s.srv.Close() }
+
+// URL is the base URL of the server, e.g. http://127.0.0.1:1234.
+func (s *Server) URL() string { return s.srv.URL }
+
+// Client returns an S3 client talking to the server with path-style
+// addressing and the server's credentials.
+func (s *Server) Client() *s3api.Client {
+	return s3api.New(s3api.Options{
+		Region:                     Region,
+		BaseEndpoint:               aws.String(s.srv.URL),
+		UsePathStyle:               true,
+		Credentials:                credentials.NewStaticCredentialsProvider(AccessKeyID, SecretAccessKey, ""),
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+}
+
+// Put stores an object directly, as if uploaded with a CRC32C checksum.
+func (s *Server) Put(key string, data []byte, contentType string) {
+	_, crc32cSum := checksum.Sum(data)
+	s.put(key, data, contentType, crc32cSum)
+}
+
+func (s *Server) put(key string, data []byte, contentType, crc32cSum string) {
+	sum := md5.Sum(data)
+	s.store(key, Object{
+		Data:        append([]byte(nil), data...),
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		CRC32C:      crc32cSum,
+	})
+}
+
+func (s *Server) store(key string, obj Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj.Updated = s.now()
+	s.objects[key] = obj
+}
+
+// Get returns the object stored under key.
+func (s *Server) Get(key string) (Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[key]
+	return obj, ok
+}
+
+// Uploads returns the number of multipart uploads neither completed nor
+// aborted.
+func (s *Server) Uploads() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.uploads)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path != "/"+s.Bucket && !strings.HasPrefix(path, "/"+s.Bucket+"/") {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(path, "/"+s.Bucket), "/")
+	if key == "" {
+		if r.Method == http.MethodPost {
+			s.servePost(w, r)
+			return
+		}
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	if code, reason := s.verify(r); code != 0 {
+		writeError(w, r, code, reason)
+		return
+	}
+
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.createUpload(w, r, key)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		s.uploadPart(w, r)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		s.completeUpload(w, r, key)
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		s.mu.Lock()
+		_, ok := s.uploads[q.Get("uploadId")]
+		delete(s.uploads, q.Get("uploadId"))
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		data, code, reason := readBody(r)
+		if code != 0 {
+			writeError(w, r, code, reason)
+			return
+		}
+		s.put(key, data, r.Header.Get("Content-Type"), r.Header.Get("X-Amz-Checksum-Crc32c"))
+		obj, _ := s.Get(key)
+		w.Header().Set("ETag", obj.ETag)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		s.serveRead(w, r, key)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// readBody reads the body of an upload and checks it against its Content-MD5
+// and x-amz-checksum-crc32c headers, when present.
+func readBody(r *http.Request) ([]byte, int, string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, "IncompleteBody"
+	}
+	md5Sum, crc32cSum := checksum.Sum(data)
+	if want := r.Header.Get("Content-MD5"); want != "" && want != md5Sum {
+		return nil, http.StatusBadRequest, "BadDigest"
+	}
+	if want := r.Header.Get("X-Amz-Checksum-Crc32c"); want != "" && want != crc32cSum {
+		return nil, http.StatusBadRequest, "BadDigest"
+	}
+	return data, 0, ""
+}
+
+// serveRead serves GET and HEAD, honouring Range headers and the
+// response-content-disposition override of presigned URLs. The CRC32C is only
+// sent when asked for with x-amz-checksum-mode and the whole object is read.
+func (s *Server) serveRead(w http.ResponseWriter, r *http.Request, key string) {
+	obj, ok := s.Get(key)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", obj.ContentType)
+	h.Set("ETag", obj.ETag)
+	if v := r.URL.Query().Get("response-content-disposition"); v != "" {
+		h.Set("Content-Disposition", v)
+	}
+	if obj.CRC32C != "" && r.Header.Get("Range") == "" && strings.EqualFold(r.Header.Get("X-Amz-Checksum-Mode"), "ENABLED") {
+		h.Set("X-Amz-Checksum-Crc32c", obj.CRC32C)
+		typ := "FULL_OBJECT"
+		if strings.Contains(obj.CRC32C, "-") {
+			typ = "COMPOSITE"
+		}
+		h.Set("X-Amz-Checksum-Type", typ)
+	}
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, _, ok := parseRange(rng)
+		if ok && start >= int64(len(obj.Data)) {
+			writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			return
+		}
+	}
+	http.ServeContent(w, r, "", obj.Updated, bytes.NewReader(obj.Data))
+}
+
+// parseRange parses a single "bytes=start-[end]" range.
+func parseRange(v string) (start, end int64, ok bool) {
+	first, last, found := strings.Cut(strings.TrimPrefix(v, "bytes="), "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	end = -1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
+
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, key string) {
+	id := rand.Text()
+	s.mu.Lock()
+	s.uploads[id] = &upload{key: key, contentType: r.Header.Get("Content-Type"), parts: make(map[int32]part)}
+	s.mu.Unlock()
+	writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: s.Bucket, Key: key, UploadId: id})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request) {
+	num, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 32)
+	if err != nil || num < 1 || num > 10000 {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+	data, code, reason := readBody(r)
+	if code != 0 {
+		writeError(w, r, code, reason)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	up, ok := s.uploads[r.URL.Query().Get("uploadId")]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	sum := md5.Sum(data)
+	p := part{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`, crc32c: r.Header.Get("X-Amz-Checksum-Crc32c")}
+	up.parts[int32(num)] = p
+	w.Header().Set("ETag", p.etag)
+	if p.crc32c != "" {
+		w.Header().Set("X-Amz-Checksum-Crc32c", p.crc32c)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// minPartSize is the minimum size of every part but the last.
+const minPartSize = 5 << 20
+
+// completeUpload assembles the listed parts. Like S3, the ETag of the object
+// is the MD5 of the part MD5s followed by the part count, and so is the
+// CRC32C when the parts carried one.
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request, key string) {
+	var body struct {
+		Parts []struct {
+			PartNumber     int32
+			ETag           string
+			ChecksumCRC32C string
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Parts) == 0 {
+		writeError(w, r, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	s.mu.Lock()
+	id := r.URL.Query().Get("uploadId")
+	up, ok := s.uploads[id]
+	if !ok || up.key != key {
+		s.mu.Unlock()
+		writeError(w, r, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	var (
+		data       []byte
+		md5s, crcs []byte
+		prev       int32
+	)
+	for i, ref := range body.Parts {
+		p, ok := up.parts[ref.PartNumber]
+		if !ok || p.etag != ref.ETag || ref.PartNumber <= prev {
+			s.mu.Unlock()
+			writeError(w, r, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+		if i < len(body.Parts)-1 && len(p.data) < minPartSize {
+			s.mu.Unlock()
+			writeError(w, r, http.StatusBadRequest, "EntityTooSmall")
+			return
+		}
+		prev = ref.PartNumber
+		data = append(data, p.data...)
+		sum, _ := hex.DecodeString(strings.Trim(p.etag, `"`))
+		md5s = append(md5s, sum...)
+		if crc, err := base64.StdEncoding.DecodeString(p.crc32c); err == nil && p.crc32c != "" {
+			crcs = append(crcs, crc...)
+		}
+	}
+	delete(s.uploads, id)
+	s.mu.Unlock()
+
+	n := strconv.Itoa(len(body.Parts))
+	etag := md5.Sum(md5s)
+	obj := Object{
+		Data:        data,
+		ContentType: up.contentType,
+		ETag:        `"` + hex.EncodeToString(etag[:]) + "-" + n + `"`,
+	}
+	if len(crcs) == 4*len(body.Parts) {
+		_, composite := checksum.Sum(crcs)
+		obj.CRC32C = composite + "-" + n
+	}
+	s.store(key, obj)
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: s.Bucket, Key: key, ETag: obj.ETag})
+}
+
+// verify checks the SigV4 signature of a request, carried either in the
+// Authorization header or in the query string of a presigned URL. It returns
+// a non-zero status and an error code when the request must be rejected.
+func (s *Server) verify(r *http.Request) (int, string) {
+	q := r.URL.Query()
+	var (
+		credential, signedHeaders, signature, amzDate, payloadHash string
+		presigned                                                  = q.Has("X-Amz-Algorithm")
+	)
+	if presigned {
+		if q.Get("X-Amz-Algorithm") != algorithm {
+			return http.StatusBadRequest, "AuthorizationQueryParametersError"
+		}
+		credential, signedHeaders, signature = q.Get("X-Amz-Credential"), q.Get("X-Amz-SignedHeaders"), q.Get("X-Amz-Signature")
+		amzDate, payloadHash = q.Get("X-Amz-Date"), "UNSIGNED-PAYLOAD"
+	} else {
+		auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), algorithm+" ")
+		if !ok {
+			return http.StatusForbidden, "AccessDenied"
+		}
+		for _, kv := range strings.Split(auth, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(kv), "=")
+			switch k {
+			case "Credential":
+				credential = v
+			case "SignedHeaders":
+				signedHeaders = v
+			case "Signature":
+				signature = v
+			}
+		}
+		amzDate, payloadHash = r.Header.Get("X-Amz-Date"), r.Header.Get("X-Amz-Content-Sha256")
+		if code, reason := checkPayload(r, payloadHash); code != 0 {
+			return code, reason
+		}
+	}
+
+	scope, ok := strings.CutPrefix(credential, AccessKeyID+"/")
+	if !ok {
+		return http.StatusForbidden, "InvalidAccessKeyId"
+	}
+	date, err := time.Parse(timeFormat, amzDate)
+	if err != nil {
+		return http.StatusBadRequest, "AuthorizationQueryParametersError"
+	}
+	if presigned {
+		expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+		if err != nil || expires < 1 || expires > 7*24*3600 {
+			return http.StatusBadRequest, "AuthorizationQueryParametersError"
+		}
+		if !s.now().Before(date.Add(time.Duration(expires) * time.Second)) {
+			return http.StatusForbidden, "AccessDenied"
+		}
+	} else if d := s.now().Sub(date); d > maxSkew || d < -maxSkew {
+		return http.StatusForbidden, "RequestTimeTooSkewed"
+	}
+	if !s.validScope(scope, date) {
+		return http.StatusForbidden, "SignatureDoesNotMatch"
+	}
+
+	query := url.Values{}
+	for k, v := range q {
+		if k != "X-Amz-Signature" {
+			query[k] = v
+		}
+	}
+	var headers []string
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var v string
+		switch name {
+		case "host":
+			v = r.Host
+		case "content-length":
+			v = strconv.FormatInt(r.ContentLength, 10)
+		default:
+			vals := r.Header.Values(name)
+			for i := range vals {
+				vals[i] = strings.Join(strings.Fields(vals[i]), " ")
+			}
+			v = strings.Join(vals, ",")
+		}
+		headers = append(headers, name+":"+v)
+	}
+	sort.Strings(headers)
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{algorithm, amzDate, scope, hex.EncodeToString(sum[:])}, "\n")
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(hmacSHA256(signingKey(date), toSign)))) {
+		return http.StatusForbidden, "SignatureDoesNotMatch"
+	}
+	return 0, ""
+}
+
+// checkPayload checks the body of a header-signed request against its
+// x-amz-content-sha256 header, unless the header declares it unsigned.
+func checkPayload(r *http.Request, payloadHash string) (int, string) {
+	switch payloadHash {
+	case "":
+		return http.StatusBadRequest, "InvalidRequest"
+	case "UNSIGNED-PAYLOAD":
+		return 0, ""
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, "IncompleteBody"
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != payloadHash {
+		return http.StatusBadRequest, "XAmzContentSHA256Mismatch"
+	}
+	return 0, ""
+}
+
+// validScope checks a credential scope: date/region/s3/aws4_request.
+func (s *Server) validScope(scope string, date time.Time) bool {
+	return scope == date.UTC().Format("20060102")+"/"+Region+"/s3/aws4_request"
+}
+
+// servePost handles a multipart form upload made with a SigV4 POST policy.
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "MalformedPOSTRequest")
+		return
+	}
+	fields := map[string]string{}
+	var data []byte
+	for {
+		p, err := mr.NextPart()
+		if err != nil { // including io.EOF: the file part is mandatory
+			writeError(w, r, http.StatusBadRequest, "MalformedPOSTRequest")
+			return
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		if p.FormName() == "file" {
+			data = b
+			break
+		}
+		fields[strings.ToLower(p.FormName())] = string(b)
+	}
+
+	if code, reason := s.verifyPolicy(fields, int64(len(data))); code != 0 {
+		writeError(w, r, code, reason)
+		return
+	}
+	_, crc32cSum := checksum.Sum(data)
+	s.put(fields["key"], data, fields["content-type"], crc32cSum)
+
+	code := http.StatusNoContent
+	if v, err := strconv.Atoi(fields["success_action_status"]); err == nil {
+		code = v
+	}
+	w.WriteHeader(code)
+}
+
+// verifyPolicy checks the signature and the conditions of a POST policy
+// against the submitted form fields and file size. Like S3, it requires every
+// submitted field to be covered by a condition.
+func (s *Server) verifyPolicy(fields map[string]string, size int64) (int, string) {
+	if fields["x-amz-algorithm"] != algorithm {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	scope, ok := strings.CutPrefix(fields["x-amz-credential"], AccessKeyID+"/")
+	if !ok {
+		return http.StatusForbidden, "InvalidAccessKeyId"
+	}
+	date, err := time.Parse(timeFormat, fields["x-amz-date"])
+	if err != nil || !s.validScope(scope, date) {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	want := hex.EncodeToString(hmacSHA256(signingKey(date), fields["policy"]))
+	if !hmac.Equal([]byte(fields["x-amz-signature"]), []byte(want)) {
+		return http.StatusForbidden, "SignatureDoesNotMatch"
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(fields["policy"])
+	if err != nil {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	var policy struct {
+		Expiration time.Time         `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return http.StatusBadRequest, "InvalidPolicyDocument"
+	}
+	if !s.now().Before(policy.Expiration) {
+		return http.StatusForbidden, "AccessDenied"
+	}
+
+	covered := map[string]bool{"policy": true, "x-amz-signature": true, "file": true}
+	for _, c := range policy.Conditions {
+		var exact map[string]string
+		if json.Unmarshal(c, &exact) == nil {
+			for k, v := range exact {
+				k = strings.ToLower(k)
+				got := fields[k]
+				if k == "bucket" {
+					got = s.Bucket
+				}
+				if got != v {
+					return http.StatusForbidden, "AccessDenied"
+				}
+				covered[k] = true
+			}
+			continue
+		}
+		var op []any
+		if err := json.Unmarshal(c, &op); err != nil || len(op) != 3 {
+			return http.StatusBadRequest, "InvalidPolicyDocument"
+		}
+		switch name, _ := op[0].(string); name {
+		case "content-length-range":
+			lo, _ := op[1].(float64)
+			hi, _ := op[2].(float64)
+			if size < int64(lo) {
+				return http.StatusBadRequest, "EntityTooSmall"
+			}
+			if size > int64(hi) {
+				return http.StatusBadRequest, "EntityTooLarge"
+			}
+		case "eq", "starts-with":
+			field, _ := op[1].(string)
+			want, _ := op[2].(string)
+			field = strings.ToLower(strings.TrimPrefix(field, "$"))
+			got := fields[field]
+			if name == "eq" && got != want || name == "starts-with" && !strings.HasPrefix(got, want) {
+				return http.StatusForbidden, "AccessDenied"
+			}
+			covered[field] = true
+		default:
+			return http.StatusBadRequest, "InvalidPolicyDocument"
+		}
+	}
+	for k := range fields {
+		if !covered[k] && !strings.HasPrefix(k, "x-ignore-") {
+			return http.StatusForbidden, "AccessDenied"
+		}
+	}
+	return 0, ""
+}
+
+// signingKey derives the SigV4 key of the server's secret for date.
+func signingKey(date time.Time) []byte {
+	k := hmacSHA256([]byte("AWS4"+SecretAccessKey), date.UTC().Format("20060102"))
+	k = hmacSHA256(k, Region)
+	k = hmacSHA256(k, "s3")
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an S3 error document; HEAD responses carry the status
+// only.
+func writeError(w http.ResponseWriter, r *http.Request, code int, reason string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, reason, reason)
+}
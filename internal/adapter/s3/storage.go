@@ -0,0 +1,431 @@
+// Package s3 implements port.BlobStorage on Amazon S3 and S3-compatible
+// stores such as MinIO. Uploads and downloads go through SigV4 presigned URLs
+// and POST policies.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	s3api "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+)
+
+// maxPresignExpiry is the longest validity SigV4 presigned URLs may have.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// partSize is the size of the parts of multipart uploads made by writers;
+// smaller objects are stored with a single PUT. S3 requires at least 5 MiB.
+const partSize = 8 << 20
+
+type S3Storage struct {
+	client  *s3api.Client
+	presign *s3api.PresignClient
+	bucket  string
+}
+
+// S3Option customises the client built by NewS3Storage.
+type S3Option func(*s3api.Options)
+
+// WithRegion sets the region requests are signed for, overriding AWS_REGION
+// and the shared config.
+func WithRegion(region string) S3Option {
+	return func(o *s3api.Options) { o.Region = region }
+}
+
+// WithEndpoint sends requests to endpoint, e.g. http://minio:9000, instead of
+// AWS. Presigned URLs point at it too.
+func WithEndpoint(endpoint string) S3Option {
+	return func(o *s3api.Options) { o.BaseEndpoint = aws.String(endpoint) }
+}
+
+// WithPathStyle addresses the bucket in the URL path (endpoint/bucket/key)
+// rather than in the host name (bucket.endpoint/key), as MinIO and most
+// S3-compatible stores expect.
+func WithPathStyle(enabled bool) S3Option {
+	return func(o *s3api.Options) { o.UsePathStyle = enabled }
+}
+
+// WithStaticCredentials signs requests with an access key instead of the
+// default credential chain (environment, shared config, instance role).
+func WithStaticCredentials(accessKeyID, secretAccessKey string) S3Option {
+	return func(o *s3api.Options) {
+		o.Credentials = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")
+	}
+}
+
+// NewS3Storage builds a storage on bucket with the default AWS configuration,
+// customised by opts.
+func NewS3Storage(ctx context.Context, bucket string, opts ...S3Option) (*S3Storage, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s3: load aws config: %w", err)
+	}
+	optFns := []func(*s3api.Options){func(o *s3api.Options) {
+		// Only send the checksums this package sets itself: S3-compatible
+		// stores do not all support the SDK's default trailing checksums.
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	}}
+	for _, opt := range opts {
+		optFns = append(optFns, opt)
+	}
+	cl := s3api.NewFromConfig(cfg, optFns...)
+	if cl.Options().Region == "" {
+		return nil, errors.New("s3: no region configured")
+	}
+	return NewS3StorageFromClient(cl, bucket), nil
+}
+
+// NewS3StorageFromClient builds an S3Storage on an existing client.
+func NewS3StorageFromClient(cl *s3api.Client, bucket string) *S3Storage {
+	return &S3Storage{client: cl, presign: s3api.NewPresignClient(cl), bucket: bucket}
+}
+
+// SignedURL presigns a PUT or GET of object. Content-Type, Content-MD5 and
+// X-Goog-Hash become signed headers. S3 verifies the uploaded bytes against
+// Content-MD5 but ignores X-Goog-Hash, so a CRC32C alone is only checked when
+// the upload completes.
+func (s *S3Storage) SignedURL(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	expires, err := expiresIn(opts.ExpiredAt)
+	if err != nil {
+		return "", err
+	}
+	presignOpts := func(o *s3api.PresignOptions) { o.Expires = expires }
+
+	switch method := strings.ToUpper(opts.Method); method {
+	case "", http.MethodPut:
+		in := &s3api.PutObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(object)}
+		if opts.ContentType != "" {
+			in.ContentType = aws.String(opts.ContentType)
+		}
+		if opts.Checksums.MD5 != "" {
+			in.ContentMD5 = aws.String(opts.Checksums.MD5)
+		}
+		var headerOpts []func(*s3api.PresignOptions)
+		if opts.Checksums.CRC32C != "" {
+			hash := checksum.HashHeader("", opts.Checksums.CRC32C)
+			headerOpts = append(headerOpts, s3api.WithPresignClientFromClientOptions(
+				s3api.WithAPIOptions(smithyhttp.AddHeaderValue(checksum.HeaderHash, hash))))
+		}
+		r, err := s.presign.PresignPutObject(ctx, in, append(headerOpts, presignOpts)...)
+		if err != nil {
+			return "", fromS3(err)
+		}
+		return r.URL, nil
+	case http.MethodGet:
+		in := &s3api.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(object)}
+		if opts.ContentDisposition != "" {
+			in.ResponseContentDisposition = aws.String(opts.ContentDisposition)
+		}
+		r, err := s.presign.PresignGetObject(ctx, in, presignOpts)
+		if err != nil {
+			return "", fromS3(err)
+		}
+		return r.URL, nil
+	default:
+		return "", domain.NewError(domain.ErrValidation, fmt.Sprintf("s3: cannot sign %s urls", method))
+	}
+}
+
+// SignedPostPolicy signs a SigV4 POST policy bound to the key and content
+// type, with a content-length-range condition when opts.MaxSize is set, so S3
+// itself refuses oversized uploads.
+func (s *S3Storage) SignedPostPolicy(ctx context.Context, object string, opts port.SignedURLOptions) (*port.PostPolicy, error) {
+	expires, err := expiresIn(opts.ExpiredAt)
+	if err != nil {
+		return nil, err
+	}
+	var conds []any
+	if opts.ContentType != "" {
+		conds = append(conds, map[string]string{"Content-Type": opts.ContentType})
+	}
+	if opts.MaxSize > 0 {
+		conds = append(conds, []any{"content-length-range", max(opts.MinSize, 0), opts.MaxSize})
+	}
+	r, err := s.presign.PresignPostObject(ctx,
+		&s3api.PutObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(object)},
+		func(o *s3api.PresignPostOptions) {
+			o.Expires = expires
+			o.Conditions = conds
+		})
+	if err != nil {
+		return nil, fromS3(err)
+	}
+	if opts.ContentType != "" {
+		r.Values["Content-Type"] = opts.ContentType
+	}
+	return &port.PostPolicy{URL: r.URL, Fields: r.Values}, nil
+}
+
+// expiresIn is the validity of a presigned request expiring at t. S3 cannot
+// sign already expired requests, nor ones valid for more than a week.
+func expiresIn(t time.Time) (time.Duration, error) {
+	d := time.Until(t).Truncate(time.Second)
+	switch {
+	case d <= 0:
+		return 0, domain.NewError(domain.ErrValidation, "s3: presigned request would already be expired")
+	case d > maxPresignExpiry:
+		return 0, domain.NewError(domain.ErrValidation, fmt.Sprintf("s3: presigned requests expire within %s", maxPresignExpiry))
+	}
+	return d, nil
+}
+
+// StartResumable always fails: S3 has no resumable upload protocol clients
+// could use directly. Large files go through tus and NewWriter instead.
+func (s *S3Storage) StartResumable(ctx context.Context, object string, opts port.SignedURLOptions) (string, error) {
+	return "", port.ErrResumableUnsupported
+}
+
+// ResumableStatus knows no sessions, as StartResumable opens none.
+func (s *S3Storage) ResumableStatus(ctx context.Context, sessionURI string) (port.UploadStatus, error) {
+	return port.UploadStatus{}, fmt.Errorf("%w: %s", port.ErrSessionNotFound, sessionURI)
+}
+
+// NewWriter buffers the object and stores it with a single PUT on Close, or
+// with a multipart upload once it outgrows partSize. Abort aborts the
+// multipart upload, which makes S3 discard the parts.
+func (s *S3Storage) NewWriter(ctx context.Context, object, contentType string) (port.ObjectWriter, error) {
+	return &objectWriter{
+		ctx:         context.WithoutCancel(ctx),
+		s:           s,
+		key:         object,
+		contentType: contentType,
+	}, nil
+}
+
+// Head reports the object's CRC32C when it was uploaded with one, as writers
+// do, and its MD5 when the ETag is one: S3 only uses the MD5 as ETag for
+// objects stored with a single PUT and without SSE-KMS or SSE-C.
+func (s *S3Storage) Head(ctx context.Context, object string) (port.ObjectAttrs, bool, error) {
+	out, err := s.client.HeadObject(ctx, &s3api.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(object),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return port.ObjectAttrs{}, false, nil
+		}
+		return port.ObjectAttrs{}, false, fromS3(err)
+	}
+	res := port.ObjectAttrs{
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+	}
+	// Multipart uploads have composite checksums, "<checksum of checksums>-<parts>".
+	if sum := aws.ToString(out.ChecksumCRC32C); sum != "" && !strings.Contains(sum, "-") &&
+		out.ChecksumType != types.ChecksumTypeComposite {
+		res.Checksums.CRC32C = sum
+	}
+	kms := out.ServerSideEncryption == types.ServerSideEncryptionAwsKms ||
+		out.ServerSideEncryption == types.ServerSideEncryptionAwsKmsDsse
+	if !kms && out.SSECustomerAlgorithm == nil {
+		res.Checksums.MD5 = etagMD5(aws.ToString(out.ETag))
+	}
+	return res, true, nil
+}
+
+// etagMD5 returns the base64 encoded MD5 an ETag holds, or "" when it holds
+// none, e.g. for multipart uploads.
+func etagMD5(etag string) string {
+	b, err := hex.DecodeString(strings.Trim(etag, `"`))
+	if err != nil || len(b) != md5.Size {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// Read streams the range from S3. The returned reader is bound to a context
+// derived from ctx that is cancelled on Close.
+func (s *S3Storage) Read(ctx context.Context, object string, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		// S3 ignores empty ranges and would send the whole object.
+		if _, exists, err := s.Head(ctx, object); err != nil || !exists {
+			return nil, readError(object, err)
+		}
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	in := &s3api.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(object)}
+	if length > 0 {
+		in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		in.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	out, err := s.client.GetObject(ctx, in)
+	if err != nil {
+		cancel()
+		if statusCode(err) == http.StatusRequestedRangeNotSatisfiable {
+			// The object exists but ends before offset.
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, readError(object, err)
+	}
+	return &cancelReader{ReadCloser: out.Body, cancel: cancel}, nil
+}
+
+// readError is the error of a read of object that failed with err, or found
+// no object when err is nil.
+func readError(object string, err error) error {
+	if err == nil || isNotFound(err) {
+		return fmt.Errorf("%w: %s", port.ErrObjectNotFound, object)
+	}
+	return fromS3(err)
+}
+
+func (s *S3Storage) Delete(ctx context.Context, object string) error {
+	_, err := s.client.DeleteObject(ctx, &s3api.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(object),
+	})
+	if isNotFound(err) {
+		return nil
+	}
+	return fromS3(err)
+}
+
+type cancelReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// objectWriter uploads the content written to it in partSize parts. Every
+// request carries the CRC32C of its body, which S3 verifies and keeps.
+type objectWriter struct {
+	ctx         context.Context
+	s           *S3Storage
+	key         string
+	contentType string
+
+	buf      []byte
+	uploadID string
+	parts    []types.CompletedPart
+	err      error
+}
+
+var errWriterClosed = errors.New("s3: writer already closed")
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= partSize {
+		if err := w.uploadPart(w.buf[:partSize]); err != nil {
+			w.err = err
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[partSize:]...)
+	}
+	return len(p), nil
+}
+
+func (w *objectWriter) uploadPart(part []byte) error {
+	if w.uploadID == "" {
+		out, err := w.s.client.CreateMultipartUpload(w.ctx, &s3api.CreateMultipartUploadInput{
+			Bucket:            aws.String(w.s.bucket),
+			Key:               aws.String(w.key),
+			ContentType:       aws.String(w.contentType),
+			ChecksumAlgorithm: types.ChecksumAlgorithmCrc32c,
+		})
+		if err != nil {
+			return fromS3(err)
+		}
+		w.uploadID = aws.ToString(out.UploadId)
+	}
+	md5Sum, crc32cSum := checksum.Sum(part)
+	num := aws.Int32(int32(len(w.parts) + 1))
+	out, err := w.s.client.UploadPart(w.ctx, &s3api.UploadPartInput{
+		Bucket:         aws.String(w.s.bucket),
+		Key:            aws.String(w.key),
+		UploadId:       aws.String(w.uploadID),
+		PartNumber:     num,
+		Body:           bytes.NewReader(part),
+		ContentMD5:     aws.String(md5Sum),
+		ChecksumCRC32C: aws.String(crc32cSum),
+	})
+	if err != nil {
+		return fromS3(err)
+	}
+	w.parts = append(w.parts, types.CompletedPart{ETag: out.ETag, PartNumber: num, ChecksumCRC32C: out.ChecksumCRC32C})
+	return nil
+}
+
+func (w *objectWriter) Close() error {
+	if err := w.err; err != nil {
+		if err != errWriterClosed {
+			_ = w.Abort()
+		}
+		return err
+	}
+	if w.uploadID == "" {
+		w.err = errWriterClosed
+		md5Sum, crc32cSum := checksum.Sum(w.buf)
+		_, err := w.s.client.PutObject(w.ctx, &s3api.PutObjectInput{
+			Bucket:         aws.String(w.s.bucket),
+			Key:            aws.String(w.key),
+			ContentType:    aws.String(w.contentType),
+			Body:           bytes.NewReader(w.buf),
+			ContentMD5:     aws.String(md5Sum),
+			ChecksumCRC32C: aws.String(crc32cSum),
+		})
+		return fromS3(err)
+	}
+	if len(w.buf) > 0 {
+		if err := w.uploadPart(w.buf); err != nil {
+			_ = w.Abort()
+			return err
+		}
+	}
+	w.err = errWriterClosed
+	_, err := w.s.client.CompleteMultipartUpload(w.ctx, &s3api.CompleteMultipartUploadInput{
+		Bucket:          aws.String(w.s.bucket),
+		Key:             aws.String(w.key),
+		UploadId:        aws.String(w.uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: w.parts},
+	})
+	return fromS3(err)
+}
+
+func (w *objectWriter) Abort() error {
+	w.err, w.buf = errWriterClosed, nil
+	if w.uploadID == "" {
+		return nil
+	}
+	_, err := w.s.client.AbortMultipartUpload(w.ctx, &s3api.AbortMultipartUploadInput{
+		Bucket:   aws.String(w.s.bucket),
+		Key:      aws.String(w.key),
+		UploadId: aws.String(w.uploadID),
+	})
+	w.uploadID = ""
+	if isNotFound(err) {
+		return nil
+	}
+	return fromS3(err)
+}
+
+var _ port.BlobStorage = (*S3Storage)(nil)
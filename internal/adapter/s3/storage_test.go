@@ -0,0 +1,212 @@
+package s3_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cv-platform/internal/adapter/s3"
+	"cv-platform/internal/adapter/s3/s3test"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/internal/port/porttest"
+	"cv-platform/pkg/checksum"
+)
+
+func newStorage(t *testing.T) (*s3test.Server, *s3.S3Storage) {
+	t.Helper()
+	srv := s3test.NewServer("cvs")
+	t.Cleanup(srv.Close)
+	return srv, s3.NewS3StorageFromClient(srv.Client(), "cvs")
+}
+
+func TestS3StorageConformance(t *testing.T) {
+	porttest.RunBlobStorageConformance(t, func(t *testing.T) porttest.BlobStorageHarness {
+		srv, st := newStorage(t)
+		return porttest.BlobStorageHarness{
+			Storage: st,
+			Seed: func(t *testing.T, object string, data []byte, contentType string) {
+				srv.Put(object, data, contentType)
+			},
+			Client:      http.DefaultClient,
+			NoResumable: true,
+		}
+	})
+}
+
+func TestSignedURLPutBindsHeaders(t *testing.T) {
+	srv, st := newStorage(t)
+	data := []byte("%PDF-1.7 presigned")
+	md5Sum, _ := checksum.Sum(data)
+	u, err := st.SignedURL(t.Context(), "cv/a.pdf", port.SignedURLOptions{
+		Method:      http.MethodPut,
+		ContentType: "application/pdf",
+		ExpiredAt:   time.Now().Add(time.Minute),
+		Checksums:   port.Checksums{MD5: md5Sum},
+	})
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatalf("parse %q: %v", u, err)
+	}
+	signed := parsed.Query().Get("X-Amz-SignedHeaders")
+	for _, h := range []string{"content-md5", "content-type"} {
+		if !strings.Contains(signed, h) {
+			t.Errorf("X-Amz-SignedHeaders = %q, want %s signed", signed, h)
+		}
+	}
+
+	put := func(contentType string, body []byte) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Content-MD5", md5Sum)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := put("text/html", data); code != http.StatusForbidden {
+		t.Errorf("PUT with another content type: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := put("application/pdf", []byte("%PDF-1.7 tampered!")); code < 400 {
+		t.Errorf("PUT of content not matching Content-MD5: got %d, want an error", code)
+	}
+	if _, ok := srv.Get("cv/a.pdf"); ok {
+		t.Fatalf("rejected upload was stored")
+	}
+	if code := put("application/pdf", data); code != http.StatusOK {
+		t.Fatalf("PUT: got %d, want %d", code, http.StatusOK)
+	}
+	obj, ok := srv.Get("cv/a.pdf")
+	if !ok || !bytes.Equal(obj.Data, data) || obj.ContentType != "application/pdf" {
+		t.Fatalf("stored object: got %+v, %t", obj, ok)
+	}
+}
+
+func TestSignedURLGet(t *testing.T) {
+	srv, st := newStorage(t)
+	data := []byte("%PDF-1.7 download")
+	srv.Put("cv/b.pdf", data, "application/pdf")
+
+	u, err := st.SignedURL(t.Context(), "cv/b.pdf", port.SignedURLOptions{
+		Method:             http.MethodGet,
+		ExpiredAt:          time.Now().Add(time.Minute),
+		ContentDisposition: `attachment; filename="cv.pdf"`,
+	})
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, data) {
+		t.Fatalf("GET: got %d %q, want %d %q", resp.StatusCode, got, http.StatusOK, data)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); cd != `attachment; filename="cv.pdf"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+}
+
+func TestSignedURLRejectsUnsignableRequests(t *testing.T) {
+	_, st := newStorage(t)
+	for _, tc := range []struct {
+		name string
+		opts port.SignedURLOptions
+	}{
+		{"method", port.SignedURLOptions{Method: http.MethodDelete, ExpiredAt: time.Now().Add(time.Minute)}},
+		{"expired", port.SignedURLOptions{Method: http.MethodGet, ExpiredAt: time.Now().Add(-time.Minute)}},
+		{"beyond a week", port.SignedURLOptions{Method: http.MethodGet, ExpiredAt: time.Now().Add(8 * 24 * time.Hour)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := st.SignedURL(t.Context(), "cv/c.pdf", tc.opts); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("SignedURL: got err %v, want %v", err, domain.ErrValidation)
+			}
+		})
+	}
+}
+
+func TestSignedPostPolicy(t *testing.T) {
+	srv, st := newStorage(t)
+	pp, err := st.SignedPostPolicy(t.Context(), "cv/d.pdf", port.SignedURLOptions{
+		Method:      http.MethodPost,
+		ContentType: "application/pdf",
+		ExpiredAt:   time.Now().Add(time.Minute),
+		MinSize:     1,
+		MaxSize:     32,
+	})
+	if err != nil {
+		t.Fatalf("SignedPostPolicy: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		fields map[string]string
+		data   string
+		want   int
+	}{
+		{"too large", nil, strings.Repeat("x", 33), http.StatusBadRequest},
+		{"empty", nil, "", http.StatusBadRequest},
+		{"other key", map[string]string{"key": "cv/other.pdf"}, "%PDF-1.7", http.StatusForbidden},
+		{"other content type", map[string]string{"Content-Type": "text/html"}, "%PDF-1.7", http.StatusForbidden},
+		{"within policy", nil, "%PDF-1.7 posted", http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := maps.Clone(pp.Fields)
+			maps.Copy(fields, tc.fields)
+			if code := postForm(t, pp.URL, fields, []byte(tc.data)); code != tc.want {
+				t.Fatalf("POST: got %d, want %d", code, tc.want)
+			}
+		})
+	}
+	obj, ok := srv.Get("cv/d.pdf")
+	if !ok || string(obj.Data) != "%PDF-1.7 posted" || obj.ContentType != "application/pdf" {
+		t.Fatalf("stored object: got %+v, %t", obj, ok)
+	}
+	if _, ok := srv.Get("cv/other.pdf"); ok {
+		t.Fatalf("upload under another key was stored")
+	}
+}
+
+// postForm sends a browser-style form upload: fields, then the file last.
+func postForm(t *testing.T, target string, fields map[string]string, data []byte) int {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatalf("WriteField: %v", err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", "cv.pdf")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	resp, err := http.Post(target, mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
@@ -0,0 +1,380 @@
+// Package sqlite implements port.CVRepository on an SQLite database file,
+// for single-node deployments without a database server. It uses a pure-Go
+// driver, so the binary keeps building without cgo.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/cursor"
+)
+
+// Open opens, creating it if needed, the database file at path in WAL mode:
+// readers do not block the writer and a crash loses no committed data. Writers
+// wait up to busyTimeout for the write lock, and transactions take it up front
+// so they cannot deadlock upgrading a read lock.
+func Open(ctx context.Context, path string, busyTimeout time.Duration) (*sql.DB, error) {
+	params := url.Values{
+		"_pragma": {
+			fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()),
+			"journal_mode(WAL)",
+			"synchronous(NORMAL)",
+			"foreign_keys(ON)",
+		},
+		"_txlock": {"immediate"},
+	}
+	// Escaped, so that '?', '#' and '%' in path are not read as URI syntax.
+	dsn := url.URL{Scheme: "file", Opaque: (&url.URL{Path: path}).EscapedPath(), RawQuery: params.Encode()}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// Every connection would open a database of its own.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fromSQLite(err)
+	}
+	return db, nil
+}
+
+type CVRepository struct {
+	db      *sql.DB
+	cursors *cursor.Codec
+}
+
+// NewCVRepository builds a repository on db, whose schema must be migrated.
+// cursors signs the List cursors; a nil codec uses a random key, so cursors
+// do not survive a restart.
+func NewCVRepository(db *sql.DB, cursors *cursor.Codec) *CVRepository {
+	if cursors == nil {
+		cursors = cursor.NewCodec(nil)
+	}
+	return &CVRepository{db: db, cursors: cursors}
+}
+
+const cvColumns = `id, owner_id, file_name, mime_type, detected_mime_type, size, gcs_path,
+	md5, crc32c, duplicate_of, ref_count, status, reject_reason,
+	created_at, updated_at, upload_expires_at, upload_session_uri`
+
+func (r *CVRepository) Create(ctx context.Context, cv *domain.CV) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO cvs (`+cvColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cvArgs(cv)...)
+	err = fromSQLite(err)
+	if errors.Is(err, domain.ErrConflict) {
+		return fmt.Errorf("%w: %s", port.ErrCVAlreadyExists, cv.ID)
+	}
+	return err
+}
+
+// Update replaces every field of the stored CV but ref_count, which only
+// AddRef changes. It returns ErrCVNotFound when no CV has the ID.
+func (r *CVRepository) Update(ctx context.Context, cv *domain.CV) error {
+	res, err := r.db.ExecContext(ctx, updateCV+` WHERE id = ?1`, updateArgs(cv)...)
+	if err != nil {
+		return fromSQLite(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return noRowError(err, cv.ID)
+	}
+	return nil
+}
+
+// UpdateIfStatus is Update guarded by the stored status in the same
+// statement. When no row matches, a lookup tells a missing CV from one whose
+// status changed.
+func (r *CVRepository) UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error {
+	res, err := r.db.ExecContext(ctx, updateCV+` WHERE id = ?1 AND status = ?17`,
+		append(updateArgs(cv), string(from))...)
+	if err != nil {
+		return fromSQLite(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fromSQLite(err)
+	}
+	if n == 0 {
+		return r.statusChanged(ctx, cv.ID, from)
+	}
+	return nil
+}
+
+// updateCV sets the columns of updateArgs; callers append the WHERE clause.
+const updateCV = `UPDATE cvs SET
+	owner_id = ?2, file_name = ?3, mime_type = ?4, detected_mime_type = ?5, size = ?6, gcs_path = ?7,
+	md5 = ?8, crc32c = ?9, duplicate_of = ?10, status = ?11, reject_reason = ?12,
+	created_at = ?13, updated_at = ?14, upload_expires_at = ?15, upload_session_uri = ?16`
+
+// statusChanged is the error of a conditional update of CV id that matched
+// no row.
+func (r *CVRepository) statusChanged(ctx context.Context, id string, from domain.CVStatus) error {
+	var status string
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM cvs WHERE id = ?`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return fromSQLite(err)
+	}
+	return fmt.Errorf("%w: %s is %s, not %s", port.ErrCVStatusChanged, id, status, from)
+}
+
+func (r *CVRepository) FindByID(ctx context.Context, id string) (*domain.CV, error) {
+	cv, err := scanCV(r.db.QueryRowContext(ctx, `SELECT `+cvColumns+` FROM cvs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return nil, fromSQLite(err)
+	}
+	return &cv, nil
+}
+
+var sortColumns = map[port.CVSortField]string{
+	port.CVSortCreatedAt: "created_at",
+	port.CVSortUpdatedAt: "updated_at",
+	port.CVSortSize:      "size",
+}
+
+// List pages through the CVs matching q with keyset pagination on the
+// (sort column, id) row value, served by the matching index.
+func (r *CVRepository) List(ctx context.Context, q port.CVQuery) ([]domain.CV, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidQuery, err)
+	}
+	q = q.Normalized()
+
+	col := sortColumns[q.SortBy]
+	dir, after := "DESC", "<"
+	if q.Order == port.SortAsc {
+		dir, after = "ASC", ">"
+	}
+
+	w := filterWhere(q.Filter)
+	if q.Cursor != "" {
+		pos, err := r.cursors.Decode(q.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", port.ErrInvalidCursor, err)
+		}
+		if pos.Scope != q.Scope() {
+			return nil, "", fmt.Errorf("%w: issued for another query", port.ErrInvalidCursor)
+		}
+		key := unixNano(pos.Time)
+		if q.SortBy == port.CVSortSize {
+			key = pos.Int
+		}
+		w.add(fmt.Sprintf("(%s, id) %s (?, ?)", col, after), key, pos.ID)
+	}
+
+	query := `SELECT ` + cvColumns + ` FROM cvs` + w.String() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", col, dir, dir)
+	args := w.args
+	if q.Limit > 0 {
+		// Fetch one extra row to learn whether another page exists.
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	out, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Limit <= 0 || len(out) <= q.Limit {
+		return out, "", nil
+	}
+	out = out[:q.Limit]
+	return out, r.cursors.Encode(q.PositionOf(out[q.Limit-1])), nil
+}
+
+func filterWhere(f port.CVFilter) *where {
+	w := &where{}
+	if f.OwnerID != "" {
+		w.add("owner_id = ?", f.OwnerID)
+	}
+	if f.Status != "" {
+		w.add("status = ?", string(f.Status))
+	}
+	if f.MimeType != "" {
+		w.add("mime_type = ?", f.MimeType)
+	}
+	if !f.CreatedFrom.IsZero() {
+		w.add("created_at >= ?", unixNano(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		w.add("created_at < ?", unixNano(f.CreatedTo))
+	}
+	if f.MinSize > 0 {
+		w.add("size >= ?", f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		w.add("size <= ?", f.MaxSize)
+	}
+	if f.FileNamePrefix != "" {
+		// substr counts characters, like length on text values.
+		w.add("substr(file_name, 1, length(?)) = ?", f.FileNamePrefix, f.FileNamePrefix)
+	}
+	return w
+}
+
+// where builds a WHERE clause with positional parameters.
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string, args ...any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+func (r *CVRepository) FindByContent(ctx context.Context, q port.ContentQuery) (*domain.CV, error) {
+	w := &where{}
+	w.add("md5 = ?", q.MD5)
+	w.add("crc32c = ?", q.CRC32C)
+	w.add("size = ?", q.Size)
+	if q.ScopeToOwner {
+		w.add("owner_id = ?", q.OwnerID)
+	}
+	w.add("duplicate_of = ''")
+	w.add("status IN (?, ?, ?, ?)",
+		string(domain.CVStatusUploaded), string(domain.CVStatusScanning),
+		string(domain.CVStatusProcessing), string(domain.CVStatusReady))
+
+	cvs, err := r.query(ctx, `SELECT `+cvColumns+` FROM cvs`+w.String()+` ORDER BY created_at, id LIMIT 1`, w.args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(cvs) == 0 {
+		return nil, fmt.Errorf("%w: no cv with md5 %s", port.ErrCVNotFound, q.MD5)
+	}
+	return &cvs[0], nil
+}
+
+// AddRef updates the count in place, so concurrent links and unlinks of the
+// same canonical CV are not lost.
+func (r *CVRepository) AddRef(ctx context.Context, id string, delta int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `UPDATE cvs SET ref_count = ref_count + ? WHERE id = ? RETURNING ref_count`,
+		delta, id).Scan(&count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+	}
+	if err != nil {
+		return 0, fromSQLite(err)
+	}
+	return count, nil
+}
+
+func (r *CVRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM cvs WHERE id = ?`, id)
+	if err != nil {
+		return fromSQLite(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return noRowError(err, id)
+	}
+	return nil
+}
+
+// noRowError is the error of a write that affected no row, or err when
+// counting the rows failed.
+func noRowError(err error, id string) error {
+	if err != nil {
+		return fromSQLite(err)
+	}
+	return fmt.Errorf("%w: %s", port.ErrCVNotFound, id)
+}
+
+func (r *CVRepository) query(ctx context.Context, query string, args ...any) ([]domain.CV, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fromSQLite(err)
+	}
+	defer rows.Close()
+	var out []domain.CV
+	for rows.Next() {
+		cv, err := scanCV(rows)
+		if err != nil {
+			return nil, fromSQLite(err)
+		}
+		out = append(out, cv)
+	}
+	return out, fromSQLite(rows.Err())
+}
+
+// cvArgs are the values of cvColumns for cv.
+func cvArgs(cv *domain.CV) []any {
+	var expires sql.NullInt64
+	if !cv.UploadExpiresAt.IsZero() {
+		expires = sql.NullInt64{Int64: unixNano(cv.UploadExpiresAt), Valid: true}
+	}
+	return []any{
+		cv.ID, cv.OwnerID, cv.FileName, cv.MimeType, cv.DetectedMimeType, cv.Size, cv.GCSPath,
+		cv.MD5, cv.CRC32C, cv.DuplicateOf, cv.RefCount, string(cv.Status), cv.RejectReason,
+		unixNano(cv.CreatedAt), unixNano(cv.UpdatedAt), expires, cv.UploadSessionURI,
+	}
+}
+
+// updateArgs are cvArgs without ref_count, so that Update does not overwrite
+// the count of concurrent AddRef calls with a stale one.
+func updateArgs(cv *domain.CV) []any {
+	args := cvArgs(cv)
+	return append(args[:10:10], args[11:]...)
+}
+
+func scanCV(row interface{ Scan(...any) error }) (domain.CV, error) {
+	var (
+		cv               domain.CV
+		status           string
+		created, updated int64
+		expires          sql.NullInt64
+	)
+	err := row.Scan(
+		&cv.ID, &cv.OwnerID, &cv.FileName, &cv.MimeType, &cv.DetectedMimeType, &cv.Size, &cv.GCSPath,
+		&cv.MD5, &cv.CRC32C, &cv.DuplicateOf, &cv.RefCount, &status, &cv.RejectReason,
+		&created, &updated, &expires, &cv.UploadSessionURI,
+	)
+	cv.Status = domain.CVStatus(status)
+	cv.CreatedAt, cv.UpdatedAt = fromUnixNano(created), fromUnixNano(updated)
+	if expires.Valid {
+		cv.UploadExpiresAt = fromUnixNano(expires.Int64)
+	}
+	return cv, err
+}
+
+// unixNano stores t; the zero time is stored as 0.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+var _ port.CVRepository = (*CVRepository)(nil)
@@ -0,0 +1,109 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cv-platform/internal/adapter/sqlite"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/internal/port/porttest"
+)
+
+func open(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Open(t.Context(), path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Open %s: %v", path, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCVRepositoryConformance(t *testing.T) {
+	for name, path := range map[string]func(t *testing.T) string{
+		"file":   func(t *testing.T) string { return filepath.Join(t.TempDir(), "cv.db") },
+		"memory": func(*testing.T) string { return ":memory:" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			porttest.RunCVRepositoryConformance(t, func(t *testing.T) port.CVRepository {
+				db := open(t, path(t))
+				if _, err := sqlite.Migrate(t.Context(), db); err != nil {
+					t.Fatalf("Migrate: %v", err)
+				}
+				return sqlite.NewCVRepository(db, nil)
+			})
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "cv.db"))
+	all, err := sqlite.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	pending, err := sqlite.PendingMigrations(t.Context(), db)
+	if err != nil || len(pending) != len(all) {
+		t.Fatalf("PendingMigrations of a new database: got %v, %v, want %v", pending, err, all)
+	}
+	if _, err := sqlite.NewCVRepository(db, nil).FindByID(t.Context(), "cv-1"); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Fatalf("FindByID before Migrate: got err %v, want %v", err, domain.ErrPreconditionFailed)
+	}
+
+	// Concurrent instances starting on the same file apply every migration once.
+	var wg sync.WaitGroup
+	applied := make([]int, 4)
+	for i := range applied {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ms, err := sqlite.Migrate(t.Context(), db)
+			if err != nil {
+				t.Errorf("Migrate: %v", err)
+			}
+			applied[i] = len(ms)
+		}()
+	}
+	wg.Wait()
+	total := 0
+	for _, n := range applied {
+		total += n
+	}
+	if total != len(all) {
+		t.Fatalf("concurrent Migrate applied %d migrations, want %d", total, len(all))
+	}
+
+	if pending, err := sqlite.PendingMigrations(t.Context(), db); err != nil || len(pending) != 0 {
+		t.Fatalf("PendingMigrations after Migrate: got %v, %v, want none", pending, err)
+	}
+	if ms, err := sqlite.Migrate(t.Context(), db); err != nil || len(ms) != 0 {
+		t.Fatalf("second Migrate: got %v, %v, want none applied", ms, err)
+	}
+	var mode string
+	if err := db.QueryRowContext(t.Context(), "PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q, %v, want wal", mode, err)
+	}
+}
+
+// TestOpenPath checks that the file is created at path even when path holds
+// characters with a meaning in URIs.
+func TestOpenPath(t *testing.T) {
+	for _, name := range []string{"cv.db", "cv?mode=ro.db", "cv#1.db", "cv%20.db", "my cvs.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			db := open(t, path)
+			if _, err := sqlite.Migrate(t.Context(), db); err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("database file: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"cv-platform/internal/domain"
+)
+
+// fromSQLite classifies an SQLite error by its result code. A database that
+// stays locked past the busy timeout, or a full disk, is ErrUnavailable; a
+// missing table, i.e. a database that was not migrated, is
+// ErrPreconditionFailed.
+func fromSQLite(err error) error {
+	if err == nil {
+		return nil
+	}
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return domain.WithKind(domain.ErrUnavailable, err)
+		}
+		return err
+	}
+	var kind error
+	switch code := serr.Code(); code & 0xff {
+	case sqlite3.SQLITE_CONSTRAINT:
+		if isUniqueViolation(serr) {
+			kind = domain.ErrConflict
+		} else {
+			kind = domain.ErrValidation
+		}
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_FULL, sqlite3.SQLITE_IOERR:
+		kind = domain.ErrUnavailable
+	case sqlite3.SQLITE_ERROR:
+		if !strings.Contains(serr.Error(), "no such table") && !strings.Contains(serr.Error(), "no such column") {
+			return err
+		}
+		kind = domain.ErrPreconditionFailed
+	default:
+		return err
+	}
+	return domain.WithKind(kind, err)
+}
+
+func isUniqueViolation(err *sqlite.Error) bool {
+	switch err.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return true
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
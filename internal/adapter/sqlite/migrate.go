@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"cv-platform/pkg/migration"
+)
+
+// migrationFiles are the schema migrations; see package migration.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]migration.Migration, error) {
+	return migration.Load(migrationFiles, "migrations/*.sql")
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+)`
+
+// Migrate applies the pending migrations, each in its own transaction, and
+// returns them. Transactions of databases opened with Open take the write
+// lock up front, so concurrent calls apply every migration once.
+func Migrate(ctx context.Context, db *sql.DB) ([]migration.Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fromSQLite(err)
+	}
+	var applied []migration.Migration
+	for _, m := range all {
+		ok, err := apply(ctx, db, m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m, fromSQLite(err))
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// apply runs m unless it was applied already, and reports whether it ran.
+func apply(ctx context.Context, db *sql.DB, m migration.Migration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&n); err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PendingMigrations returns the migrations Migrate would apply.
+func PendingMigrations(ctx context.Context, db *sql.DB) ([]migration.Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var exists int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists)
+	if err != nil {
+		return nil, fromSQLite(err)
+	}
+	if exists == 0 {
+		return all, nil
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fromSQLite(err)
+	}
+	defer rows.Close()
+	var applied []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fromSQLite(err)
+		}
+		applied = append(applied, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fromSQLite(err)
+	}
+	return migration.Pending(all, applied), nil
+}
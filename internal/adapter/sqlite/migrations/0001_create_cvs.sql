@@ -0,0 +1,31 @@
+-- CV metadata. Times are Unix nanoseconds in UTC, so they sort and compare as
+-- integers; upload_expires_at is NULL when unset. Text uses the default BINARY
+-- collation, which compares bytes like the other repositories.
+CREATE TABLE cvs (
+    id                 TEXT PRIMARY KEY,
+    owner_id           TEXT NOT NULL DEFAULT '',
+    file_name          TEXT NOT NULL,
+    mime_type          TEXT NOT NULL,
+    detected_mime_type TEXT NOT NULL DEFAULT '',
+    size               INTEGER NOT NULL DEFAULT 0,
+    gcs_path           TEXT NOT NULL,
+    md5                TEXT NOT NULL DEFAULT '',
+    crc32c             TEXT NOT NULL DEFAULT '',
+    duplicate_of       TEXT NOT NULL DEFAULT '',
+    ref_count          INTEGER NOT NULL DEFAULT 0,
+    status             TEXT NOT NULL,
+    reject_reason      TEXT NOT NULL DEFAULT '',
+    created_at         INTEGER NOT NULL,
+    updated_at         INTEGER NOT NULL,
+    upload_expires_at  INTEGER,
+    upload_session_uri TEXT NOT NULL DEFAULT ''
+) WITHOUT ROWID;
+
+-- Keyset pagination: one index per sort field, ID as tie-breaker.
+CREATE INDEX cvs_created_at_idx ON cvs (created_at, id);
+CREATE INDEX cvs_updated_at_idx ON cvs (updated_at, id);
+CREATE INDEX cvs_size_idx ON cvs (size, id);
+CREATE INDEX cvs_status_created_at_idx ON cvs (status, created_at, id);
+
+-- Duplicate detection only looks at canonical CVs.
+CREATE INDEX cvs_content_idx ON cvs (md5, crc32c, size, owner_id) WHERE duplicate_of = '';
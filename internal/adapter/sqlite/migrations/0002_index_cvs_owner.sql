@@ -0,0 +1,2 @@
+-- Listings are scoped to the caller, so every API listing filters on owner_id.
+CREATE INDEX cvs_owner_created_at_idx ON cvs (owner_id, created_at, id);
@@ -0,0 +1,62 @@
+package timeout
+
+import (
+	"context"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+)
+
+type cvRepository struct {
+	next port.CVRepository
+	cfg  Config
+}
+
+// NewCVRepository returns next with the operations bounded by cfg.
+func NewCVRepository(next port.CVRepository, cfg Config) port.CVRepository {
+	return &cvRepository{next: next, cfg: cfg}
+}
+
+func (r *cvRepository) Create(ctx context.Context, cv *domain.CV) error {
+	ctx, done := r.cfg.bound(ctx, "Create")
+	return done(r.next.Create(ctx, cv))
+}
+
+func (r *cvRepository) Update(ctx context.Context, cv *domain.CV) error {
+	ctx, done := r.cfg.bound(ctx, "Update")
+	return done(r.next.Update(ctx, cv))
+}
+
+func (r *cvRepository) UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error {
+	ctx, done := r.cfg.bound(ctx, "UpdateIfStatus")
+	return done(r.next.UpdateIfStatus(ctx, cv, from))
+}
+
+func (r *cvRepository) FindByID(ctx context.Context, id string) (*domain.CV, error) {
+	ctx, done := r.cfg.bound(ctx, "FindByID")
+	cv, err := r.next.FindByID(ctx, id)
+	return cv, done(err)
+}
+
+func (r *cvRepository) List(ctx context.Context, q port.CVQuery) ([]domain.CV, string, error) {
+	ctx, done := r.cfg.bound(ctx, "List")
+	cvs, next, err := r.next.List(ctx, q)
+	return cvs, next, done(err)
+}
+
+func (r *cvRepository) FindByContent(ctx context.Context, q port.ContentQuery) (*domain.CV, error) {
+	ctx, done := r.cfg.bound(ctx, "FindByContent")
+	cv, err := r.next.FindByContent(ctx, q)
+	return cv, done(err)
+}
+
+func (r *cvRepository) AddRef(ctx context.Context, id string, delta int) (int, error) {
+	ctx, done := r.cfg.bound(ctx, "AddRef")
+	n, err := r.next.AddRef(ctx, id, delta)
+	return n, done(err)
+}
+
+func (r *cvRepository) Delete(ctx context.Context, id string) error {
+	ctx, done := r.cfg.bound(ctx, "Delete")
+	return done(r.next.Delete(ctx, id))
+}
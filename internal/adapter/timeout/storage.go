@@ -0,0 +1,85 @@
+package timeout
+
+import (
+	"context"
+	"io"
+
+	"cv-platform/internal/port"
+)
+
+type blobStorage struct {
+	next port.BlobStorage
+	cfg  Config
+}
+
+// NewBlobStorage returns next with the operations bounded by cfg. A bounded
+// Read covers reading the content up to Close.
+func NewBlobStorage(next port.BlobStorage, cfg Config) port.BlobStorage {
+	return &blobStorage{next: next, cfg: cfg}
+}
+
+func (s *blobStorage) SignedURL(ctx context.Context, objectPath string, opts port.SignedURLOptions) (string, error) {
+	ctx, done := s.cfg.bound(ctx, "SignedURL")
+	url, err := s.next.SignedURL(ctx, objectPath, opts)
+	return url, done(err)
+}
+
+func (s *blobStorage) SignedPostPolicy(ctx context.Context, objectPath string, opts port.SignedURLOptions) (*port.PostPolicy, error) {
+	ctx, done := s.cfg.bound(ctx, "SignedPostPolicy")
+	p, err := s.next.SignedPostPolicy(ctx, objectPath, opts)
+	return p, done(err)
+}
+
+func (s *blobStorage) StartResumable(ctx context.Context, objectPath string, opts port.SignedURLOptions) (string, error) {
+	ctx, done := s.cfg.bound(ctx, "StartResumable")
+	uri, err := s.next.StartResumable(ctx, objectPath, opts)
+	return uri, done(err)
+}
+
+func (s *blobStorage) ResumableStatus(ctx context.Context, sessionURI string) (port.UploadStatus, error) {
+	ctx, done := s.cfg.bound(ctx, "ResumableStatus")
+	st, err := s.next.ResumableStatus(ctx, sessionURI)
+	return st, done(err)
+}
+
+func (s *blobStorage) NewWriter(ctx context.Context, objectPath, contentType string) (port.ObjectWriter, error) {
+	return s.next.NewWriter(ctx, objectPath, contentType)
+}
+
+func (s *blobStorage) Head(ctx context.Context, objectPath string) (port.ObjectAttrs, bool, error) {
+	ctx, done := s.cfg.bound(ctx, "Head")
+	attrs, ok, err := s.next.Head(ctx, objectPath)
+	return attrs, ok, done(err)
+}
+
+func (s *blobStorage) Read(ctx context.Context, objectPath string, offset, length int64) (io.ReadCloser, error) {
+	ctx, done := s.cfg.bound(ctx, "Read")
+	rc, err := s.next.Read(ctx, objectPath, offset, length)
+	if err != nil {
+		return nil, done(err)
+	}
+	return &reader{ReadCloser: rc, done: done}, nil
+}
+
+func (s *blobStorage) Delete(ctx context.Context, objectPath string) error {
+	ctx, done := s.cfg.bound(ctx, "Delete")
+	return done(s.next.Delete(ctx, objectPath))
+}
+
+// reader releases the context of a Read on Close.
+type reader struct {
+	io.ReadCloser
+	done func(error) error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = r.done(err)
+	}
+	return n, err
+}
+
+func (r *reader) Close() error {
+	return r.done(r.ReadCloser.Close())
+}
@@ -0,0 +1,88 @@
+// Package timeout bounds the operations of the repository and the blob
+// storage with per-operation timeouts, on top of the deadline the caller's
+// context already carries.
+package timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cv-platform/internal/domain"
+)
+
+// RepositoryOps are the port.CVRepository operations Config.Ops may name.
+var RepositoryOps = []string{"Create", "Update", "UpdateIfStatus", "FindByID", "List", "FindByContent", "AddRef", "Delete"}
+
+// StorageOps are the port.BlobStorage operations Config.Ops may name.
+// NewWriter is not among them: writers outlive the call that opens them.
+var StorageOps = []string{"SignedURL", "SignedPostPolicy", "StartResumable", "ResumableStatus", "Head", "Read", "Delete"}
+
+// streaming operations are only bounded by an explicit Ops entry; the Default
+// is meant for single round trips.
+var streaming = []string{"Read"}
+
+// Config holds the timeouts of the operations of one port.
+type Config struct {
+	// Default bounds every operation without an entry in Ops, except Read.
+	// 0 leaves them unbounded.
+	Default time.Duration
+	// Ops overrides Default by operation name, e.g. "List", matched
+	// case-insensitively; 0 leaves the operation unbounded.
+	Ops map[string]time.Duration
+}
+
+// For returns the timeout of op, 0 for none.
+func (c Config) For(op string) time.Duration {
+	for name, d := range c.Ops {
+		if strings.EqualFold(name, op) {
+			return d
+		}
+	}
+	if slices.Contains(streaming, op) {
+		return 0
+	}
+	return c.Default
+}
+
+// Check reports the Ops entries that are not in known, or negative.
+func (c Config) Check(known []string) error {
+	var errs []error
+	for op, d := range c.Ops {
+		switch {
+		case !slices.ContainsFunc(known, func(k string) bool { return strings.EqualFold(k, op) }):
+			errs = append(errs, fmt.Errorf("unknown operation %q (want one of %s)", op, strings.Join(known, ", ")))
+		case d < 0:
+			errs = append(errs, fmt.Errorf("negative timeout for %s", op))
+		}
+	}
+	if c.Default < 0 {
+		errs = append(errs, errors.New("negative default timeout"))
+	}
+	return errors.Join(errs...)
+}
+
+// bound derives the context of op from ctx. done classifies the error of the
+// operation: when the timeout of op, rather than ctx, expired, the error is
+// ErrUnavailable.
+func (c Config) bound(ctx context.Context, op string) (context.Context, func(error) error) {
+	d := c.For(op)
+	if d <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	opCtx, cancel := context.WithTimeout(ctx, d)
+	return opCtx, func(err error) error {
+		cancel()
+		return c.classify(ctx, opCtx, op, err)
+	}
+}
+
+func (c Config) classify(parent, opCtx context.Context, op string, err error) error {
+	if err == nil || parent.Err() != nil || !errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return domain.WithKind(domain.ErrUnavailable, fmt.Errorf("%s timed out after %s: %w", op, c.For(op), err))
+}
@@ -1,61 +1,283 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
+// Config is the configuration of the API. Every setting has a key in the
+// config file and an environment variable overriding it; secret settings are
+// masked by WriteYAML when asked to.
 type Config struct {
 	// Server
-	Port string `env:"PORT" envDefault:"8080"`
-
-	//// Google Cloud
-	//ProjectID  string `env:"GCP_PROJECT_ID,required"`
-	//BucketName string `env:"GCS_BUCKET_NAME,required"`
-	//CredsPath  string `env:"GOOGLE_APPLICATION_CREDENTIALS"`
-	//CredsRaw   string `env:"GOOGLE_APPLICATION_CREDENTIALS_JSON"`
-	//
-	//// Logging
-	//LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
-	//LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
-	//
-	//// Derived
-	//CredsJSON []byte `env:"-"`
+	Port          string `key:"server.port" env:"PORT" envDefault:"8080"`
+	PublicBaseURL string `key:"server.public_base_url" env:"PUBLIC_BASE_URL"`
+	// ReadHeaderTimeout bounds reading request headers and IdleTimeout idle
+	// keep-alive connections; bodies are streamed and not bounded.
+	// ShutdownTimeout is how long in-flight requests may take to finish on
+	// shutdown.
+	ReadHeaderTimeout time.Duration `key:"server.read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"10s"`
+	IdleTimeout       time.Duration `key:"server.idle_timeout" env:"HTTP_IDLE_TIMEOUT" envDefault:"2m"`
+	ShutdownTimeout   time.Duration `key:"server.shutdown_timeout" env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+	// Browser origins allowed to call the API; empty disables CORS
+	CORSAllowedOrigins []string `key:"server.cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS"` // comma separated, "*" for any
+
+	// Storage
+	StorageDriver     string `key:"storage.driver" env:"STORAGE_DRIVER" envDefault:"localfs"` // gcs | s3 | localfs | memory
+	LocalFSRoot       string `key:"storage.localfs.root" env:"LOCALFS_ROOT" envDefault:"./data/blobs"`
+	LocalFSSigningKey string `key:"auth.localfs_signing_key" env:"LOCALFS_SIGNING_KEY" secret:"true"`
+
+	// S3 and S3-compatible stores; the region and credentials fall back to
+	// the AWS environment and shared config when unset
+	S3Bucket          string `key:"storage.s3.bucket" env:"S3_BUCKET"`
+	S3Region          string `key:"storage.s3.region" env:"S3_REGION"`
+	S3Endpoint        string `key:"storage.s3.endpoint" env:"S3_ENDPOINT"` // e.g. http://minio:9000
+	S3UsePathStyle    bool   `key:"storage.s3.use_path_style" env:"S3_USE_PATH_STYLE" envDefault:"false"`
+	S3AccessKeyID     string `key:"auth.s3_access_key_id" env:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `key:"auth.s3_secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+
+	// Repository
+	RepoDriver        string        `key:"repository.driver" env:"REPO_DRIVER" envDefault:"sqlite"` // memory | firestore | postgres | sqlite
+	PostgresDSN       string        `key:"repository.postgres.dsn" env:"POSTGRES_DSN" secret:"true"`
+	SQLitePath        string        `key:"repository.sqlite.path" env:"SQLITE_PATH" envDefault:"./data/cv.db"`
+	SQLiteBusyTimeout time.Duration `key:"repository.sqlite.busy_timeout" env:"SQLITE_BUSY_TIMEOUT" envDefault:"5s"`
+
+	// Per-operation timeouts of the storage and repository calls. The
+	// *_OP_TIMEOUTS override the default by operation, e.g. "List=10s,Read=1m".
+	// 0 leaves the calls unbounded.
+	StorageTimeout    time.Duration            `key:"storage.timeout" env:"STORAGE_TIMEOUT" envDefault:"5s"`
+	StorageOpTimeouts map[string]time.Duration `key:"storage.op_timeouts" env:"STORAGE_OP_TIMEOUTS"`
+	RepoTimeout       time.Duration            `key:"repository.timeout" env:"REPO_TIMEOUT" envDefault:"5s"`
+	RepoOpTimeouts    map[string]time.Duration `key:"repository.op_timeouts" env:"REPO_OP_TIMEOUTS"`
+
+	// Pagination
+	CursorSigningKey string `key:"auth.cursor_signing_key" env:"CURSOR_SIGNING_KEY" secret:"true"`
+
+	// Upload policy; empty lists keep the built-in defaults
+	UploadAllowedMimeTypes  []string `key:"upload.allowed_mime_types" env:"UPLOAD_ALLOWED_MIME_TYPES"` // comma separated
+	UploadAllowedExtensions []string `key:"upload.allowed_extensions" env:"UPLOAD_ALLOWED_EXTENSIONS"` // comma separated, without dots
+	UploadMaxSize           int64    `key:"upload.max_size" env:"UPLOAD_MAX_SIZE" envDefault:"10485760"`
+	UploadMaxFileNameLength int      `key:"upload.max_filename_length" env:"UPLOAD_MAX_FILENAME_LENGTH" envDefault:"255"`
+	UploadASCIIFileNames    bool     `key:"upload.ascii_filenames" env:"UPLOAD_ASCII_FILENAMES" envDefault:"false"`
+	// Duplicate content handling: allow | link | reject
+	UploadDuplicates         string `key:"upload.duplicates" env:"UPLOAD_DUPLICATES" envDefault:"link"`
+	UploadDuplicatesPerOwner bool   `key:"upload.duplicates_per_owner" env:"UPLOAD_DUPLICATES_PER_OWNER" envDefault:"true"`
+	// Validity of signed upload URLs and POST policies, and of resumable
+	// sessions and tus uploads
+	UploadTTL          time.Duration `key:"upload.ttl" env:"UPLOAD_TTL" envDefault:"10m"`
+	UploadResumableTTL time.Duration `key:"upload.resumable_ttl" env:"UPLOAD_RESUMABLE_TTL" envDefault:"24h"`
+
+	// Downloads: redirect (signed GET URL) | stream (through the API)
+	DownloadMode string `key:"server.download_mode" env:"DOWNLOAD_MODE" envDefault:"redirect"`
+
+	// tus upload server for clients that cannot reach the storage
+	TusEnabled bool `key:"upload.tus_enabled" env:"TUS_ENABLED" envDefault:"false"`
+
+	// Pending upload reaper
+	ReaperEnabled     bool          `key:"upload.reaper.enabled" env:"REAPER_ENABLED" envDefault:"true"`
+	ReaperInterval    time.Duration `key:"upload.reaper.interval" env:"REAPER_INTERVAL" envDefault:"5m"`
+	ReaperGracePeriod time.Duration `key:"upload.reaper.grace_period" env:"REAPER_GRACE_PERIOD" envDefault:"15m"`
+	ReaperBatchSize   int           `key:"upload.reaper.batch_size" env:"REAPER_BATCH_SIZE" envDefault:"100"`
+
+	// Google Cloud, for STORAGE_DRIVER=gcs and REPO_DRIVER=firestore. Without
+	// credentials the clients use the application default credentials.
+	ProjectID  string `key:"repository.firestore.project_id" env:"GCP_PROJECT_ID"`
+	BucketName string `key:"storage.gcs.bucket" env:"GCS_BUCKET_NAME"`
+	CredsPath  string `key:"auth.google_credentials_file" env:"GOOGLE_APPLICATION_CREDENTIALS"`
+	CredsRaw   string `key:"auth.google_credentials_json" env:"GOOGLE_APPLICATION_CREDENTIALS_JSON" secret:"true"`
+
+	// Logging
+	LogLevel  string `key:"logging.level" env:"LOG_LEVEL" envDefault:"info"`   // debug | info | warn | error
+	LogFormat string `key:"logging.format" env:"LOG_FORMAT" envDefault:"json"` // json | text
+
+	// Derived: the service account key of CredsRaw or CredsPath, when a
+	// Google Cloud driver is selected
+	CredsJSON []byte `env:"-"`
 }
 
-func Load() (*Config, error) {
+// Load reads the configuration from the config file at path, or else the one
+// CONFIG_FILE names, overridden by the environment and a .env file. Without a
+// file the environment alone is read. Load validates the result: the error
+// lists every malformed, unknown or missing setting.
+func Load(path string) (*Config, error) {
 	_ = godotenv.Load()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
 	v := viper.New()
-	v.AutomaticEnv()
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
-
-	// Defaults
-	v.SetDefault("PORT", "8080")
-	v.SetDefault("LOG_LEVEL", "info")
-	v.SetDefault("LOG_FORMAT", "json")
-
-	cfg := &Config{
-		Port: v.GetString("PORT"),
-		//ProjectID:  v.GetString("GCP_PROJECT_ID"),
-		//BucketName: v.GetString("GCS_BUCKET_NAME"),
-		//CredsPath:  v.GetString("GOOGLE_APPLICATION_CREDENTIALS"),
-		//CredsRaw:   v.GetString("GOOGLE_APPLICATION_CREDENTIALS_JSON"),
-		//LogLevel:   v.GetString("LOG_LEVEL"),
-		//LogFormat:  v.GetString("LOG_FORMAT"),
-	}
-
-	// Materialize credentials JSON
-	//if strings.TrimSpace(cfg.CredsRaw) != "" {
-	//	cfg.CredsJSON = []byte(cfg.CredsRaw)
-	//} else if p := strings.TrimSpace(cfg.CredsPath); p != "" {
-	//	if b, err := os.ReadFile(p); err == nil {
-	//		cfg.CredsJSON = b
-	//	} else {
-	//		return nil, err
-	//	}
-	//}
+	if path != "" {
+		if err := readFile(v, path); err != nil {
+			return nil, err
+		}
+	}
+
+	l := &loader{v: v}
+	cfg := &Config{}
+	l.load(cfg)
+	cfg.StorageDriver = strings.ToLower(cfg.StorageDriver)
+	cfg.RepoDriver = strings.ToLower(cfg.RepoDriver)
+	cfg.UploadDuplicates = strings.ToLower(cfg.UploadDuplicates)
+	cfg.DownloadMode = strings.ToLower(cfg.DownloadMode)
+	cfg.LogLevel = strings.ToLower(cfg.LogLevel)
+	cfg.LogFormat = strings.ToLower(cfg.LogFormat)
 
+	if strings.TrimSpace(cfg.PublicBaseURL) == "" {
+		cfg.PublicBaseURL = "http://localhost:" + cfg.Port
+	}
+
+	errs := append(l.errs, cfg.validate()...)
+	if cfg.StorageDriver == "gcs" || cfg.RepoDriver == "firestore" {
+		if err := cfg.loadCreds(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return cfg, nil
 }
+
+// readFile reads the YAML or TOML config file at path into v.
+func readFile(v *viper.Viper, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".toml":
+	default:
+		return fmt.Errorf("config file %s: want a .yaml, .yml or .toml file", path)
+	}
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// maxSignedURLTTL is the longest validity GCS and S3 accept for signed URLs.
+const maxSignedURLTTL = 7 * 24 * time.Hour
+
+// validate checks the settings against each other and the selected drivers.
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		check(false, "PORT: %q is not a port number", c.Port)
+	}
+	if u, err := url.Parse(c.PublicBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		check(false, "PUBLIC_BASE_URL: %q is not an absolute URL", c.PublicBaseURL)
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && u.Scheme != "" && u.Host != "" && strings.Trim(u.Path, "/") == "",
+			"CORS_ALLOWED_ORIGINS: %q is not an origin such as https://app.example.com", origin)
+	}
+	check(c.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT must be positive")
+	check(c.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT must be positive")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+
+	switch c.StorageDriver {
+	case "gcs":
+		check(c.BucketName != "", "GCS_BUCKET_NAME is required with STORAGE_DRIVER=gcs")
+	case "s3":
+		check(c.S3Bucket != "", "S3_BUCKET is required with STORAGE_DRIVER=s3")
+		check((c.S3AccessKeyID == "") == (c.S3SecretAccessKey == ""),
+			"S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set together")
+		if c.S3Endpoint != "" {
+			u, err := url.Parse(c.S3Endpoint)
+			check(err == nil && u.Scheme != "" && u.Host != "", "S3_ENDPOINT: %q is not an absolute URL", c.S3Endpoint)
+		}
+	case "localfs":
+		check(c.LocalFSRoot != "", "LOCALFS_ROOT is required with STORAGE_DRIVER=localfs")
+	case "memory":
+	default:
+		check(false, "STORAGE_DRIVER: %q is not one of gcs, s3, localfs or memory", c.StorageDriver)
+	}
+	switch c.RepoDriver {
+	case "firestore":
+		check(c.ProjectID != "", "GCP_PROJECT_ID is required with REPO_DRIVER=firestore")
+	case "postgres":
+		check(c.PostgresDSN != "", "POSTGRES_DSN is required with REPO_DRIVER=postgres")
+	case "sqlite":
+		check(c.SQLitePath != "", "SQLITE_PATH is required with REPO_DRIVER=sqlite")
+		check(c.SQLiteBusyTimeout >= 0, "SQLITE_BUSY_TIMEOUT must not be negative")
+	case "memory":
+	default:
+		check(false, "REPO_DRIVER: %q is not one of memory, firestore, postgres or sqlite", c.RepoDriver)
+	}
+
+	// 0 leaves the storage and repository calls unbounded; operation names
+	// are checked against the ports when the adapters are built.
+	check(c.StorageTimeout >= 0, "STORAGE_TIMEOUT must not be negative")
+	for _, op := range slices.Sorted(maps.Keys(c.StorageOpTimeouts)) {
+		check(c.StorageOpTimeouts[op] >= 0, "STORAGE_OP_TIMEOUTS: timeout of %s must not be negative", op)
+	}
+	check(c.RepoTimeout >= 0, "REPO_TIMEOUT must not be negative")
+	for _, op := range slices.Sorted(maps.Keys(c.RepoOpTimeouts)) {
+		check(c.RepoOpTimeouts[op] >= 0, "REPO_OP_TIMEOUTS: timeout of %s must not be negative", op)
+	}
+
+	check(c.UploadMaxSize >= 0, "UPLOAD_MAX_SIZE must not be negative")
+	check(c.UploadMaxFileNameLength > 0, "UPLOAD_MAX_FILENAME_LENGTH must be positive")
+	check(oneOf(c.UploadDuplicates, "allow", "link", "reject"),
+		"UPLOAD_DUPLICATES: %q is not one of allow, link or reject", c.UploadDuplicates)
+	check(c.UploadTTL > 0 && c.UploadTTL <= maxSignedURLTTL, "UPLOAD_TTL must be positive and at most 168h")
+	check(c.UploadResumableTTL > 0 && c.UploadResumableTTL <= maxSignedURLTTL,
+		"UPLOAD_RESUMABLE_TTL must be positive and at most 168h")
+	check(oneOf(c.DownloadMode, "redirect", "stream"),
+		"DOWNLOAD_MODE: %q is not one of redirect or stream", c.DownloadMode)
+
+	if c.ReaperEnabled {
+		check(c.ReaperInterval > 0, "REAPER_INTERVAL must be positive")
+	}
+	check(c.ReaperGracePeriod >= 0, "REAPER_GRACE_PERIOD must not be negative")
+	check(c.ReaperBatchSize > 0, "REAPER_BATCH_SIZE must be positive")
+
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "warning", "error"),
+		"LOG_LEVEL: %q is not one of debug, info, warn or error", c.LogLevel)
+	check(oneOf(c.LogFormat, "json", "text"), "LOG_FORMAT: %q is not one of json or text", c.LogFormat)
+	return errs
+}
+
+// loadCreds materializes CredsJSON from the inline credentials, or else the
+// file CredsPath names.
+func (c *Config) loadCreds() error {
+	name, raw := "GOOGLE_APPLICATION_CREDENTIALS_JSON", []byte(strings.TrimSpace(c.CredsRaw))
+	if len(raw) == 0 {
+		p := strings.TrimSpace(c.CredsPath)
+		if p == "" {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+		}
+		name, raw = "GOOGLE_APPLICATION_CREDENTIALS", b
+	}
+	if !json.Valid(raw) {
+		return fmt.Errorf("%s: credentials are not valid JSON", name)
+	}
+	c.CredsJSON = raw
+	return nil
+}
+
+func oneOf(s string, values ...string) bool {
+	return slices.Contains(values, s)
+}
@@ -0,0 +1,298 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cv-platform/internal/config"
+)
+
+// isolate runs the test in an empty directory, without .env file, and with
+// every variable Load reads unset; they are restored afterwards.
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	names := []string{"CONFIG_FILE"}
+	ct := reflect.TypeFor[config.Config]()
+	for i := range ct.NumField() {
+		if env := ct.Field(i).Tag.Get("env"); env != "" && env != "-" {
+			names = append(names, env)
+		}
+	}
+	for _, name := range names {
+		// Setenv records the value to restore; .env files only fill
+		// variables that are not set at all.
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// writeFile writes a file named name in the working directory.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return name
+}
+
+func TestLoadDefaults(t *testing.T) {
+	isolate(t)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for name, tc := range map[string]struct{ got, want any }{
+		"Port":              {cfg.Port, "8080"},
+		"PublicBaseURL":     {cfg.PublicBaseURL, "http://localhost:8080"},
+		"ReadHeaderTimeout": {cfg.ReadHeaderTimeout, 10 * time.Second},
+		"StorageDriver":     {cfg.StorageDriver, "localfs"},
+		"RepoDriver":        {cfg.RepoDriver, "sqlite"},
+		"StorageTimeout":    {cfg.StorageTimeout, 5 * time.Second},
+		"UploadMaxSize":     {cfg.UploadMaxSize, int64(10 << 20)},
+		"UploadDuplicates":  {cfg.UploadDuplicates, "link"},
+		"UploadTTL":         {cfg.UploadTTL, 10 * time.Minute},
+		"ReaperEnabled":     {cfg.ReaperEnabled, true},
+		"ReaperBatchSize":   {cfg.ReaperBatchSize, 100},
+		"CORSOrigins":       {len(cfg.CORSAllowedOrigins), 0},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// wantErrs are substrings of the error, none when the config is
+		// valid.
+		wantErrs []string
+	}{
+		{name: "defaults"},
+		{name: "drivers in upper case", env: map[string]string{"STORAGE_DRIVER": "MEMORY", "REPO_DRIVER": "Memory"}},
+		{name: "unbounded storage calls", env: map[string]string{"STORAGE_TIMEOUT": "0", "REPO_OP_TIMEOUTS": "List=0s"}},
+		{name: "reaper disabled without interval", env: map[string]string{"REAPER_ENABLED": "false", "REAPER_INTERVAL": "0"}},
+		{name: "port out of range", env: map[string]string{"PORT": "70000"}, wantErrs: []string{`PORT: "70000" is not a port number`}},
+		{name: "relative base url", env: map[string]string{"PUBLIC_BASE_URL": "/api"}, wantErrs: []string{"PUBLIC_BASE_URL"}},
+		{name: "cors origin with path", env: map[string]string{"CORS_ALLOWED_ORIGINS": "*, https://app.example.com/login"}, wantErrs: []string{"CORS_ALLOWED_ORIGINS"}},
+		{name: "zero read header timeout", env: map[string]string{"HTTP_READ_HEADER_TIMEOUT": "0"}, wantErrs: []string{"HTTP_READ_HEADER_TIMEOUT must be positive"}},
+		{name: "negative idle timeout", env: map[string]string{"HTTP_IDLE_TIMEOUT": "-1s"}, wantErrs: []string{"HTTP_IDLE_TIMEOUT must be positive"}},
+		{name: "zero shutdown timeout", env: map[string]string{"SHUTDOWN_TIMEOUT": "0s"}, wantErrs: []string{"SHUTDOWN_TIMEOUT must be positive"}},
+		{name: "negative storage timeout", env: map[string]string{"STORAGE_TIMEOUT": "-5s"}, wantErrs: []string{"STORAGE_TIMEOUT must not be negative"}},
+		{
+			name:     "negative operation timeout",
+			env:      map[string]string{"STORAGE_OP_TIMEOUTS": "Read=1m,List=-1s"},
+			wantErrs: []string{"STORAGE_OP_TIMEOUTS: timeout of List must not be negative"},
+		},
+		{name: "malformed operation timeouts", env: map[string]string{"REPO_OP_TIMEOUTS": "List"}, wantErrs: []string{`REPO_OP_TIMEOUTS (repository.op_timeouts): "List" is not name=duration`}},
+		{name: "malformed duration", env: map[string]string{"UPLOAD_TTL": "ten minutes"}, wantErrs: []string{"UPLOAD_TTL (upload.ttl)", "is not a duration"}},
+		{name: "malformed integer", env: map[string]string{"UPLOAD_MAX_SIZE": "10MB"}, wantErrs: []string{`UPLOAD_MAX_SIZE (upload.max_size): "10MB" is not an integer`}},
+		{name: "malformed boolean", env: map[string]string{"TUS_ENABLED": "sure"}, wantErrs: []string{`TUS_ENABLED (upload.tus_enabled): "sure" is not a boolean`}},
+		{name: "unknown storage driver", env: map[string]string{"STORAGE_DRIVER": "ftp"}, wantErrs: []string{"STORAGE_DRIVER"}},
+		{name: "s3 without bucket", env: map[string]string{"STORAGE_DRIVER": "s3"}, wantErrs: []string{"S3_BUCKET is required"}},
+		{
+			name:     "s3 with half the credentials",
+			env:      map[string]string{"STORAGE_DRIVER": "s3", "S3_BUCKET": "cvs", "S3_ACCESS_KEY_ID": "key"},
+			wantErrs: []string{"must be set together"},
+		},
+		{name: "gcs without bucket", env: map[string]string{"STORAGE_DRIVER": "gcs"}, wantErrs: []string{"GCS_BUCKET_NAME is required"}},
+		{
+			name:     "malformed gcs credentials",
+			env:      map[string]string{"STORAGE_DRIVER": "gcs", "GCS_BUCKET_NAME": "cvs", "GOOGLE_APPLICATION_CREDENTIALS_JSON": "{"},
+			wantErrs: []string{"GOOGLE_APPLICATION_CREDENTIALS_JSON: credentials are not valid JSON"},
+		},
+		{name: "postgres without dsn", env: map[string]string{"REPO_DRIVER": "postgres"}, wantErrs: []string{"POSTGRES_DSN is required"}},
+		{name: "firestore without project", env: map[string]string{"REPO_DRIVER": "firestore"}, wantErrs: []string{"GCP_PROJECT_ID is required"}},
+		{name: "unknown duplicates mode", env: map[string]string{"UPLOAD_DUPLICATES": "merge"}, wantErrs: []string{"UPLOAD_DUPLICATES"}},
+		{name: "upload ttl over a week", env: map[string]string{"UPLOAD_TTL": "200h"}, wantErrs: []string{"UPLOAD_TTL must be positive and at most 168h"}},
+		{name: "unknown download mode", env: map[string]string{"DOWNLOAD_MODE": "inline"}, wantErrs: []string{"DOWNLOAD_MODE"}},
+		{name: "reaper without interval", env: map[string]string{"REAPER_INTERVAL": "0"}, wantErrs: []string{"REAPER_INTERVAL must be positive"}},
+		{
+			name:     "every error reported",
+			env:      map[string]string{"PORT": "http", "LOG_LEVEL": "loud", "REAPER_BATCH_SIZE": "0"},
+			wantErrs: []string{"PORT", "LOG_LEVEL", "REAPER_BATCH_SIZE must be positive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := config.Load("")
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load: got no error, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load: got %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+// TestLoadLayering checks that the environment beats the .env file, which
+// beats the config file, which beats the defaults.
+func TestLoadLayering(t *testing.T) {
+	isolate(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  port: "9000"
+  cors_allowed_origins: [https://app.example.com, https://admin.example.com]
+storage:
+  driver: memory
+  op_timeouts:
+    List: 10s
+    Read: 1m
+upload:
+  max_size: 2048
+  duplicates: reject
+logging:
+  level: warn
+`)
+	writeFile(t, ".env", "LOG_LEVEL=debug\nUPLOAD_DUPLICATES=allow\n")
+	t.Setenv("UPLOAD_DUPLICATES", "link")
+	t.Setenv("PORT", "9100")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for name, tc := range map[string]struct{ got, want any }{
+		"Port (env)":                {cfg.Port, "9100"},
+		"UploadDuplicates (env)":    {cfg.UploadDuplicates, "link"},
+		"LogLevel (.env)":           {cfg.LogLevel, "debug"},
+		"StorageDriver (file)":      {cfg.StorageDriver, "memory"},
+		"UploadMaxSize (file)":      {cfg.UploadMaxSize, int64(2048)},
+		"CORS origins (file)":       {strings.Join(cfg.CORSAllowedOrigins, ","), "https://app.example.com,https://admin.example.com"},
+		"List timeout (file)":       {cfg.StorageOpTimeouts["list"], 10 * time.Second},
+		"Read timeout (file)":       {cfg.StorageOpTimeouts["read"], time.Minute},
+		"PublicBaseURL (derived)":   {cfg.PublicBaseURL, "http://localhost:9100"},
+		"ReaperBatchSize (default)": {cfg.ReaperBatchSize, 100},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		// wantErr is a substring of the error, "" when the file is valid.
+		wantErr string
+	}{
+		{name: "yaml", file: "config.yml", content: "storage:\n  driver: memory\n"},
+		{name: "toml", file: "config.toml", content: "[storage]\ndriver = \"memory\"\n\n[upload]\nmax_size = 2048\n"},
+		{name: "unknown setting", file: "config.yaml", content: "server:\n  prot: 9000\n", wantErr: "unknown setting server.prot"},
+		{name: "unknown section", file: "config.yaml", content: "cache:\n  size: 10\n", wantErr: "unknown setting cache.size"},
+		{name: "malformed value", file: "config.yaml", content: "upload:\n  max_size: lots\n", wantErr: `"lots" is not an integer`},
+		{name: "malformed operation timeout", file: "config.yaml", content: "repository:\n  op_timeouts:\n    List: soon\n", wantErr: "list: \"soon\" is not a duration"},
+		{name: "unsupported format", file: "config.json", content: "{}", wantErr: "want a .yaml, .yml or .toml file"},
+		{name: "malformed yaml", file: "config.yaml", content: "server: [\n", wantErr: "config file config.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			path := writeFile(t, tt.file, tt.content)
+
+			cfg, err := config.Load(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				if cfg.StorageDriver != "memory" {
+					t.Fatalf("StorageDriver: got %q, want memory", cfg.StorageDriver)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load: got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("CONFIG_FILE", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("CONFIG_FILE", writeFile(t, "other.yaml", "upload:\n  max_size: 4096\n"))
+		cfg, err := config.Load("")
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if cfg.UploadMaxSize != 4096 {
+			t.Fatalf("UploadMaxSize: got %d, want 4096", cfg.UploadMaxSize)
+		}
+	})
+}
+
+func TestWriteYAML(t *testing.T) {
+	isolate(t)
+	t.Setenv("POSTGRES_DSN", "postgres://cv:hunter2@db:5432/cv?sslmode=disable")
+	t.Setenv("CURSOR_SIGNING_KEY", "cursor-secret")
+	t.Setenv("REPO_DRIVER", "postgres")
+	t.Setenv("STORAGE_OP_TIMEOUTS", "List=10s")
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	t.Run("redacted", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := cfg.WriteYAML(&buf, true); err != nil {
+			t.Fatalf("WriteYAML: %v", err)
+		}
+		out := buf.String()
+		for _, secret := range []string{"hunter2", "cursor-secret"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("output contains secret %q:\n%s", secret, out)
+			}
+		}
+		for _, want := range []string{"postgres://cv:xxxxx@db:5432/cv", "cursor_signing_key: '******'"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output does not contain %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := cfg.WriteYAML(&buf, false); err != nil {
+			t.Fatalf("WriteYAML: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		for _, env := range []string{"POSTGRES_DSN", "CURSOR_SIGNING_KEY", "REPO_DRIVER", "STORAGE_OP_TIMEOUTS"} {
+			os.Unsetenv(env)
+		}
+
+		got, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("Load of the written file: %v\n%s", err, buf.String())
+		}
+		// Operation names come back lowercased from config files.
+		want := *cfg
+		want.StorageOpTimeouts = map[string]time.Duration{"list": 10 * time.Second}
+		if !reflect.DeepEqual(*got, want) {
+			t.Fatalf("Load of the written file:\ngot  %+v\nwant %+v", *got, want)
+		}
+	})
+}
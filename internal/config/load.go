@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// setting is a field of Config: its key in the config file, the environment
+// variable overriding it and its default.
+type setting struct {
+	index  int
+	typ    reflect.Type
+	key    string
+	env    string
+	def    string
+	secret bool
+}
+
+func (s setting) String() string { return s.env + " (" + s.key + ")" }
+
+// settings are the fields of Config with a key tag, in declaration order.
+var settings = func() []setting {
+	var out []setting
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, ok := f.Tag.Lookup("key")
+		if !ok {
+			continue
+		}
+		out = append(out, setting{
+			index:  i,
+			typ:    f.Type,
+			key:    key,
+			env:    f.Tag.Get("env"),
+			def:    f.Tag.Get("envDefault"),
+			secret: f.Tag.Get("secret") == "true",
+		})
+	}
+	return out
+}()
+
+// loader reads typed settings, collecting an error for every malformed value
+// instead of silently reading it as zero.
+type loader struct {
+	v    *viper.Viper
+	errs []error
+}
+
+// load fills cfg from the config file read into l.v, the environment and the
+// defaults, in increasing order of precedence.
+func (l *loader) load(cfg *Config) {
+	l.checkKeys()
+	rv := reflect.ValueOf(cfg).Elem()
+	for _, s := range settings {
+		l.v.SetDefault(s.key, s.def)
+		if err := l.v.BindEnv(s.key, s.env); err != nil {
+			panic(err)
+		}
+		f := rv.Field(s.index)
+		switch f.Interface().(type) {
+		case string:
+			f.SetString(l.string(s))
+		case bool:
+			f.SetBool(l.bool(s))
+		case int, int64:
+			f.SetInt(l.int64(s))
+		case time.Duration:
+			f.SetInt(int64(l.duration(s)))
+		case []string:
+			f.Set(reflect.ValueOf(l.list(s)))
+		case map[string]time.Duration:
+			f.Set(reflect.ValueOf(l.durations(s)))
+		default:
+			panic("config: unsupported type " + s.typ.String() + " of " + s.key)
+		}
+	}
+}
+
+// checkKeys reports the keys of the config file that name no setting, such
+// as misspelt ones. It must run before the defaults are set.
+func (l *loader) checkKeys() {
+	for _, k := range l.v.AllKeys() {
+		known := slices.ContainsFunc(settings, func(s setting) bool {
+			return k == s.key || s.typ.Kind() == reflect.Map && strings.HasPrefix(k, s.key+".")
+		})
+		if !known {
+			l.errs = append(l.errs, fmt.Errorf("config file: unknown setting %s", k))
+		}
+	}
+}
+
+func (l *loader) errorf(s setting, format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf("%s: %s", s, fmt.Sprintf(format, args...)))
+}
+
+func (l *loader) string(s setting) string {
+	return strings.TrimSpace(l.v.GetString(s.key))
+}
+
+func (l *loader) bool(s setting) bool {
+	str := l.string(s)
+	if str == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(str)
+	if err != nil {
+		l.errorf(s, "%q is not a boolean", str)
+	}
+	return b
+}
+
+func (l *loader) int64(s setting) int64 {
+	str := l.string(s)
+	if str == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		l.errorf(s, "%q is not an integer", str)
+	}
+	return n
+}
+
+func (l *loader) duration(s setting) time.Duration {
+	str := l.string(s)
+	if str == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		l.errorf(s, "%q is not a duration such as 30s or 5m", str)
+	}
+	return d
+}
+
+// list reads a list from a config file sequence or a comma separated value.
+func (l *loader) list(s setting) []string {
+	items, ok := l.v.Get(s.key).([]any)
+	if !ok {
+		return splitList(l.string(s))
+	}
+	var out []string
+	for _, item := range items {
+		if str := strings.TrimSpace(fmt.Sprint(item)); str != "" {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+// durations reads name=duration pairs from a config file mapping or a comma
+// separated value. Names in config files come lowercased.
+func (l *loader) durations(s setting) map[string]time.Duration {
+	m, ok := l.v.Get(s.key).(map[string]any)
+	if !ok {
+		out, err := splitDurations(l.string(s))
+		if err != nil {
+			l.errorf(s, "%v", err)
+		}
+		return out
+	}
+	out := make(map[string]time.Duration, len(m))
+	for name, value := range m {
+		d, err := time.ParseDuration(strings.TrimSpace(fmt.Sprint(value)))
+		if err != nil {
+			l.errorf(s, "%s: %q is not a duration such as 30s or 5m", name, value)
+			continue
+		}
+		out[name] = d
+	}
+	return out
+}
+
+// splitList parses a comma separated env value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// splitDurations parses a comma separated list of name=duration pairs.
+func splitDurations(s string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+	for _, item := range splitList(s) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=duration", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(name), err)
+		}
+		out[strings.TrimSpace(name)] = d
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"io"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces the values of secret settings.
+const redactedValue = "******"
+
+// WriteYAML writes the settings of c as a YAML config file, in the sections
+// of their keys. With redact, secret settings are masked; URLs keep all but
+// their password.
+func (c *Config) WriteYAML(w io.Writer, redact bool) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	rv := reflect.ValueOf(c).Elem()
+	for _, s := range settings {
+		parent := root
+		path := strings.Split(s.key, ".")
+		for _, section := range path[:len(path)-1] {
+			parent = child(parent, section)
+		}
+		value := valueNode(rv.Field(s.index).Interface())
+		if redact && s.secret && value.Value != "" {
+			value.Value = redacted(value.Value)
+		}
+		parent.Content = append(parent.Content, scalar(path[len(path)-1]), value)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// child returns the mapping under name in m, adding it when missing.
+func child(m *yaml.Node, name string) *yaml.Node {
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == name {
+			return m.Content[i+1]
+		}
+	}
+	c := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, scalar(name), c)
+	return c
+}
+
+func valueNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case string:
+		return scalar(v)
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(v)}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}
+	case time.Duration:
+		return scalar(v.String())
+	case []string:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range v {
+			n.Content = append(n.Content, scalar(item))
+		}
+		return n
+	case map[string]time.Duration:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			n.Content = append(n.Content, scalar(name), scalar(v[name].String()))
+		}
+		if len(n.Content) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		return n
+	}
+	panic("config: cannot print " + reflect.TypeOf(v).String())
+}
+
+func scalar(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// redacted masks a secret value. Of URLs such as postgres DSNs only the
+// password is masked, keeping the host and database visible.
+func redacted(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return redactedValue
+}
@@ -5,17 +5,80 @@ import "time"
 type CVStatus string
 
 const (
-	CVStatusPending  CVStatus = "pending"
+	// CVStatusPending: record created, waiting for the client to upload.
+	CVStatusPending CVStatus = "pending"
+	// CVStatusUploaded: the object is in storage and the upload was completed.
 	CVStatusUploaded CVStatus = "uploaded"
+	// CVStatusScanning: the file is being checked (malware, validity).
+	CVStatusScanning CVStatus = "scanning"
+	// CVStatusProcessing: the file passed checks and is being processed.
+	CVStatusProcessing CVStatus = "processing"
+	// CVStatusReady: processing finished; the CV is usable.
+	CVStatusReady CVStatus = "ready"
+	// CVStatusRejected: the file failed validation or scanning.
+	CVStatusRejected CVStatus = "rejected"
+	// CVStatusExpired: the client never completed the upload in time.
+	CVStatusExpired CVStatus = "expired"
+	// CVStatusDeleted: the CV was deleted. Terminal.
+	CVStatusDeleted CVStatus = "deleted"
 )
 
 type CV struct {
-	ID        string
-	FileName  string
-	MimeType   string
-	Size      int64
-	GCSPath   string
-	Status    CVStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID string
+	// OwnerID is the profile or tenant the CV belongs to, the authenticated
+	// caller that started the upload. CVs without one are available to no
+	// caller.
+	OwnerID  string
+	FileName string
+	// MimeType is the content type declared by the client.
+	MimeType string
+	// DetectedMimeType is the type sniffed from the stored content when the
+	// upload was completed.
+	DetectedMimeType string
+	Size             int64
+	// GCSPath is the key of the CV's object. It is cleared once the reaper
+	// has removed the partial upload of an expired CV.
+	GCSPath string
+	// MD5 and CRC32C are base64 encoded digests of the content. Until the
+	// upload is completed they hold the values declared by the client, if any;
+	// afterwards the ones of the stored object.
+	MD5    string
+	CRC32C string
+	// DuplicateOf is the ID of the canonical CV whose object this one shares
+	// (GCSPath then points at that object); empty for canonical CVs.
+	DuplicateOf string
+	// RefCount is the number of duplicates linked to this canonical CV. Its
+	// object may only be deleted once the count is zero.
+	RefCount int
+	Status   CVStatus
+	// RejectReason explains why a CV is rejected.
+	RejectReason string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	// UploadExpiresAt is when the signed upload URL of a pending CV expires.
+	UploadExpiresAt time.Time
+	// UploadSessionURI is the resumable upload session of a pending CV, if it
+	// was started as one. It is cleared once the upload is completed.
+	UploadSessionURI string
+}
+
+// TransitionTo moves the CV to status to, stamping UpdatedAt with at. It
+// returns a *TransitionError, leaving the CV untouched, when the lifecycle does
+// not allow the change.
+func (cv *CV) TransitionTo(to CVStatus, at time.Time) error {
+	if !cv.Status.CanTransitionTo(to) {
+		return &TransitionError{ID: cv.ID, From: cv.Status, To: to}
+	}
+	cv.Status = to
+	cv.UpdatedAt = at
+	return nil
+}
+
+// Reject moves the CV to CVStatusRejected, recording reason.
+func (cv *CV) Reject(reason string, at time.Time) error {
+	if err := cv.TransitionTo(CVStatusRejected, at); err != nil {
+		return err
+	}
+	cv.RejectReason = reason
+	return nil
 }
@@ -0,0 +1,72 @@
+package domain
+
+import "fmt"
+
+// ErrIllegalTransition matches every *TransitionError with errors.Is.
+var ErrIllegalTransition = NewError(ErrConflict, "illegal cv status transition")
+
+// transitions is the CV lifecycle:
+//
+//	pending ──► uploaded ──► scanning ──► processing ──► ready
+//	   │           │            │             │            │
+//	   ▼           ▼            ▼             ▼            │
+//	expired     rejected ◄──────┴─────────────┘            │
+//	   │           │                                       │
+//	   └───────────┴──────────► deleted ◄──────────────────┘
+//
+// Every non-deleted status may also go straight to deleted.
+var transitions = map[CVStatus][]CVStatus{
+	CVStatusPending:    {CVStatusUploaded, CVStatusExpired, CVStatusDeleted},
+	CVStatusUploaded:   {CVStatusScanning, CVStatusRejected, CVStatusDeleted},
+	CVStatusScanning:   {CVStatusProcessing, CVStatusRejected, CVStatusDeleted},
+	CVStatusProcessing: {CVStatusReady, CVStatusRejected, CVStatusDeleted},
+	CVStatusReady:      {CVStatusDeleted},
+	CVStatusRejected:   {CVStatusDeleted},
+	CVStatusExpired:    {CVStatusDeleted},
+	CVStatusDeleted:    nil,
+}
+
+// Valid reports whether s is a known status.
+func (s CVStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// Terminal reports whether no transition leaves s.
+func (s CVStatus) Terminal() bool {
+	return s.Valid() && len(transitions[s]) == 0
+}
+
+// HasAcceptedContent reports whether a CV in status s holds an upload that
+// passed validation: uploaded, scanning, processing or ready.
+func (s CVStatus) HasAcceptedContent() bool {
+	switch s {
+	case CVStatusUploaded, CVStatusScanning, CVStatusProcessing, CVStatusReady:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to to.
+func (s CVStatus) CanTransitionTo(to CVStatus) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError reports an attempt to move a CV along an edge the lifecycle
+// does not have, e.g. completing an upload twice.
+type TransitionError struct {
+	ID   string
+	From CVStatus
+	To   CVStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cv %s: cannot transition from %q to %q", e.ID, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error { return ErrIllegalTransition }
@@ -0,0 +1,103 @@
+package domain
+
+import "errors"
+
+// Error kinds. Errors callers can act on match exactly one of them with
+// errors.Is: sentinel errors are declared with NewError, and adapters
+// classify backend failures (gRPC codes, HTTP statuses) with WithKind. The
+// HTTP layer picks its response from the kind alone; errors of no kind are
+// internal errors.
+var (
+	// ErrNotFound: the addressed resource does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict: the request clashes with the current state of the
+	// resource, e.g. a taken ID or an illegal status transition.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation: the request is malformed or breaks a rule.
+	ErrValidation = errors.New("validation failed")
+	// ErrRejected: the request was well-formed, but the content it refers to
+	// was refused, e.g. an uploaded file of a disallowed type.
+	ErrRejected = errors.New("rejected")
+	// ErrPreconditionFailed: a condition the operation depends on does not
+	// hold, e.g. a generation match or a missing database index.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUnavailable: a backend is unreachable, overloaded or timed out;
+	// retrying later may succeed.
+	ErrUnavailable = errors.New("unavailable")
+	// ErrUnauthenticated: the caller is unknown.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrPermissionDenied: the caller may not access the resource.
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+var kinds = []error{
+	ErrNotFound,
+	ErrConflict,
+	ErrValidation,
+	ErrRejected,
+	ErrPreconditionFailed,
+	ErrUnavailable,
+	ErrUnauthenticated,
+	ErrPermissionDenied,
+}
+
+// NewError returns an error of kind with message msg, for declaring sentinel
+// errors: errors.Is matches it against itself and against kind.
+func NewError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// WithKind classifies err as kind, keeping its message and chain. It returns
+// nil for a nil err.
+func WithKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{kind: kind, err: err}
+}
+
+type classified struct {
+	kind error
+	err  error
+}
+
+func (e *classified) Error() string { return e.err.Error() }
+
+func (e *classified) Unwrap() error { return e.err }
+
+func (e *classified) Is(target error) bool { return target == e.kind }
+
+// KindOf returns the kind of err, or nil when it has none.
+func KindOf(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// FieldError is a violation of a rule by one request field.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// FieldsOf returns the offending fields of a validation error, found along
+// the chain of err as an error with a FieldErrors method, or nil.
+func FieldsOf(err error) []FieldError {
+	var fe interface{ FieldErrors() []FieldError }
+	if errors.As(err, &fe) {
+		return fe.FieldErrors()
+	}
+	return nil
+}
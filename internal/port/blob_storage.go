@@ -1,16 +1,126 @@
 package port
 
 import (
+	"context"
+	"io"
+	"net/http"
 	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/pkg/checksum"
+)
+
+var (
+	// ErrObjectNotFound is returned by BlobStorage reads of a missing object.
+	ErrObjectNotFound = domain.NewError(domain.ErrNotFound, "object not found")
+	// ErrSessionNotFound is returned for resumable sessions that do not exist
+	// or have expired.
+	ErrSessionNotFound = domain.NewError(domain.ErrNotFound, "upload session not found")
+	// ErrResumableUnsupported is returned by StartResumable on storages that
+	// have no resumable upload protocol; clients can upload through tus instead.
+	ErrResumableUnsupported = domain.NewError(domain.ErrValidation, "resumable uploads are not supported by this storage")
 )
 
+// Checksums are digests of object content, base64 encoded: MD5 (16 bytes) and
+// CRC32C (4 bytes, big-endian). An empty field is unknown.
+type Checksums struct {
+	MD5    string
+	CRC32C string
+}
+
+// Header returns the headers a PUT to a URL signed with these checksums must
+// carry: Content-MD5 and X-Goog-Hash with the crc32c entry.
+func (c Checksums) Header() http.Header {
+	h := http.Header{}
+	if c.MD5 != "" {
+		h.Set(checksum.HeaderMD5, c.MD5)
+	}
+	if c.CRC32C != "" {
+		h.Set(checksum.HeaderHash, checksum.HashHeader("", c.CRC32C))
+	}
+	return h
+}
+
 type SignedURLOptions struct {
 	Method      string
 	ContentType string
 	ExpiredAt   time.Time
+	// MinSize and MaxSize bound the uploaded size in bytes. POST policies
+	// enforce both, resumable sessions and self-served (localfs) PUT URLs
+	// MaxSize; MaxSize 0 means unbounded.
+	MinSize int64
+	MaxSize int64
+	// Checksums bind a PUT URL to content with these digests: the uploader
+	// must send Checksums.Header() and the storage refuses other content.
+	// POST policies ignore them.
+	Checksums Checksums
+	// ContentDisposition is the Content-Disposition a GET URL is served with,
+	// e.g. to download the object under its original file name.
+	ContentDisposition string
+}
+
+// PostPolicy is a signed browser-style form upload: the client POSTs a
+// multipart/form-data body to URL carrying Fields, followed by the content in
+// a final "file" part.
+type PostPolicy struct {
+	URL    string
+	Fields map[string]string
+}
+
+// ObjectAttrs is the metadata of a stored object. Checksums are those the
+// storage computed itself; either may be empty when the backend keeps none
+// (e.g. MD5 of composite GCS objects).
+type ObjectAttrs struct {
+	Size        int64
+	ContentType string
+	Checksums   Checksums
+}
+
+// UploadStatus is the progress of a resumable upload session.
+type UploadStatus struct {
+	// Received is the number of bytes persisted so far.
+	Received int64
+	// Complete is set once the object has been finalized.
+	Complete bool
+}
+
+// ObjectWriter streams the content of an object written by the server itself.
+// The object only becomes visible, replacing any previous one, when Close
+// returns nil; Abort discards everything written. Either ends the writer.
+type ObjectWriter interface {
+	io.Writer
+	Close() error
+	Abort() error
 }
 
+// BlobStorage stores CV objects. Every method honours the cancellation and
+// deadline of its ctx.
 type BlobStorage interface {
-	SignedURL(objectPath string, opts SignedURLOptions) (string, error)
-	Head(objectPath string) (exists bool, size int64, contentType string, err error)
+	SignedURL(ctx context.Context, objectPath string, opts SignedURLOptions) (string, error)
+	// SignedPostPolicy signs a form upload of objectPath bound to
+	// opts.ContentType and the opts size range. opts.Method is ignored.
+	SignedPostPolicy(ctx context.Context, objectPath string, opts SignedURLOptions) (*PostPolicy, error)
+	// StartResumable opens a resumable upload session for objectPath bound
+	// to opts.ContentType and returns the session URI. The client PUTs the
+	// content to it in chunks carrying Content-Range headers, as in the GCS
+	// resumable protocol, and may query progress with an empty PUT carrying
+	// "Content-Range: bytes */*". The session ends at opts.ExpiredAt. Storages
+	// without such a protocol return ErrResumableUnsupported.
+	StartResumable(ctx context.Context, objectPath string, opts SignedURLOptions) (sessionURI string, err error)
+	// ResumableStatus reports the progress of a session started by
+	// StartResumable, or ErrSessionNotFound.
+	ResumableStatus(ctx context.Context, sessionURI string) (UploadStatus, error)
+	// NewWriter starts writing objectPath with contentType, for content that
+	// reaches the server rather than the storage. The writer keeps the values
+	// of ctx but not its cancellation or deadline: it lives until closed or
+	// aborted, possibly across requests.
+	NewWriter(ctx context.Context, objectPath, contentType string) (ObjectWriter, error)
+	Head(ctx context.Context, objectPath string) (attrs ObjectAttrs, exists bool, err error)
+	// Read returns a reader over length bytes of the object starting at
+	// offset; a negative length reads to the end. Ranges running past the end
+	// are truncated. It returns ErrObjectNotFound for a missing object. The
+	// reader is bound to ctx, which must stay alive until it is closed.
+	Read(ctx context.Context, objectPath string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, objectPath string) error
 }
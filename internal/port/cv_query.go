@@ -0,0 +1,192 @@
+package port
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/pkg/cursor"
+)
+
+// CVSortField is a field CVs can be listed by. Ties are always broken by ID in
+// the same direction.
+type CVSortField string
+
+const (
+	CVSortCreatedAt CVSortField = "created_at"
+	CVSortUpdatedAt CVSortField = "updated_at"
+	CVSortSize      CVSortField = "size"
+)
+
+// SortOrder is the direction of a listing.
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// CVFilter narrows a listing. Zero values mean "no constraint".
+type CVFilter struct {
+	// OwnerID restricts the listing to the CVs of one owner.
+	OwnerID  string
+	Status   domain.CVStatus
+	MimeType string
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// MinSize and MaxSize are inclusive; MaxSize 0 means unbounded.
+	MinSize        int64
+	MaxSize        int64
+	FileNamePrefix string
+}
+
+// CVQuery describes one page of a CV listing.
+type CVQuery struct {
+	Filter CVFilter
+	SortBy CVSortField // defaults to CVSortCreatedAt
+	Order  SortOrder   // defaults to SortDesc
+	Limit  int         // <= 0 returns everything
+	Cursor string      // NextCursor of the previous page, empty for the first
+}
+
+// Normalized returns q with the default sort applied.
+func (q CVQuery) Normalized() CVQuery {
+	if q.SortBy == "" {
+		q.SortBy = CVSortCreatedAt
+	}
+	if q.Order == "" {
+		q.Order = SortDesc
+	}
+	return q
+}
+
+// Validate reports unsupported sort options or contradictory filters.
+func (q CVQuery) Validate() error {
+	q = q.Normalized()
+	switch q.SortBy {
+	case CVSortCreatedAt, CVSortUpdatedAt, CVSortSize:
+	default:
+		return fmt.Errorf("unsupported sort field %q", q.SortBy)
+	}
+	if q.Order != SortAsc && q.Order != SortDesc {
+		return fmt.Errorf("unsupported sort order %q", q.Order)
+	}
+	f := q.Filter
+	if f.Status != "" && !f.Status.Valid() {
+		return fmt.Errorf("unknown status %q", f.Status)
+	}
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && !f.CreatedFrom.Before(f.CreatedTo) {
+		return fmt.Errorf("created_from must be before created_to")
+	}
+	if f.MinSize < 0 || f.MaxSize < 0 {
+		return fmt.Errorf("size bounds must not be negative")
+	}
+	if f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return fmt.Errorf("min_size must not exceed max_size")
+	}
+	return nil
+}
+
+// Scope fingerprints the filter and sort of q. Repositories embed it in their
+// cursors so a cursor cannot be replayed against a different query.
+func (q CVQuery) Scope() string {
+	q = q.Normalized()
+	f := q.Filter
+	parts := []string{
+		string(q.SortBy), string(q.Order),
+		f.OwnerID, string(f.Status), f.MimeType,
+		fmtTime(f.CreatedFrom), fmtTime(f.CreatedTo),
+		fmt.Sprint(f.MinSize), fmt.Sprint(f.MaxSize),
+		f.FileNamePrefix,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Matches reports whether cv satisfies the filter. It defines the filter
+// semantics for repositories that evaluate filters in process.
+func (f CVFilter) Matches(cv domain.CV) bool {
+	switch {
+	case f.OwnerID != "" && cv.OwnerID != f.OwnerID:
+		return false
+	case f.Status != "" && cv.Status != f.Status:
+		return false
+	case f.MimeType != "" && cv.MimeType != f.MimeType:
+		return false
+	case !f.CreatedFrom.IsZero() && cv.CreatedAt.Before(f.CreatedFrom):
+		return false
+	case !f.CreatedTo.IsZero() && !cv.CreatedAt.Before(f.CreatedTo):
+		return false
+	case cv.Size < f.MinSize:
+		return false
+	case f.MaxSize > 0 && cv.Size > f.MaxSize:
+		return false
+	case !strings.HasPrefix(cv.FileName, f.FileNamePrefix):
+		return false
+	}
+	return true
+}
+
+// PositionOf returns the keyset position of cv in the listing of q.
+func (q CVQuery) PositionOf(cv domain.CV) cursor.Position {
+	q = q.Normalized()
+	pos := cursor.Position{Scope: q.Scope(), ID: cv.ID}
+	switch q.SortBy {
+	case CVSortUpdatedAt:
+		pos.Time = cv.UpdatedAt
+	case CVSortSize:
+		pos.Int = cv.Size
+	default:
+		pos.Time = cv.CreatedAt
+	}
+	return pos
+}
+
+// Compare orders two positions of q: it is negative when a is listed before b,
+// positive when after, and zero when they are the same position.
+func (q CVQuery) Compare(a, b cursor.Position) int {
+	c := a.Time.Compare(b.Time)
+	if c == 0 {
+		c = cmp.Compare(a.Int, b.Int)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if q.Normalized().Order == SortDesc {
+		return -c
+	}
+	return c
+}
+
+func fmtTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ContentQuery selects the canonical CV holding some content: a CV that is not
+// itself a duplicate, whose status has accepted content, and whose size and
+// checksums are equal to the query's. With ScopeToOwner only CVs of OwnerID
+// match.
+type ContentQuery struct {
+	MD5          string
+	CRC32C       string
+	Size         int64
+	OwnerID      string
+	ScopeToOwner bool
+}
+
+// Matches reports whether cv satisfies the query, for repositories that
+// evaluate it in process.
+func (q ContentQuery) Matches(cv domain.CV) bool {
+	return cv.DuplicateOf == "" &&
+		cv.Status.HasAcceptedContent() &&
+		cv.MD5 == q.MD5 && cv.CRC32C == q.CRC32C && cv.Size == q.Size &&
+		(!q.ScopeToOwner || cv.OwnerID == q.OwnerID)
+}
@@ -1,12 +1,55 @@
 package port
 
 import (
+	"context"
+
 	"cv-platform/internal/domain"
 )
 
+var (
+	// ErrCVNotFound is returned by CVRepository.FindByID when no CV has the id.
+	ErrCVNotFound = domain.NewError(domain.ErrNotFound, "cv not found")
+	// ErrCVAlreadyExists is returned by CVRepository.Create when the id is taken.
+	ErrCVAlreadyExists = domain.NewError(domain.ErrConflict, "cv already exists")
+	// ErrCVStatusChanged is returned by CVRepository.UpdateIfStatus when the
+	// stored CV is no longer in the expected status.
+	ErrCVStatusChanged = domain.NewError(domain.ErrConflict, "cv status changed")
+	// ErrInvalidCursor is returned by CVRepository.List for a cursor it did not
+	// issue for the same query.
+	ErrInvalidCursor = domain.NewError(domain.ErrValidation, "invalid cursor")
+	// ErrInvalidQuery is returned by CVRepository.List when CVQuery.Validate fails.
+	ErrInvalidQuery = domain.NewError(domain.ErrValidation, "invalid query")
+)
+
+// CVRepository persists CV metadata. Implementations must wrap the errors
+// above so callers can match them with errors.Is; porttest checks this. Other
+// backend failures should be classified with domain.WithKind where a kind
+// applies, e.g. domain.ErrUnavailable for timeouts. Every method honours the
+// cancellation and deadline of its ctx.
+//
+// List returns the CVs matching q.Filter ordered by q.SortBy, then ID, in
+// q.Order, and a cursor for the next page which is empty once the last page is
+// reached. A cursor issued for one query is rejected by any other query.
 type CVRepository interface {
-	Create(cv *domain.CV) error
-	Update(cv *domain.CV) error
-	FindByID(id string) (*domain.CV, error)
-	List(limit int, cursor string) ([]domain.CV, string, error)
+	Create(ctx context.Context, cv *domain.CV) error
+	// Update replaces the stored fields of cv.ID with those of cv, except
+	// RefCount: only AddRef changes it, so a stale copy cannot undo
+	// concurrent links. It returns ErrCVNotFound when no CV has the id.
+	Update(ctx context.Context, cv *domain.CV) error
+	// UpdateIfStatus is Update on the condition, checked atomically with the
+	// write, that the stored CV is still in status from. It returns
+	// ErrCVStatusChanged otherwise, so of two concurrent changes of the same
+	// status only one applies.
+	UpdateIfStatus(ctx context.Context, cv *domain.CV, from domain.CVStatus) error
+	FindByID(ctx context.Context, id string) (*domain.CV, error)
+	List(ctx context.Context, q CVQuery) ([]domain.CV, string, error)
+	// FindByContent returns the oldest CV (by CreatedAt, then ID) matching q,
+	// or ErrCVNotFound.
+	FindByContent(ctx context.Context, q ContentQuery) (*domain.CV, error)
+	// AddRef atomically adds delta to the RefCount of CV id and returns the
+	// new count. It returns ErrCVNotFound when no CV has the id.
+	AddRef(ctx context.Context, id string, delta int) (int, error)
+	// Delete removes the CV record for good. It returns ErrCVNotFound when
+	// no CV has the id.
+	Delete(ctx context.Context, id string) error
 }
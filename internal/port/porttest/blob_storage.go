@@ -0,0 +1,708 @@
+package porttest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/resumable"
+
+	"github.com/google/uuid"
+)
+
+// BlobStorageHarness is a storage under test together with the hooks the
+// suite needs to drive it from the outside.
+type BlobStorageHarness struct {
+	Storage port.BlobStorage
+	// Seed writes an object straight into the backend, bypassing signed URLs.
+	Seed func(t *testing.T, object string, data []byte, contentType string)
+	// Client sends requests to the storage's signed URLs. When nil, the
+	// round-trip checks are skipped and only URL generation is exercised.
+	Client *http.Client
+	// NoResumable declares a storage without resumable uploads: the
+	// resumable checks only expect StartResumable to fail with
+	// port.ErrResumableUnsupported.
+	NoResumable bool
+}
+
+// BlobStorageFactory returns a harness around an empty storage. It is called
+// once per subtest.
+type BlobStorageFactory func(t *testing.T) BlobStorageHarness
+
+// RunBlobStorageConformance runs the port.BlobStorage contract against the
+// storages produced by newStorage.
+//
+// For example, against the fake GCS server:
+//
+//	porttest.RunBlobStorageConformance(t, func(t *testing.T) porttest.BlobStorageHarness {
+//		srv := gcstest.NewServer("bucket")
+//		t.Cleanup(srv.Close)
+//		cl, _ := srv.Client(context.Background())
+//		st := gcp.NewGCSStorageFromClient(cl, "bucket",
+//			gcp.WithSigner(srv.AccessID, srv.PrivateKey),
+//			gcp.WithSignedURLHost(srv.Host(), true))
+//		return porttest.BlobStorageHarness{
+//			Storage: st,
+//			Seed: func(t *testing.T, object string, data []byte, ctype string) {
+//				srv.Put(object, data, ctype)
+//			},
+//			Client: http.DefaultClient,
+//		}
+//	})
+func RunBlobStorageConformance(t *testing.T, newStorage BlobStorageFactory) {
+	t.Helper()
+
+	t.Run("HeadMissingObject", func(t *testing.T) {
+		h := newStorage(t)
+		attrs, exists, err := h.Storage.Head(t.Context(), newObjectKey())
+		if err != nil {
+			t.Fatalf("Head missing object: got err %v, want nil", err)
+		}
+		if exists || attrs != (port.ObjectAttrs{}) {
+			t.Fatalf("Head missing object: got (%+v, %v), want zero attrs and false", attrs, exists)
+		}
+	})
+
+	t.Run("HeadReportsSizeAndContentType", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("%PDF-1.7 conformance")
+		h.Seed(t, key, data, "application/pdf")
+		assertHead(t, h.Storage, key, int64(len(data)), "application/pdf")
+	})
+
+	t.Run("HeadReportsChecksums", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("%PDF-1.7 checksummed")
+		h.Seed(t, key, data, "application/pdf")
+		attrs, _, err := h.Storage.Head(t.Context(), key)
+		if err != nil {
+			t.Fatalf("Head: %v", err)
+		}
+		md5Sum, crc32cSum := checksum.Sum(data)
+		if want := (port.Checksums{MD5: md5Sum, CRC32C: crc32cSum}); attrs.Checksums != want {
+			t.Fatalf("Head checksums: got %+v, want %+v", attrs.Checksums, want)
+		}
+	})
+
+	t.Run("ReadWholeObject", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("%PDF-1.7 read me")
+		h.Seed(t, key, data, "application/pdf")
+		if got := mustRead(t, h.Storage, key, 0, -1); !bytes.Equal(got, data) {
+			t.Fatalf("Read(0, -1): got %q, want %q", got, data)
+		}
+	})
+
+	t.Run("ReadRange", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("0123456789")
+		h.Seed(t, key, data, "text/plain")
+		if got := mustRead(t, h.Storage, key, 0, 4); string(got) != "0123" {
+			t.Fatalf("Read(0, 4): got %q, want %q", got, "0123")
+		}
+		if got := mustRead(t, h.Storage, key, 3, 4); string(got) != "3456" {
+			t.Fatalf("Read(3, 4): got %q, want %q", got, "3456")
+		}
+		if got := mustRead(t, h.Storage, key, 6, -1); string(got) != "6789" {
+			t.Fatalf("Read(6, -1): got %q, want %q", got, "6789")
+		}
+	})
+
+	t.Run("ReadPastEndIsTruncated", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		h.Seed(t, key, []byte("short"), "text/plain")
+		if got := mustRead(t, h.Storage, key, 0, 512); string(got) != "short" {
+			t.Fatalf("Read(0, 512): got %q, want %q", got, "short")
+		}
+	})
+
+	t.Run("ReadMissingObject", func(t *testing.T) {
+		h := newStorage(t)
+		rc, err := h.Storage.Read(t.Context(), newObjectKey(), 0, -1)
+		if err == nil {
+			rc.Close()
+		}
+		if !errors.Is(err, port.ErrObjectNotFound) {
+			t.Fatalf("Read missing object: got err %v, want ErrObjectNotFound", err)
+		}
+	})
+
+	t.Run("DeleteRemovesObject", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		h.Seed(t, key, []byte("partial"), "application/pdf")
+		if err := h.Storage.Delete(t.Context(), key); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("DeleteMissingObject", func(t *testing.T) {
+		h := newStorage(t)
+		if err := h.Storage.Delete(t.Context(), newObjectKey()); err != nil {
+			t.Fatalf("Delete missing object: got err %v, want nil", err)
+		}
+	})
+
+	t.Run("WriterStoresObjectOnClose", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		h.Seed(t, key, []byte("old"), "text/plain")
+		w, err := h.Storage.NewWriter(t.Context(), key, "application/pdf")
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		data := []byte("%PDF-1.7 written in pieces")
+		for _, chunk := range [][]byte{data[:8], data[8:]} {
+			if _, err := w.Write(chunk); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+		}
+		assertHead(t, h.Storage, key, 3, "text/plain")
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		assertHead(t, h.Storage, key, int64(len(data)), "application/pdf")
+		if got := mustRead(t, h.Storage, key, 0, -1); !bytes.Equal(got, data) {
+			t.Fatalf("Read: got %q, want %q", got, data)
+		}
+	})
+
+	t.Run("WriterAbortDiscardsObject", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		w, err := h.Storage.NewWriter(t.Context(), key, "application/pdf")
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		if _, err := w.Write([]byte("%PDF-1.7 abandoned")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Abort(); err != nil {
+			t.Fatalf("Abort: %v", err)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("SignedPutURLUploads", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		u := mustSign(t, h.Storage, key, http.MethodPut, "application/pdf", time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		data := []byte("%PDF-1.7 uploaded through a signed url")
+		if code := do(t, h.Client, http.MethodPut, u, "application/pdf", data); !success(code) {
+			t.Fatalf("PUT signed url: status %d", code)
+		}
+		assertHead(t, h.Storage, key, int64(len(data)), "application/pdf")
+	})
+
+	t.Run("SignedPutURLBindsContentType", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		u := mustSign(t, h.Storage, key, http.MethodPut, "application/pdf", time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := do(t, h.Client, http.MethodPut, u, "text/plain", []byte("hello")); success(code) {
+			t.Fatalf("PUT with other content type: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("SignedPutURLBindsChecksums", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("%PDF-1.7 with known digests")
+		md5Sum, crc32cSum := checksum.Sum(data)
+		sums := port.Checksums{MD5: md5Sum, CRC32C: crc32cSum}
+		u, err := h.Storage.SignedURL(t.Context(), key, port.SignedURLOptions{
+			Method:      http.MethodPut,
+			ContentType: "application/pdf",
+			ExpiredAt:   time.Now().Add(time.Hour),
+			Checksums:   sums,
+		})
+		if err != nil {
+			t.Fatalf("SignedURL: %v", err)
+		}
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := do(t, h.Client, http.MethodPut, u, "application/pdf", data); success(code) {
+			t.Fatalf("PUT without checksum headers: status %d, want rejection", code)
+		}
+		header := sums.Header()
+		header.Set("Content-Type", "application/pdf")
+		if code := doWithHeader(t, h.Client, http.MethodPut, u, header, []byte("%PDF-1.7 tampered")); success(code) {
+			t.Fatalf("PUT of other content: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+		if code := doWithHeader(t, h.Client, http.MethodPut, u, header, data); !success(code) {
+			t.Fatalf("PUT with checksum headers: status %d", code)
+		}
+		assertHead(t, h.Storage, key, int64(len(data)), "application/pdf")
+	})
+
+	t.Run("SignedPutURLBindsMethod", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		h.Seed(t, key, []byte("secret"), "text/plain")
+		u := mustSign(t, h.Storage, key, http.MethodPut, "text/plain", time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := do(t, h.Client, http.MethodGet, u, "", nil); success(code) {
+			t.Fatalf("GET on a PUT url: status %d, want rejection", code)
+		}
+	})
+
+	t.Run("SignedGetURLDownloads", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		data := []byte("%PDF-1.7 download me")
+		h.Seed(t, key, data, "application/pdf")
+		u := mustSign(t, h.Storage, key, http.MethodGet, "", time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		resp := send(t, h.Client, http.MethodGet, u, "", nil)
+		defer resp.Body.Close()
+		if !success(resp.StatusCode) {
+			t.Fatalf("GET signed url: status %d", resp.StatusCode)
+		}
+		got, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("GET signed url: got %q, want %q", got, data)
+		}
+		if code := do(t, h.Client, http.MethodPut, u, "", []byte("overwrite")); success(code) {
+			t.Fatalf("PUT on a GET url: status %d, want rejection", code)
+		}
+	})
+
+	t.Run("SignedGetURLSetsContentDisposition", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		h.Seed(t, key, []byte("%PDF-1.7 named download"), "application/pdf")
+		disposition := `attachment; filename="Jane Doe CV.pdf"`
+		u, err := h.Storage.SignedURL(t.Context(), key, port.SignedURLOptions{
+			Method:             http.MethodGet,
+			ExpiredAt:          time.Now().Add(time.Hour),
+			ContentDisposition: disposition,
+		})
+		if err != nil {
+			t.Fatalf("SignedURL: %v", err)
+		}
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		resp := send(t, h.Client, http.MethodGet, u, "", nil)
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if !success(resp.StatusCode) {
+			t.Fatalf("GET signed url: status %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Disposition"); got != disposition {
+			t.Fatalf("Content-Disposition: got %q, want %q", got, disposition)
+		}
+	})
+
+	t.Run("SignedPostPolicyUploads", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		p := mustSignPost(t, h.Storage, key, "application/pdf", 0, 1024, time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		data := []byte("%PDF-1.7 uploaded through a post policy")
+		if code := postForm(t, h.Client, p, nil, data); !success(code) {
+			t.Fatalf("POST policy form: status %d", code)
+		}
+		assertHead(t, h.Storage, key, int64(len(data)), "application/pdf")
+	})
+
+	t.Run("SignedPostPolicyEnforcesSizeRange", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		p := mustSignPost(t, h.Storage, key, "application/pdf", 4, 16, time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := postForm(t, h.Client, p, nil, bytes.Repeat([]byte("x"), 17)); success(code) {
+			t.Fatalf("POST above max size: status %d, want rejection", code)
+		}
+		if code := postForm(t, h.Client, p, nil, []byte("xx")); success(code) {
+			t.Fatalf("POST below min size: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+		if code := postForm(t, h.Client, p, nil, bytes.Repeat([]byte("x"), 16)); !success(code) {
+			t.Fatalf("POST at max size: status %d", code)
+		}
+	})
+
+	t.Run("SignedPostPolicyBindsContentType", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		p := mustSignPost(t, h.Storage, key, "application/pdf", 0, 1024, time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := postForm(t, h.Client, p, map[string]string{"content-type": "text/html"}, []byte("<p>")); success(code) {
+			t.Fatalf("POST with other content type: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("SignedPostPolicyBindsKey", func(t *testing.T) {
+		h := newStorage(t)
+		key, other := newObjectKey(), newObjectKey()
+		p := mustSignPost(t, h.Storage, key, "application/pdf", 0, 1024, time.Hour)
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := postForm(t, h.Client, p, map[string]string{"key": other}, []byte("%PDF-")); success(code) {
+			t.Fatalf("POST with other key: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+		assertMissing(t, h.Storage, other)
+	})
+
+	t.Run("SignedPostPolicyHonoursExpiry", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		p, err := h.Storage.SignedPostPolicy(t.Context(), key, port.SignedURLOptions{
+			ContentType: "application/pdf",
+			MaxSize:     1024,
+			ExpiredAt:   time.Now().Add(-time.Minute),
+		})
+		if err != nil {
+			// Refusing to sign an already expired policy is also acceptable.
+			return
+		}
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := postForm(t, h.Client, p, nil, []byte("late")); success(code) {
+			t.Fatalf("POST on an expired policy: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("ResumableUploadInChunks", func(t *testing.T) {
+		h := newStorage(t)
+		skipUnlessResumable(t, h)
+		key := newObjectKey()
+		uri := mustStartResumable(t, h.Storage, key, "application/pdf")
+		assertUploadStatus(t, h.Storage, uri, port.UploadStatus{})
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		data := []byte("%PDF-1.7 uploaded in two chunks")
+		total := len(data)
+		first := contentRange(0, 9, total)
+		if code := doWithHeader(t, h.Client, http.MethodPut, uri, first, data[:10]); code != resumable.StatusIncomplete {
+			t.Fatalf("PUT first chunk: status %d, want %d", code, resumable.StatusIncomplete)
+		}
+		assertUploadStatus(t, h.Storage, uri, port.UploadStatus{Received: 10})
+		assertMissing(t, h.Storage, key)
+
+		// Resending bytes already received is harmless, as after a lost response.
+		rest := contentRange(5, total-1, total)
+		if code := doWithHeader(t, h.Client, http.MethodPut, uri, rest, data[5:]); !success(code) {
+			t.Fatalf("PUT last chunk: status %d", code)
+		}
+		assertUploadStatus(t, h.Storage, uri, port.UploadStatus{Received: int64(total), Complete: true})
+		assertHead(t, h.Storage, key, int64(total), "application/pdf")
+		if got := mustRead(t, h.Storage, key, 0, -1); !bytes.Equal(got, data) {
+			t.Fatalf("Read: got %q, want %q", got, data)
+		}
+	})
+
+	t.Run("ResumableRejectsGaps", func(t *testing.T) {
+		h := newStorage(t)
+		skipUnlessResumable(t, h)
+		key := newObjectKey()
+		uri := mustStartResumable(t, h.Storage, key, "application/pdf")
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := doWithHeader(t, h.Client, http.MethodPut, uri, contentRange(4, 7, 8), []byte("late")); success(code) || code == resumable.StatusIncomplete {
+			t.Fatalf("PUT chunk past the received bytes: status %d, want rejection", code)
+		}
+		assertUploadStatus(t, h.Storage, uri, port.UploadStatus{})
+	})
+
+	t.Run("ResumableRejectsOversizedChunks", func(t *testing.T) {
+		h := newStorage(t)
+		skipUnlessResumable(t, h)
+		key := newObjectKey()
+		uri, err := h.Storage.StartResumable(t.Context(), key, port.SignedURLOptions{
+			ContentType: "application/pdf",
+			ExpiredAt:   time.Now().Add(time.Hour),
+			MaxSize:     8,
+		})
+		if err != nil {
+			t.Fatalf("StartResumable: %v", err)
+		}
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		data := []byte("%PDF-1.7 over the session maximum")
+		if code := doWithHeader(t, h.Client, http.MethodPut, uri, contentRange(0, len(data)-1, len(data)), data); success(code) || code == resumable.StatusIncomplete {
+			t.Fatalf("PUT chunk past MaxSize: status %d, want rejection", code)
+		}
+		assertUploadStatus(t, h.Storage, uri, port.UploadStatus{})
+		assertMissing(t, h.Storage, key)
+	})
+
+	t.Run("ResumableStatusUnknownSession", func(t *testing.T) {
+		h := newStorage(t)
+		skipUnlessResumable(t, h)
+		uri := mustStartResumable(t, h.Storage, newObjectKey(), "application/pdf")
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatalf("parse session uri: %v", err)
+		}
+		q := u.Query()
+		q.Set("upload_id", "0123456789abcdef0123456789abcdef")
+		u.RawQuery = q.Encode()
+		if _, err := h.Storage.ResumableStatus(t.Context(), u.String()); !errors.Is(err, port.ErrSessionNotFound) {
+			t.Fatalf("ResumableStatus of unknown session: got err %v, want ErrSessionNotFound", err)
+		}
+	})
+
+	t.Run("SignedURLHonoursExpiry", func(t *testing.T) {
+		h := newStorage(t)
+		key := newObjectKey()
+		u, err := h.Storage.SignedURL(t.Context(), key, port.SignedURLOptions{
+			Method:      http.MethodPut,
+			ContentType: "application/pdf",
+			ExpiredAt:   time.Now().Add(-time.Minute),
+		})
+		if err != nil {
+			// Refusing to sign an already expired URL is also acceptable.
+			return
+		}
+		if h.Client == nil {
+			t.Skip("no client for signed url round trips")
+		}
+		if code := do(t, h.Client, http.MethodPut, u, "application/pdf", []byte("late")); success(code) {
+			t.Fatalf("PUT on an expired url: status %d, want rejection", code)
+		}
+		assertMissing(t, h.Storage, key)
+	})
+}
+
+func newObjectKey() string {
+	return "cv/" + uuid.NewString() + ".pdf"
+}
+
+func mustSign(t *testing.T, s port.BlobStorage, key, method, ctype string, ttl time.Duration) string {
+	t.Helper()
+	u, err := s.SignedURL(t.Context(), key, port.SignedURLOptions{
+		Method:      method,
+		ContentType: ctype,
+		ExpiredAt:   time.Now().Add(ttl),
+	})
+	if err != nil {
+		t.Fatalf("SignedURL(%s): %v", method, err)
+	}
+	if strings.TrimSpace(u) == "" {
+		t.Fatalf("SignedURL(%s): empty url", method)
+	}
+	return u
+}
+
+func mustSignPost(t *testing.T, s port.BlobStorage, key, ctype string, minSize, maxSize int64, ttl time.Duration) *port.PostPolicy {
+	t.Helper()
+	p, err := s.SignedPostPolicy(t.Context(), key, port.SignedURLOptions{
+		ContentType: ctype,
+		MinSize:     minSize,
+		MaxSize:     maxSize,
+		ExpiredAt:   time.Now().Add(ttl),
+	})
+	if err != nil {
+		t.Fatalf("SignedPostPolicy: %v", err)
+	}
+	if strings.TrimSpace(p.URL) == "" {
+		t.Fatalf("SignedPostPolicy: empty url")
+	}
+	return p
+}
+
+// postForm submits the policy form with data as file part. Entries of
+// override replace (or add) form fields.
+func postForm(t *testing.T, cl *http.Client, p *port.PostPolicy, override map[string]string, data []byte) int {
+	t.Helper()
+	fields := maps.Clone(p.Fields)
+	maps.Copy(fields, override)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		if err := mw.WriteField(k, fields[k]); err != nil {
+			t.Fatalf("write form field %s: %v", k, err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatalf("create file part: %v", err)
+	}
+	_, _ = fw.Write(data)
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+	return do(t, cl, http.MethodPost, p.URL, mw.FormDataContentType(), body.Bytes())
+}
+
+// skipUnlessResumable checks that a storage declared without resumable
+// uploads refuses them, and skips the rest of the test.
+func skipUnlessResumable(t *testing.T, h BlobStorageHarness) {
+	t.Helper()
+	if !h.NoResumable {
+		return
+	}
+	_, err := h.Storage.StartResumable(t.Context(), newObjectKey(), port.SignedURLOptions{
+		ContentType: "application/pdf",
+		ExpiredAt:   time.Now().Add(time.Hour),
+	})
+	if !errors.Is(err, port.ErrResumableUnsupported) {
+		t.Fatalf("StartResumable: got err %v, want ErrResumableUnsupported", err)
+	}
+	t.Skip("storage has no resumable uploads")
+}
+
+func mustStartResumable(t *testing.T, s port.BlobStorage, key, ctype string) string {
+	t.Helper()
+	uri, err := s.StartResumable(t.Context(), key, port.SignedURLOptions{
+		ContentType: ctype,
+		ExpiredAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("StartResumable: %v", err)
+	}
+	if strings.TrimSpace(uri) == "" {
+		t.Fatalf("StartResumable: empty session uri")
+	}
+	return uri
+}
+
+func assertUploadStatus(t *testing.T, s port.BlobStorage, uri string, want port.UploadStatus) {
+	t.Helper()
+	got, err := s.ResumableStatus(t.Context(), uri)
+	if err != nil {
+		t.Fatalf("ResumableStatus: %v", err)
+	}
+	if got != want {
+		t.Fatalf("ResumableStatus: got %+v, want %+v", got, want)
+	}
+}
+
+func contentRange(first, last, total int) http.Header {
+	h := http.Header{}
+	h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, total))
+	return h
+}
+
+func mustRead(t *testing.T, s port.BlobStorage, key string, offset, length int64) []byte {
+	t.Helper()
+	rc, err := s.Read(t.Context(), key, offset, length)
+	if err != nil {
+		t.Fatalf("Read(%q, %d, %d): %v", key, offset, length, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Read(%q, %d, %d): reading body: %v", key, offset, length, err)
+	}
+	return b
+}
+
+func assertHead(t *testing.T, s port.BlobStorage, key string, size int64, ctype string) {
+	t.Helper()
+	attrs, exists, err := s.Head(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if !exists || attrs.Size != size || attrs.ContentType != ctype {
+		t.Fatalf("Head: got (%v, %d, %q), want (true, %d, %q)", exists, attrs.Size, attrs.ContentType, size, ctype)
+	}
+}
+
+func assertMissing(t *testing.T, s port.BlobStorage, key string) {
+	t.Helper()
+	_, exists, err := s.Head(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if exists {
+		t.Fatalf("Head: object %s exists, want missing", key)
+	}
+}
+
+func send(t *testing.T, cl *http.Client, method, u, ctype string, body []byte) *http.Response {
+	t.Helper()
+	return sendWithHeader(t, cl, method, u, contentType(ctype), body)
+}
+
+func sendWithHeader(t *testing.T, cl *http.Client, method, u string, header http.Header, body []byte) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		t.Fatalf("build %s request: %v", method, err)
+	}
+	maps.Copy(req.Header, header)
+	resp, err := cl.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, u, err)
+	}
+	return resp
+}
+
+func do(t *testing.T, cl *http.Client, method, u, ctype string, body []byte) int {
+	t.Helper()
+	return doWithHeader(t, cl, method, u, contentType(ctype), body)
+}
+
+func doWithHeader(t *testing.T, cl *http.Client, method, u string, header http.Header, body []byte) int {
+	t.Helper()
+	resp := sendWithHeader(t, cl, method, u, header, body)
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
+
+func contentType(ctype string) http.Header {
+	h := http.Header{}
+	if ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	return h
+}
+
+func success(code int) bool {
+	return code >= 200 && code < 300
+}
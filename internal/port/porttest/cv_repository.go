@@ -0,0 +1,627 @@
+// Package porttest holds conformance suites that every implementation of the
+// interfaces in package port must pass, so adapters stay interchangeable.
+//
+// A suite is run from the adapter's own tests, e.g. the memory repository's:
+//
+//	func TestCVRepositoryConformance(t *testing.T) {
+//		porttest.RunCVRepositoryConformance(t, func(t *testing.T) port.CVRepository {
+//			return memory.NewCVRepository()
+//		})
+//	}
+//
+// Suites of adapters backed by a service skip unless its emulator or test
+// instance is configured; see the gcp and postgres tests.
+package porttest
+
+import (
+	"cmp"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+
+	"github.com/google/uuid"
+)
+
+// CVRepositoryFactory returns an empty repository. It is called once per
+// subtest, so implementations backed by shared infrastructure should hand out
+// an isolated namespace (table, collection, ...) each time.
+type CVRepositoryFactory func(t *testing.T) port.CVRepository
+
+// RunCVRepositoryConformance runs the port.CVRepository contract against the
+// repositories produced by newRepo.
+func RunCVRepositoryConformance(t *testing.T, newRepo CVRepositoryFactory) {
+	t.Helper()
+
+	t.Run("CreateThenFindByID", func(t *testing.T) {
+		repo := newRepo(t)
+		want := newCV(time.Now())
+		mustCreate(t, repo, want)
+
+		got, err := repo.FindByID(t.Context(), want.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertCVEqual(t, got, want)
+	})
+
+	t.Run("CreateRejectsDuplicateID", func(t *testing.T) {
+		repo := newRepo(t)
+		orig := newCV(time.Now())
+		mustCreate(t, repo, orig)
+
+		dup := *orig
+		dup.FileName = "other.pdf"
+		err := repo.Create(t.Context(), &dup)
+		if !errors.Is(err, port.ErrCVAlreadyExists) {
+			t.Fatalf("Create duplicate: got err %v, want %v", err, port.ErrCVAlreadyExists)
+		}
+
+		got, err := repo.FindByID(t.Context(), orig.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertCVEqual(t, got, orig)
+	})
+
+	t.Run("UpdateReplacesFields", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newCV(time.Now())
+		mustCreate(t, repo, cv)
+
+		cv.Size = 4096
+		cv.MimeType = "application/pdf"
+		cv.DetectedMimeType = "text/plain"
+		cv.MD5 = "1B2M2Y8AsgTpgAmY7PhCfg=="
+		cv.CRC32C = "AAAAAA=="
+		cv.Status = domain.CVStatusRejected
+		cv.RejectReason = "declared type application/pdf does not match detected type text/plain"
+		cv.UpdatedAt = cv.UpdatedAt.Add(time.Minute)
+		if err := repo.Update(t.Context(), cv); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := repo.FindByID(t.Context(), cv.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertCVEqual(t, got, cv)
+	})
+
+	t.Run("UpdateKeepsRefCount", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newStoredCV(time.Now(), "")
+		mustCreate(t, repo, cv)
+		stale := *cv
+		if _, err := repo.AddRef(t.Context(), cv.ID, 2); err != nil {
+			t.Fatalf("AddRef: %v", err)
+		}
+
+		stale.Status = domain.CVStatusReady
+		stale.RefCount = 7
+		if err := repo.Update(t.Context(), &stale); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.FindByID(t.Context(), cv.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Status != domain.CVStatusReady || got.RefCount != 2 {
+			t.Fatalf("after Update of a stale copy: got status %s, RefCount %d; want %s, 2",
+				got.Status, got.RefCount, domain.CVStatusReady)
+		}
+	})
+
+	t.Run("UpdateNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newCV(time.Now())
+		if err := repo.Update(t.Context(), cv); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("Update of unknown ID: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+		if _, err := repo.FindByID(t.Context(), cv.ID); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("FindByID after Update of unknown ID: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("UpdateIfStatus", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newCV(time.Now())
+		mustCreate(t, repo, cv)
+
+		uploaded := *cv
+		uploaded.Status = domain.CVStatusUploaded
+		uploaded.Size = 2048
+		if err := repo.UpdateIfStatus(t.Context(), &uploaded, domain.CVStatusPending); err != nil {
+			t.Fatalf("UpdateIfStatus from pending: %v", err)
+		}
+		// A second writer that also read the pending CV must lose.
+		expired := *cv
+		expired.Status = domain.CVStatusExpired
+		err := repo.UpdateIfStatus(t.Context(), &expired, domain.CVStatusPending)
+		if !errors.Is(err, port.ErrCVStatusChanged) {
+			t.Fatalf("UpdateIfStatus of a stale status: got err %v, want %v", err, port.ErrCVStatusChanged)
+		}
+
+		got, err := repo.FindByID(t.Context(), cv.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertCVEqual(t, got, &uploaded)
+
+		if err := repo.UpdateIfStatus(t.Context(), newCV(time.Now()), domain.CVStatusPending); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("UpdateIfStatus of unknown ID: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("FindByIDNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		got, err := repo.FindByID(t.Context(), uuid.NewString())
+		if !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("FindByID missing: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+		if got != nil {
+			t.Fatalf("FindByID missing: got %+v, want nil", got)
+		}
+	})
+
+	t.Run("FindByContentReturnsOldestCanonical", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+		older, newer := newStoredCV(now.Add(-time.Hour), "alice"), newStoredCV(now, "alice")
+		dup := newStoredCV(now.Add(-2*time.Hour), "alice")
+		dup.DuplicateOf = older.ID
+		rejected := newStoredCV(now.Add(-3*time.Hour), "alice")
+		rejected.Status = domain.CVStatusRejected
+		other := newStoredCV(now.Add(-4*time.Hour), "alice")
+		other.Size++
+		for _, cv := range []*domain.CV{newer, older, dup, rejected, other} {
+			mustCreate(t, repo, cv)
+		}
+
+		got, err := repo.FindByContent(t.Context(), contentOf(older, false))
+		if err != nil {
+			t.Fatalf("FindByContent: %v", err)
+		}
+		assertCVEqual(t, got, older)
+	})
+
+	t.Run("FindByContentScopesToOwner", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+		alice, bob := newStoredCV(now.Add(-time.Hour), "alice"), newStoredCV(now, "bob")
+		mustCreate(t, repo, alice)
+		mustCreate(t, repo, bob)
+
+		got, err := repo.FindByContent(t.Context(), contentOf(bob, true))
+		if err != nil {
+			t.Fatalf("FindByContent scoped: %v", err)
+		}
+		assertCVEqual(t, got, bob)
+
+		q := contentOf(bob, true)
+		q.OwnerID = "carol"
+		if _, err := repo.FindByContent(t.Context(), q); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("FindByContent for other owner: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("FindByContentNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newCV(time.Now()))
+		cv := newStoredCV(time.Now(), "")
+		if _, err := repo.FindByContent(t.Context(), contentOf(cv, false)); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("FindByContent: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("AddRefCounts", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newStoredCV(time.Now(), "")
+		mustCreate(t, repo, cv)
+
+		for _, step := range []struct{ delta, want int }{{1, 1}, {1, 2}, {-1, 1}} {
+			got, err := repo.AddRef(t.Context(), cv.ID, step.delta)
+			if err != nil {
+				t.Fatalf("AddRef(%d): %v", step.delta, err)
+			}
+			if got != step.want {
+				t.Fatalf("AddRef(%d): got %d, want %d", step.delta, got, step.want)
+			}
+		}
+		stored, err := repo.FindByID(t.Context(), cv.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if stored.RefCount != 1 {
+			t.Fatalf("RefCount after AddRef: got %d, want 1", stored.RefCount)
+		}
+	})
+
+	t.Run("AddRefNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.AddRef(t.Context(), uuid.NewString(), 1); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("AddRef missing: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("DeleteRemovesCV", func(t *testing.T) {
+		repo := newRepo(t)
+		cv := newCV(time.Now())
+		other := newCV(time.Now())
+		mustCreate(t, repo, cv)
+		mustCreate(t, repo, other)
+
+		if err := repo.Delete(t.Context(), cv.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByID(t.Context(), cv.ID); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("FindByID after Delete: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+		if _, err := repo.FindByID(t.Context(), other.ID); err != nil {
+			t.Fatalf("FindByID of other cv: %v", err)
+		}
+	})
+
+	t.Run("DeleteNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.Delete(t.Context(), uuid.NewString()); !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("Delete missing: got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+
+	t.Run("ListEmpty", func(t *testing.T) {
+		repo := newRepo(t)
+		items, next, err := repo.List(t.Context(), port.CVQuery{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(items) != 0 || next != "" {
+			t.Fatalf("List on empty repo: got %d items, cursor %q", len(items), next)
+		}
+	})
+
+	t.Run("ListOrdersByCreatedAtDesc", func(t *testing.T) {
+		repo := newRepo(t)
+		want := seed(t, repo, 5)
+
+		items, next, err := repo.List(t.Context(), port.CVQuery{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if next != "" {
+			t.Errorf("List: got cursor %q on last page, want empty", next)
+		}
+		assertOrder(t, items, want)
+	})
+
+	t.Run("ListPagesWithCursor", func(t *testing.T) {
+		repo := newRepo(t)
+		want := seed(t, repo, 7)
+
+		var (
+			got    []domain.CV
+			cursor string
+		)
+		for page := 0; ; page++ {
+			if page > len(want) {
+				t.Fatalf("List: cursor never reached the end after %d pages", page)
+			}
+			items, next, err := repo.List(t.Context(), port.CVQuery{Limit: 3, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("List page %d: %v", page, err)
+			}
+			if len(items) > 3 {
+				t.Fatalf("List page %d: got %d items, limit is 3", page, len(items))
+			}
+			got = append(got, items...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		assertOrder(t, got, want)
+	})
+
+	t.Run("ListPagesStableUnderInserts", func(t *testing.T) {
+		repo := newRepo(t)
+		want := seed(t, repo, 6)
+
+		first, next, err := repo.List(t.Context(), port.CVQuery{Limit: 2})
+		if err != nil {
+			t.Fatalf("List first page: %v", err)
+		}
+		if next == "" {
+			t.Fatalf("List first page: missing cursor")
+		}
+		// A CV created between two page requests sorts before the cursor and
+		// must neither appear on later pages nor shift them.
+		mustCreate(t, repo, newCV(time.Now()))
+
+		got := append([]domain.CV(nil), first...)
+		for next != "" {
+			var items []domain.CV
+			items, next, err = repo.List(t.Context(), port.CVQuery{Limit: 2, Cursor: next})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			got = append(got, items...)
+		}
+		assertOrder(t, got, want)
+	})
+
+	t.Run("ListFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+		fx := []struct {
+			owner  string
+			name   string
+			mime   string
+			status domain.CVStatus
+			size   int64
+			age    time.Duration
+		}{
+			{"alice", "alice-cv.pdf", "application/pdf", domain.CVStatusUploaded, 100, 0},
+			{"alice", "alice-cover.docx", docxMime, domain.CVStatusUploaded, 2000, time.Hour},
+			{"bob", "bob-cv.pdf", "application/pdf", domain.CVStatusPending, 0, 2 * time.Hour},
+			{"bob", "bob-cv-v2.pdf", "application/pdf", domain.CVStatusUploaded, 5000, 3 * time.Hour},
+			{"carol", "carol.txt", "text/plain", domain.CVStatusUploaded, 50, 4 * time.Hour},
+		}
+		cvs := make([]domain.CV, len(fx))
+		for i, f := range fx {
+			cv := newCV(base.Add(f.age))
+			cv.OwnerID, cv.FileName, cv.MimeType, cv.Status, cv.Size = f.owner, f.name, f.mime, f.status, f.size
+			mustCreate(t, repo, cv)
+			cvs[i] = *cv
+		}
+
+		cases := []struct {
+			name   string
+			filter port.CVFilter
+			want   []int // indexes into fx, any order
+		}{
+			{"owner", port.CVFilter{OwnerID: "alice"}, []int{0, 1}},
+			{"unknown owner", port.CVFilter{OwnerID: "dave"}, nil},
+			{"status", port.CVFilter{Status: domain.CVStatusPending}, []int{2}},
+			{"mime type", port.CVFilter{MimeType: "application/pdf"}, []int{0, 2, 3}},
+			{"created from", port.CVFilter{CreatedFrom: base.Add(3 * time.Hour)}, []int{3, 4}},
+			{"created to", port.CVFilter{CreatedTo: base.Add(time.Hour)}, []int{0}},
+			{"created range", port.CVFilter{CreatedFrom: base.Add(time.Hour), CreatedTo: base.Add(3 * time.Hour)}, []int{1, 2}},
+			{"min size", port.CVFilter{MinSize: 2000}, []int{1, 3}},
+			{"max size", port.CVFilter{MaxSize: 100}, []int{0, 2, 4}},
+			{"size range", port.CVFilter{MinSize: 50, MaxSize: 2000}, []int{0, 1, 4}},
+			{"name prefix", port.CVFilter{FileNamePrefix: "bob-cv"}, []int{2, 3}},
+			{"combined", port.CVFilter{Status: domain.CVStatusUploaded, MimeType: "application/pdf", FileNamePrefix: "bob"}, []int{3}},
+			{"owner and status", port.CVFilter{OwnerID: "bob", Status: domain.CVStatusUploaded}, []int{3}},
+		}
+		for _, tc := range cases {
+			got := listAll(t, repo, port.CVQuery{Filter: tc.filter}, 2)
+			want := make([]domain.CV, 0, len(tc.want))
+			for _, i := range tc.want {
+				want = append(want, cvs[i])
+			}
+			sortCVs(want, port.CVSortCreatedAt, port.SortDesc)
+			t.Run(tc.name, func(t *testing.T) { assertOrder(t, got, want) })
+		}
+	})
+
+	t.Run("ListSorts", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		sizes := []int64{300, 100, 300, 0, 700, 100}
+		var all []domain.CV
+		for i, size := range sizes {
+			cv := newCV(base.Add(time.Duration(i) * time.Minute))
+			cv.Size = size
+			// Updates happen in the reverse order of creation.
+			cv.UpdatedAt = base.Add(time.Duration(len(sizes)-i) * time.Hour)
+			mustCreate(t, repo, cv)
+			all = append(all, *cv)
+		}
+
+		for _, field := range []port.CVSortField{port.CVSortCreatedAt, port.CVSortUpdatedAt, port.CVSortSize} {
+			for _, order := range []port.SortOrder{port.SortAsc, port.SortDesc} {
+				want := append([]domain.CV(nil), all...)
+				sortCVs(want, field, order)
+				got := listAll(t, repo, port.CVQuery{SortBy: field, Order: order}, 4)
+				t.Run(string(field)+"_"+string(order), func(t *testing.T) { assertOrder(t, got, want) })
+			}
+		}
+	})
+
+	t.Run("ListRejectsCursorOfOtherQuery", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo, 3)
+		_, next, err := repo.List(t.Context(), port.CVQuery{SortBy: port.CVSortSize, Limit: 1})
+		if err != nil || next == "" {
+			t.Fatalf("List: got cursor %q, err %v", next, err)
+		}
+		_, _, err = repo.List(t.Context(), port.CVQuery{Limit: 1, Cursor: next})
+		if !errors.Is(err, port.ErrInvalidCursor) {
+			t.Fatalf("List with cursor of another sort: got err %v, want %v", err, port.ErrInvalidCursor)
+		}
+		_, _, err = repo.List(t.Context(), port.CVQuery{SortBy: port.CVSortSize, Limit: 1, Cursor: next,
+			Filter: port.CVFilter{Status: domain.CVStatusPending}})
+		if !errors.Is(err, port.ErrInvalidCursor) {
+			t.Fatalf("List with cursor of another filter: got err %v, want %v", err, port.ErrInvalidCursor)
+		}
+	})
+
+	t.Run("ListRejectsInvalidQuery", func(t *testing.T) {
+		repo := newRepo(t)
+		_, _, err := repo.List(t.Context(), port.CVQuery{Filter: port.CVFilter{MinSize: 10, MaxSize: 5}})
+		if !errors.Is(err, port.ErrInvalidQuery) {
+			t.Fatalf("List with min_size > max_size: got err %v, want %v", err, port.ErrInvalidQuery)
+		}
+		_, _, err = repo.List(t.Context(), port.CVQuery{SortBy: "file_name"})
+		if !errors.Is(err, port.ErrInvalidQuery) {
+			t.Fatalf("List with unknown sort: got err %v, want %v", err, port.ErrInvalidQuery)
+		}
+	})
+
+	t.Run("ListRejectsForeignCursor", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo, 2)
+		_, _, err := repo.List(t.Context(), port.CVQuery{Limit: 10, Cursor: "not-a-cursor"})
+		if !errors.Is(err, port.ErrInvalidCursor) {
+			t.Fatalf("List with bogus cursor: got err %v, want %v", err, port.ErrInvalidCursor)
+		}
+	})
+}
+
+// newCV returns a pending CV created at the given time. Times are truncated to
+// microseconds, the coarsest precision among supported backends.
+func newCV(at time.Time) *domain.CV {
+	at = at.UTC().Truncate(time.Microsecond)
+	id := uuid.NewString()
+	return &domain.CV{
+		ID:              id,
+		FileName:        id + ".pdf",
+		MimeType:        "application/pdf",
+		GCSPath:         "cv/" + id + ".pdf",
+		Status:          domain.CVStatusPending,
+		CreatedAt:       at,
+		UpdatedAt:       at,
+		UploadExpiresAt: at.Add(10 * time.Minute),
+	}
+}
+
+// newStoredCV returns an uploaded CV of ownerID with fixed content.
+func newStoredCV(at time.Time, ownerID string) *domain.CV {
+	cv := newCV(at)
+	cv.OwnerID = ownerID
+	cv.Status = domain.CVStatusUploaded
+	cv.Size = 1024
+	cv.MD5 = "kK4ACRuYGUPUYk8dmDDuhA=="
+	cv.CRC32C = "yZRlqg=="
+	return cv
+}
+
+func contentOf(cv *domain.CV, scoped bool) port.ContentQuery {
+	return port.ContentQuery{
+		MD5:          cv.MD5,
+		CRC32C:       cv.CRC32C,
+		Size:         cv.Size,
+		OwnerID:      cv.OwnerID,
+		ScopeToOwner: scoped,
+	}
+}
+
+// seed creates n CVs and returns them in the expected listing order. Two of
+// them share a CreatedAt so the ID tie-break is exercised.
+func seed(t *testing.T, repo port.CVRepository, n int) []domain.CV {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	out := make([]domain.CV, 0, n)
+	for i := 0; i < n; i++ {
+		at := base.Add(time.Duration(i) * time.Second)
+		if i == 1 {
+			at = out[0].CreatedAt
+		}
+		cv := newCV(at)
+		mustCreate(t, repo, cv)
+		out = append(out, *cv)
+	}
+	sortCVs(out, port.CVSortCreatedAt, port.SortDesc)
+	return out
+}
+
+const docxMime = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// listAll walks every page of q, pageSize items at a time.
+func listAll(t *testing.T, repo port.CVRepository, q port.CVQuery, pageSize int) []domain.CV {
+	t.Helper()
+	q.Limit = pageSize
+	var out []domain.CV
+	for page := 0; ; page++ {
+		if page > 100 {
+			t.Fatalf("List: cursor never reached the end")
+		}
+		items, next, err := repo.List(t.Context(), q)
+		if err != nil {
+			t.Fatalf("List page %d: %v", page, err)
+		}
+		if len(items) > pageSize {
+			t.Fatalf("List page %d: got %d items, limit is %d", page, len(items), pageSize)
+		}
+		out = append(out, items...)
+		if next == "" {
+			return out
+		}
+		q.Cursor = next
+	}
+}
+
+// sortCVs sorts cvs in listing order, independently of port.CVQuery.Compare.
+func sortCVs(cvs []domain.CV, field port.CVSortField, order port.SortOrder) {
+	sort.SliceStable(cvs, func(i, j int) bool {
+		a, b := cvs[i], cvs[j]
+		var c int
+		switch field {
+		case port.CVSortUpdatedAt:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		case port.CVSortSize:
+			c = cmp.Compare(a.Size, b.Size)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if order == port.SortDesc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func mustCreate(t *testing.T, repo port.CVRepository, cv *domain.CV) {
+	t.Helper()
+	if err := repo.Create(t.Context(), cv); err != nil {
+		t.Fatalf("Create %s: %v", cv.ID, err)
+	}
+}
+
+func assertOrder(t *testing.T, got, want []domain.CV) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("List: got %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID {
+			t.Fatalf("List: item %d is %s, want %s (order %v)", i, got[i].ID, want[i].ID, ids(got))
+		}
+	}
+}
+
+func assertCVEqual(t *testing.T, got, want *domain.CV) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got nil cv, want %s", want.ID)
+	}
+	g, w := normalize(*got), normalize(*want)
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("cv mismatch:\n got  %+v\n want %+v", g, w)
+	}
+}
+
+// normalize strips monotonic clock readings and locations so that CVs read
+// back from a backend compare equal to the ones written.
+func normalize(cv domain.CV) domain.CV {
+	cv.CreatedAt = cv.CreatedAt.Round(0).UTC()
+	cv.UpdatedAt = cv.UpdatedAt.Round(0).UTC()
+	cv.UploadExpiresAt = cv.UploadExpiresAt.Round(0).UTC()
+	return cv
+}
+
+func ids(cvs []domain.CV) []string {
+	out := make([]string, len(cvs))
+	for i, cv := range cvs {
+		out[i] = cv.ID
+	}
+	return out
+}
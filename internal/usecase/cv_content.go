@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/filetype"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUploadRejected is returned by CompleteUpload, wrapped in a
+// *ValidationError, when the uploaded file breaks the upload policy. The CV is
+// stored as rejected with the reason.
+var ErrUploadRejected = domain.NewError(domain.ErrRejected, "upload rejected")
+
+// inspectContent sniffs the stored object of cv, records violations of the
+// policy in verr and returns the detected type. err is only set when the
+// object could not be read.
+func (uc *CVUploadUC) inspectContent(ctx context.Context, cv *domain.CV, verr *ValidationError) (string, error) {
+	if cv.Size == 0 {
+		return filetype.Unknown, nil
+	}
+	detected, err := filetype.Detect(&blobReaderAt{ctx: ctx, storage: uc.storage, path: cv.GCSPath}, cv.Size)
+	if err != nil {
+		return "", fmt.Errorf("failed to read object %s: %w", cv.GCSPath, err)
+	}
+	if !uc.policy.AllowsType(detected) {
+		verr.add("content", "file type %s is not allowed", detected)
+		return detected, nil
+	}
+	// Clients that do not know the type send none or octet-stream; only a
+	// concrete declared type has to match.
+	if declared := filetype.Normalize(cv.MimeType); declared != "" && declared != filetype.Unknown && declared != detected {
+		verr.add("content", "declared type %s does not match detected type %s", declared, detected)
+	}
+	return detected, nil
+}
+
+// verifyChecksums compares the checksums declared for cv with stored, the ones
+// the storage reports, and records a mismatch in verr. Digests the storage does
+// not report are computed from the content, so cv always ends up with the
+// checksums of the stored object. err is only set when it could not be read.
+func (uc *CVUploadUC) verifyChecksums(ctx context.Context, cv *domain.CV, stored port.Checksums, verr *ValidationError) error {
+	if stored.MD5 == "" || stored.CRC32C == "" {
+		rc, err := uc.storage.Read(ctx, cv.GCSPath, 0, -1)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %w", cv.GCSPath, err)
+		}
+		defer rc.Close()
+		h := checksum.New()
+		if _, err := io.Copy(h, rc); err != nil {
+			return fmt.Errorf("failed to read object %s: %w", cv.GCSPath, err)
+		}
+		stored = port.Checksums{MD5: h.MD5(), CRC32C: h.CRC32C()}
+	}
+
+	if cv.MD5 != "" && cv.MD5 != stored.MD5 {
+		verr.add("md5", "declared %s does not match the uploaded content (%s)", cv.MD5, stored.MD5)
+	}
+	if cv.CRC32C != "" && cv.CRC32C != stored.CRC32C {
+		verr.add("crc32c", "declared %s does not match the uploaded content (%s)", cv.CRC32C, stored.CRC32C)
+	}
+	cv.MD5, cv.CRC32C = stored.MD5, stored.CRC32C
+	return nil
+}
+
+// blobReaderAt reads an object through ranged BlobStorage reads.
+type blobReaderAt struct {
+	ctx     context.Context
+	storage port.BlobStorage
+	path    string
+}
+
+func (r *blobReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	rc, err := r.storage.Read(r.ctx, r.path, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	n, err := io.ReadFull(rc, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"errors"
+)
+
+// deduplicate looks for a canonical CV holding the same content as cv. Under
+// DuplicatesReject a match is recorded in verr; under DuplicatesLink cv is
+// linked to it: the canonical CV's RefCount is incremented, cv.DuplicateOf and
+// cv.GCSPath point at it, and the path of the now redundant upload is returned
+// for deletion once cv is saved. canonical is nil when cv holds new content.
+func (uc *CVUploadUC) deduplicate(ctx context.Context, cv *domain.CV, verr *ValidationError) (canonical *domain.CV, redundant string, err error) {
+	mode := uc.policy.Duplicates
+	if mode == "" || mode == DuplicatesAllow {
+		return nil, "", nil
+	}
+	canonical, err = uc.repo.FindByContent(ctx, port.ContentQuery{
+		MD5:          cv.MD5,
+		CRC32C:       cv.CRC32C,
+		Size:         cv.Size,
+		OwnerID:      cv.OwnerID,
+		ScopeToOwner: uc.policy.DuplicatesPerOwner,
+	})
+	if errors.Is(err, port.ErrCVNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if mode == DuplicatesReject {
+		verr.add("content", "duplicate of an existing cv")
+		return canonical, "", nil
+	}
+	if _, err := uc.repo.AddRef(ctx, canonical.ID, 1); err != nil {
+		return nil, "", err
+	}
+	redundant = cv.GCSPath
+	cv.DuplicateOf = canonical.ID
+	cv.GCSPath = canonical.GCSPath
+	return canonical, redundant, nil
+}
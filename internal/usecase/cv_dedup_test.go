@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"errors"
+	"testing"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+func TestCompleteUploadDuplicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     usecase.DuplicateMode
+		owner    string // of the second upload
+		perOwner bool
+		// wantLinked is whether the second CV shares the first's object.
+		wantLinked bool
+		wantErr    error
+	}{
+		{name: "allow", mode: usecase.DuplicatesAllow, owner: owner},
+		{name: "link", mode: usecase.DuplicatesLink, owner: owner, wantLinked: true},
+		{name: "link across owners", mode: usecase.DuplicatesLink, owner: "bob", wantLinked: true},
+		{name: "link per owner, other owner", mode: usecase.DuplicatesLink, owner: "bob", perOwner: true},
+		{name: "reject", mode: usecase.DuplicatesReject, owner: owner, wantErr: usecase.ErrUploadRejected},
+		{name: "reject per owner, other owner", mode: usecase.DuplicatesReject, owner: "bob", perOwner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := usecase.DefaultUploadPolicy()
+			policy.Duplicates, policy.DuplicatesPerOwner = tt.mode, tt.perOwner
+			f := newFixture(t, policy)
+
+			first, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+			if err != nil {
+				t.Fatalf("first CompleteUpload: %v", err)
+			}
+			res := f.start(t, usecase.StartUploadCmd{OwnerID: tt.owner})
+			f.storage.Put(res.ObjectKey, pdf, "application/pdf")
+			second, err := f.uploads.CompleteUpload(t.Context(), usecase.CompleteUploadCmd{ID: res.ID, RequesterID: tt.owner})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("second CompleteUpload: got err %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if got := fieldsOf(err); len(got) != 1 || got[0] != "content" {
+					t.Fatalf("fields: got %v, want [content]", got)
+				}
+				return
+			}
+
+			first = f.find(t, first.ID)
+			_, kept := f.storage.Get(res.ObjectKey)
+			if tt.wantLinked {
+				if second.DuplicateOf != first.ID || second.GCSPath != first.GCSPath || first.RefCount != 1 {
+					t.Fatalf("got duplicate of %q at %s with %d refs, want linked to %s at %s with 1 ref",
+						second.DuplicateOf, second.GCSPath, first.RefCount, first.ID, first.GCSPath)
+				}
+				if kept {
+					t.Fatalf("redundant upload %s kept", res.ObjectKey)
+				}
+				return
+			}
+			if second.DuplicateOf != "" || second.GCSPath != res.ObjectKey || first.RefCount != 0 || !kept {
+				t.Fatalf("got duplicate of %q at %s with %d refs, want an independent copy at %s",
+					second.DuplicateOf, second.GCSPath, first.RefCount, res.ObjectKey)
+			}
+		})
+	}
+}
+
+// TestDeleteDuplicates checks that the object shared by linked CVs outlives
+// every CV but the last one deleted, in either order.
+func TestDeleteDuplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		// order lists the CVs to delete by index, 0 being the canonical one.
+		order []int
+	}{
+		{name: "canonical first", order: []int{0, 1, 2}},
+		{name: "canonical last", order: []int{2, 1, 0}},
+		{name: "canonical between", order: []int{1, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			manage := usecase.NewCVManageUC(f.storage, f.repo)
+
+			var ids []string
+			for range 3 {
+				cv, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+				if err != nil {
+					t.Fatalf("CompleteUpload: %v", err)
+				}
+				ids = append(ids, cv.ID)
+			}
+			object := f.find(t, ids[0]).GCSPath
+			if got := f.find(t, ids[0]).RefCount; got != 2 {
+				t.Fatalf("RefCount: got %d, want 2", got)
+			}
+
+			for i, idx := range tt.order {
+				if _, err := manage.Delete(t.Context(), usecase.DeleteCVCmd{ID: ids[idx], RequesterID: owner}); err != nil {
+					t.Fatalf("Delete %s: %v", ids[idx], err)
+				}
+				last := i == len(tt.order)-1
+				if _, ok := f.storage.Get(object); ok == last {
+					t.Fatalf("after deleting %d of %d: object present %t", i+1, len(tt.order), ok)
+				}
+			}
+			if got := f.find(t, ids[0]).RefCount; got != 0 {
+				t.Fatalf("RefCount after deleting all: got %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestPurgeCanonical(t *testing.T) {
+	f := newFixture(t, usecase.DefaultUploadPolicy())
+	manage := usecase.NewCVManageUC(f.storage, f.repo)
+
+	canonical, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+	if err != nil {
+		t.Fatalf("CompleteUpload: %v", err)
+	}
+	duplicate, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+	if err != nil {
+		t.Fatalf("CompleteUpload: %v", err)
+	}
+
+	purge := usecase.DeleteCVCmd{ID: canonical.ID, RequesterID: owner, Purge: true}
+	if _, err := manage.Delete(t.Context(), purge); !errors.Is(err, usecase.ErrCVReferenced) || !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("purge referenced: got err %v, want %v", err, usecase.ErrCVReferenced)
+	}
+	if got := f.find(t, canonical.ID).Status; got != domain.CVStatusUploaded {
+		t.Fatalf("status after refused purge: got %s, want %s", got, domain.CVStatusUploaded)
+	}
+
+	if _, err := manage.Delete(t.Context(), usecase.DeleteCVCmd{ID: duplicate.ID, RequesterID: owner, Purge: true}); err != nil {
+		t.Fatalf("purge duplicate: %v", err)
+	}
+	if _, ok := f.storage.Get(canonical.GCSPath); !ok {
+		t.Fatalf("object %s deleted with the duplicate", canonical.GCSPath)
+	}
+	if _, err := manage.Delete(t.Context(), purge); err != nil {
+		t.Fatalf("purge unreferenced: %v", err)
+	}
+	if _, ok := f.storage.Get(canonical.GCSPath); ok {
+		t.Fatalf("object %s kept after purge", canonical.GCSPath)
+	}
+	if _, err := f.repo.FindByID(t.Context(), canonical.ID); err == nil {
+		t.Fatalf("FindByID %s after purge: found", canonical.ID)
+	}
+}
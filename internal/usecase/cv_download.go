@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/filetype"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// DefaultDownloadTTL is how long a signed download URL stays valid.
+const DefaultDownloadTTL = 5 * time.Minute
+
+var (
+	// ErrUnauthenticated is returned to anonymous callers of operations
+	// that need to know who is asking.
+	ErrUnauthenticated = domain.NewError(domain.ErrUnauthenticated, "authentication required")
+	// ErrForbidden is returned when the caller does not own the CV.
+	ErrForbidden = domain.NewError(domain.ErrPermissionDenied, "cv belongs to another owner")
+	// ErrNotDownloadable is returned for CVs without accepted content, e.g.
+	// pending or rejected ones.
+	ErrNotDownloadable = domain.NewError(domain.ErrConflict, "cv has no downloadable content")
+)
+
+// DownloadMode is how a CV is handed out.
+type DownloadMode string
+
+const (
+	// DownloadRedirect issues a short-lived signed GET URL, so the content is
+	// fetched from the storage directly.
+	DownloadRedirect DownloadMode = "redirect"
+	// DownloadStream reads the object through the API.
+	DownloadStream DownloadMode = "stream"
+)
+
+// Valid reports whether m is a known mode.
+func (m DownloadMode) Valid() bool {
+	switch m {
+	case DownloadRedirect, DownloadStream:
+		return true
+	}
+	return false
+}
+
+type CVDownloadUC struct {
+	storage port.BlobStorage
+	repo    port.CVRepository
+	mode    DownloadMode
+}
+
+// NewCVDownloadUC returns a download use case handing out CVs in mode unless
+// a request asks for another.
+func NewCVDownloadUC(storage port.BlobStorage, repo port.CVRepository, mode DownloadMode) *CVDownloadUC {
+	return &CVDownloadUC{
+		storage: storage,
+		repo:    repo,
+		mode:    mode,
+	}
+}
+
+type DownloadCmd struct {
+	ID string
+	// RequesterID is the authenticated caller. CVs can only be downloaded by
+	// their owner; CVs without one by nobody.
+	RequesterID string
+	// Mode overrides the default mode when set.
+	Mode DownloadMode
+}
+
+type DownloadResult struct {
+	CV   *domain.CV
+	Mode DownloadMode
+	// URL is the signed GET URL of DownloadRedirect, valid until ExpiresAt.
+	URL       string
+	ExpiresAt time.Time
+	// Body is the content of DownloadStream; the caller must close it.
+	Body io.ReadCloser
+	// ContentType, ContentDisposition and Size describe the content; the
+	// disposition names the file after the CV's original file name.
+	ContentType        string
+	ContentDisposition string
+	Size               int64
+}
+
+func (uc *CVDownloadUC) Download(ctx context.Context, cmd DownloadCmd) (*DownloadResult, error) {
+	log := logger.SimpleFromContext(ctx)
+	log.Infof("download requested: id=%s, requester=%s", cmd.ID, cmd.RequesterID)
+
+	mode := cmd.Mode
+	if mode == "" {
+		mode = uc.mode
+	}
+
+	cv, err := findOwned(ctx, uc.repo, cmd.ID, cmd.RequesterID)
+	if err != nil {
+		log.Warnf("download of cv %s refused to %q: %v", cmd.ID, cmd.RequesterID, err)
+		return nil, err
+	}
+	if !cv.Status.HasAcceptedContent() {
+		return nil, fmt.Errorf("%w: status %s", ErrNotDownloadable, cv.Status)
+	}
+
+	res := &DownloadResult{
+		CV:                 cv,
+		Mode:               mode,
+		ContentType:        downloadContentType(cv),
+		ContentDisposition: contentDisposition(cv.FileName),
+		Size:               cv.Size,
+	}
+	switch mode {
+	case DownloadStream:
+		if res.Body, err = uc.storage.Read(ctx, cv.GCSPath, 0, -1); err != nil {
+			log.Errorf("failed to read cv %s at path %s: %v", cmd.ID, cv.GCSPath, err)
+			return nil, err
+		}
+	case DownloadRedirect:
+		res.ExpiresAt = time.Now().Add(DefaultDownloadTTL)
+		res.URL, err = uc.storage.SignedURL(ctx, cv.GCSPath, port.SignedURLOptions{
+			Method:             http.MethodGet,
+			ExpiredAt:          res.ExpiresAt,
+			ContentDisposition: res.ContentDisposition,
+		})
+		if err != nil {
+			log.Errorf("failed to get signed download url for id %s: %v", cmd.ID, err)
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown download mode %q", mode)
+	}
+
+	log.Infof("download granted: id=%s, mode=%s, size=%d", cmd.ID, mode, cv.Size)
+	return res, nil
+}
+
+// downloadContentType prefers the sniffed type over the declared one.
+func downloadContentType(cv *domain.CV) string {
+	if cv.DetectedMimeType != "" && cv.DetectedMimeType != filetype.Unknown {
+		return cv.DetectedMimeType
+	}
+	if cv.MimeType != "" {
+		return cv.MimeType
+	}
+	return filetype.Unknown
+}
+
+// contentDisposition makes browsers save the content as fileName, encoding
+// non-ASCII names as RFC 2231 requires.
+func contentDisposition(fileName string) string {
+	if fileName == "" {
+		return "attachment"
+	}
+	if v := mime.FormatMediaType("attachment", map[string]string{"filename": fileName}); v != "" {
+		return v
+	}
+	return "attachment"
+}
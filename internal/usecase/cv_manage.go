@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCVReferenced is returned when purging a canonical CV whose object is
+// still shared by linked duplicates.
+var ErrCVReferenced = domain.NewError(domain.ErrConflict, "cv content is still referenced by duplicates")
+
+// CVManageUC reads and deletes single CVs on behalf of their owners.
+type CVManageUC struct {
+	storage port.BlobStorage
+	repo    port.CVRepository
+}
+
+func NewCVManageUC(storage port.BlobStorage, repo port.CVRepository) *CVManageUC {
+	return &CVManageUC{
+		storage: storage,
+		repo:    repo,
+	}
+}
+
+type GetCVCmd struct {
+	ID string
+	// RequesterID is the authenticated caller, see DownloadCmd.
+	RequesterID string
+}
+
+func (uc *CVManageUC) Get(ctx context.Context, cmd GetCVCmd) (*domain.CV, error) {
+	log := logger.SimpleFromContext(ctx)
+
+	cv, err := findOwned(ctx, uc.repo, cmd.ID, cmd.RequesterID)
+	if err != nil {
+		log.Warnf("cv %s not available to %q: %v", cmd.ID, cmd.RequesterID, err)
+		return nil, err
+	}
+	return cv, nil
+}
+
+type DeleteCVCmd struct {
+	ID string
+	// RequesterID is the authenticated caller, see DownloadCmd.
+	RequesterID string
+	// Purge removes the record as well, instead of keeping it with status
+	// deleted. Canonical CVs cannot be purged while duplicates reference them.
+	Purge bool
+}
+
+// Delete moves the CV to deleted and removes its object once nothing
+// references it: the object of a canonical CV outlives it while duplicates
+// are linked, and the last duplicate deleted after its canonical CV takes the
+// shared object with it. Deleting a deleted CV only retries the object
+// cleanup, or purges it. The returned CV is the final state of the record.
+func (uc *CVManageUC) Delete(ctx context.Context, cmd DeleteCVCmd) (*domain.CV, error) {
+	log := logger.SimpleFromContext(ctx)
+	log.Infof("deleting cv: id=%s, requester=%s, purge=%t", cmd.ID, cmd.RequesterID, cmd.Purge)
+
+	cv, err := findOwned(ctx, uc.repo, cmd.ID, cmd.RequesterID)
+	if err != nil {
+		log.Warnf("cv %s not available to %q: %v", cmd.ID, cmd.RequesterID, err)
+		return nil, err
+	}
+	if cmd.Purge && cv.RefCount > 0 {
+		return nil, fmt.Errorf("%w: %d duplicates of cv %s", ErrCVReferenced, cv.RefCount, cv.ID)
+	}
+
+	if cv.Status != domain.CVStatusDeleted {
+		from := cv.Status
+		if err := cv.TransitionTo(domain.CVStatusDeleted, time.Now()); err != nil {
+			return nil, err
+		}
+		// Conditional, so that of concurrent deletes only one releases the
+		// reference to the canonical CV.
+		if err := uc.repo.UpdateIfStatus(ctx, cv, from); err != nil {
+			log.Errorf("failed to update cv for id %s: %v", cmd.ID, err)
+			return nil, fmt.Errorf("failed to update cv: %w", err)
+		}
+		if cv.DuplicateOf != "" {
+			if _, err := uc.repo.AddRef(ctx, cv.DuplicateOf, -1); err != nil {
+				log.Errorf("failed to release reference to cv %s: %v", cv.DuplicateOf, err)
+			}
+		}
+	}
+
+	if err := uc.deleteObject(ctx, cv); err != nil {
+		log.Errorf("failed to delete object of cv %s at %s: %v", cmd.ID, cv.GCSPath, err)
+		return nil, err
+	}
+
+	if cmd.Purge {
+		if err := uc.repo.Delete(ctx, cv.ID); err != nil {
+			log.Errorf("failed to purge cv for id %s: %v", cmd.ID, err)
+			return nil, fmt.Errorf("failed to purge cv: %w", err)
+		}
+	}
+
+	log.Infof("cv deleted: id=%s, purged=%t", cmd.ID, cmd.Purge)
+	return cv, nil
+}
+
+// deleteObject removes the object of the deleted cv unless another CV still
+// references it.
+func (uc *CVManageUC) deleteObject(ctx context.Context, cv *domain.CV) error {
+	log := logger.SimpleFromContext(ctx)
+
+	if cv.GCSPath == "" {
+		return nil
+	}
+	if cv.RefCount > 0 {
+		log.Infof("keeping object %s of cv %s for %d duplicates", cv.GCSPath, cv.ID, cv.RefCount)
+		return nil
+	}
+	if cv.DuplicateOf != "" {
+		canonical, err := uc.repo.FindByID(ctx, cv.DuplicateOf)
+		if err != nil && !errors.Is(err, port.ErrCVNotFound) {
+			return fmt.Errorf("failed to find canonical cv: %w", err)
+		}
+		// Without its canonical record the references of the shared
+		// object can no longer be counted, so it is kept.
+		if err != nil || canonical.Status != domain.CVStatusDeleted || canonical.RefCount > 0 {
+			log.Infof("keeping object %s shared with cv %s", cv.GCSPath, cv.DuplicateOf)
+			return nil
+		}
+	}
+	if err := uc.storage.Delete(ctx, cv.GCSPath); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// findOwned loads CV id for requesterID, which must be set; see authorize.
+func findOwned(ctx context.Context, repo port.CVRepository, id, requesterID string) (*domain.CV, error) {
+	if requesterID == "" {
+		return nil, ErrUnauthenticated
+	}
+	cv, err := repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(cv.OwnerID, requesterID); err != nil {
+		return nil, err
+	}
+	return cv, nil
+}
+
+// authorize checks that requesterID, the authenticated caller, owns a CV of
+// ownerID. CVs without an owner are available to nobody.
+func authorize(ownerID, requesterID string) error {
+	if requesterID == "" {
+		return ErrUnauthenticated
+	}
+	if ownerID == "" || ownerID != requesterID {
+		return ErrForbidden
+	}
+	return nil
+}
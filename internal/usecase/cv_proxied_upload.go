@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUploadNotFound is returned for proxied uploads that do not exist on
+	// this instance, were terminated, finished or expired.
+	ErrUploadNotFound = domain.NewError(domain.ErrNotFound, "upload not found")
+	// ErrOffsetMismatch is returned for chunks that do not start where the
+	// upload stands.
+	ErrOffsetMismatch = domain.NewError(domain.ErrConflict, "upload offset mismatch")
+	// ErrChunkChecksumMismatch is returned for chunks that do not match their
+	// digest; nothing of them is appended.
+	ErrChunkChecksumMismatch = domain.NewError(domain.ErrValidation, "chunk checksum mismatch")
+	// ErrChunkTooLarge is returned for checksummed chunks over
+	// MaxChecksummedChunkSize; nothing of them is appended.
+	ErrChunkTooLarge = domain.NewError(domain.ErrValidation, "checksummed chunk too large")
+)
+
+// MaxChecksummedChunkSize bounds chunks sent with a digest, which are held in
+// memory until verified since the storage writer cannot take bytes back.
+// Larger uploads must be split into several such chunks.
+const MaxChecksummedChunkSize = 16 << 20
+
+// ProxiedUploadUC runs uploads whose content passes through the server, for
+// clients that cannot reach the storage directly. The file arrives in chunks
+// appended at known offsets, as with the tus protocol, and is streamed into
+// storage with BlobStorage.NewWriter. Once the declared length has arrived,
+// the upload is finalized like CVUploadUC.CompleteUpload.
+//
+// Upload state is kept in memory: the chunks of an upload must reach the
+// instance that created it, and uploads cut short by a restart stay pending
+// until the reaper expires them.
+type ProxiedUploadUC struct {
+	uploads *CVUploadUC
+
+	mu     sync.Mutex
+	active map[string]*proxiedUpload
+}
+
+func NewProxiedUploadUC(uploads *CVUploadUC) *ProxiedUploadUC {
+	return &ProxiedUploadUC{
+		uploads: uploads,
+		active:  make(map[string]*proxiedUpload),
+	}
+}
+
+// proxiedUpload is an upload in progress. mu serialises its chunks.
+type proxiedUpload struct {
+	mu      sync.Mutex
+	id      string
+	owner   string
+	length  int64
+	offset  int64
+	expires time.Time
+	writer  port.ObjectWriter
+	// done is set once the upload left uc.active.
+	done bool
+}
+
+// ProxiedUpload is the state of a proxied upload.
+type ProxiedUpload struct {
+	ID        string
+	Length    int64
+	Offset    int64
+	ExpiresAt time.Time
+	// CV is the finalized CV, set by the call that completed the upload.
+	CV *domain.CV
+}
+
+// MaxSize is the largest upload accepted, 0 when unlimited.
+func (uc *ProxiedUploadUC) MaxSize() int64 {
+	return uc.uploads.policy.MaxSize
+}
+
+// Create checks cmd against the upload policy, stores a pending CV and opens
+// a writer for cmd.Size bytes. cmd.Method is ignored.
+func (uc *ProxiedUploadUC) Create(ctx context.Context, cmd StartUploadCmd) (*ProxiedUpload, error) {
+	log := logger.SimpleFromContext(ctx)
+	log.Infof("starting proxied upload: file=%s, type=%s, size=%d", cmd.FileName, cmd.MimeType, cmd.Size)
+
+	cmd.Method = ""
+	if cmd.OwnerID == "" {
+		return nil, ErrUnauthenticated
+	}
+	fileName, sums, err := uc.uploads.policy.checkStart(cmd)
+	if err != nil {
+		log.Warnf("upload request violates policy: file=%s, %v", cmd.FileName, err)
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	objectKey := newObjectKey(id, fileName)
+	expires := time.Now().Add(uc.uploads.policy.ttl(UploadMethodResumable))
+
+	w, err := uc.uploads.storage.NewWriter(ctx, objectKey, cmd.MimeType)
+	if err != nil {
+		log.Errorf("failed to open writer for id %s: %v", id, err)
+		return nil, err
+	}
+	cv := newPendingCV(id, objectKey, fileName, cmd, sums, expires)
+	if err := uc.uploads.repo.Create(ctx, cv); err != nil {
+		log.Errorf("failed to create cv for id %s: %v", id, err)
+		_ = w.Abort()
+		return nil, err
+	}
+
+	u := &proxiedUpload{id: id, owner: cmd.OwnerID, length: cmd.Size, expires: expires, writer: w}
+	uc.mu.Lock()
+	uc.sweep()
+	uc.active[id] = u
+	uc.mu.Unlock()
+
+	log.Infof("proxied upload created: id=%s, key=%s, expires_at=%v", id, objectKey, expires)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.length == 0 {
+		return uc.finish(ctx, u)
+	}
+	return u.progress(), nil
+}
+
+// Status reports the offset reached by an upload of requesterID.
+func (uc *ProxiedUploadUC) Status(ctx context.Context, id, requesterID string) (*ProxiedUpload, error) {
+	u, err := uc.lookup(id, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.done {
+		return nil, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	return u.progress(), nil
+}
+
+type AppendChunkCmd struct {
+	ID string
+	// RequesterID is the authenticated caller, who must own the upload.
+	RequesterID string
+	// Offset is where the chunk starts; it must be the current offset.
+	Offset int64
+	// Body is read up to the declared length of the upload.
+	Body io.Reader
+	// Digest, when set, hashes the chunk, which must sum to Sum. Such chunks
+	// are buffered, up to MaxChecksummedChunkSize, and only appended once
+	// verified.
+	Digest hash.Hash
+	Sum    []byte
+}
+
+// Append writes a chunk at the current offset. When the body ends early, the
+// bytes received are kept and the error returned with the new offset. The call
+// that reaches the declared length finalizes the upload and returns the CV,
+// with CompleteUpload's errors.
+func (uc *ProxiedUploadUC) Append(ctx context.Context, cmd AppendChunkCmd) (*ProxiedUpload, error) {
+	log := logger.SimpleFromContext(ctx)
+
+	u, err := uc.lookup(cmd.ID, cmd.RequesterID)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.done {
+		return nil, fmt.Errorf("%w: %s", ErrUploadNotFound, cmd.ID)
+	}
+	if cmd.Offset != u.offset {
+		return u.progress(), fmt.Errorf("%w: upload is at %d, chunk starts at %d", ErrOffsetMismatch, u.offset, cmd.Offset)
+	}
+
+	body := io.LimitReader(cmd.Body, u.length-u.offset)
+	if cmd.Digest != nil {
+		var buf bytes.Buffer
+		n, err := io.Copy(io.MultiWriter(&buf, cmd.Digest), io.LimitReader(body, MaxChecksummedChunkSize+1))
+		if err != nil {
+			return u.progress(), fmt.Errorf("failed to read chunk: %w", err)
+		}
+		if n > MaxChecksummedChunkSize {
+			return u.progress(), fmt.Errorf("%w: at most %d bytes", ErrChunkTooLarge, MaxChecksummedChunkSize)
+		}
+		if !bytes.Equal(cmd.Digest.Sum(nil), cmd.Sum) {
+			return u.progress(), ErrChunkChecksumMismatch
+		}
+		body = &buf
+	}
+
+	sw := &storageWriter{w: u.writer}
+	n, err := io.Copy(sw, body)
+	u.offset += n
+	if sw.err != nil {
+		log.Errorf("failed to write upload %s, aborting it: %v", u.id, sw.err)
+		uc.drop(u)
+		_ = u.writer.Abort()
+		return nil, fmt.Errorf("failed to write upload: %w", sw.err)
+	}
+	if err != nil {
+		log.Warnf("chunk of upload %s ended early at offset %d: %v", u.id, u.offset, err)
+		return u.progress(), fmt.Errorf("failed to read chunk: %w", err)
+	}
+	log.Infof("chunk appended: id=%s, offset=%d, length=%d", u.id, u.offset, u.length)
+
+	if u.offset < u.length {
+		return u.progress(), nil
+	}
+	return uc.finish(ctx, u)
+}
+
+// Terminate aborts an upload of requesterID and marks its CV deleted. It
+// returns port.ErrCVStatusChanged when the CV is no longer pending, e.g.
+// because the reaper expired it.
+func (uc *ProxiedUploadUC) Terminate(ctx context.Context, id, requesterID string) error {
+	log := logger.SimpleFromContext(ctx)
+
+	u, err := uc.lookup(id, requesterID)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.done {
+		return fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	uc.drop(u)
+	if err := u.writer.Abort(); err != nil {
+		log.Warnf("failed to abort writer of upload %s: %v", id, err)
+	}
+
+	cv, err := uc.uploads.repo.FindByID(ctx, id)
+	if err != nil {
+		log.Errorf("failed to find cv for id %s: %v", id, err)
+		return err
+	}
+	if err := cv.TransitionTo(domain.CVStatusDeleted, time.Now()); err != nil {
+		return err
+	}
+	if err := uc.uploads.repo.UpdateIfStatus(ctx, cv, domain.CVStatusPending); err != nil {
+		log.Errorf("failed to update cv for id %s: %v", id, err)
+		return fmt.Errorf("failed to update cv: %w", err)
+	}
+
+	log.Infof("proxied upload terminated: id=%s, offset=%d", id, u.offset)
+	return nil
+}
+
+// finish commits the object of a fully received upload and finalizes its CV;
+// u.mu must be held.
+func (uc *ProxiedUploadUC) finish(ctx context.Context, u *proxiedUpload) (*ProxiedUpload, error) {
+	log := logger.SimpleFromContext(ctx)
+
+	uc.drop(u)
+	if err := u.writer.Close(); err != nil {
+		log.Errorf("failed to store upload %s: %v", u.id, err)
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+	p := u.progress()
+	cv, err := uc.uploads.CompleteUpload(ctx, CompleteUploadCmd{ID: u.id, RequesterID: u.owner})
+	p.CV = cv
+	return p, err
+}
+
+// lookup returns a live upload of requesterID. Expired uploads are aborted on
+// the way.
+func (uc *ProxiedUploadUC) lookup(id, requesterID string) (*proxiedUpload, error) {
+	if requesterID == "" {
+		return nil, ErrUnauthenticated
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	u, ok := uc.active[id]
+	if ok && !time.Now().Before(u.expires) {
+		uc.expire(u)
+		ok = false
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	if err := authorize(u.owner, requesterID); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// sweep aborts expired uploads that are not busy; uc.mu must be held.
+func (uc *ProxiedUploadUC) sweep() {
+	now := time.Now()
+	for _, u := range uc.active {
+		if !now.Before(u.expires) {
+			uc.expire(u)
+		}
+	}
+}
+
+// expire drops u and aborts its writer once no chunk is being written to it;
+// uc.mu must be held.
+func (uc *ProxiedUploadUC) expire(u *proxiedUpload) {
+	delete(uc.active, u.id)
+	go func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if !u.done {
+			u.done = true
+			_ = u.writer.Abort()
+		}
+	}()
+}
+
+// drop removes u from the active uploads; u.mu must be held.
+func (uc *ProxiedUploadUC) drop(u *proxiedUpload) {
+	uc.mu.Lock()
+	delete(uc.active, u.id)
+	uc.mu.Unlock()
+	u.done = true
+}
+
+func (u *proxiedUpload) progress() *ProxiedUpload {
+	return &ProxiedUpload{ID: u.id, Length: u.length, Offset: u.offset, ExpiresAt: u.expires}
+}
+
+// storageWriter records write errors, to tell them from read errors of
+// io.Copy.
+type storageWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *storageWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
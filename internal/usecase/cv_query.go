@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type CVQueryUC struct {
+	repo port.CVRepository
+}
+
+func NewCVQueryUC(repo port.CVRepository) *CVQueryUC {
+	return &CVQueryUC{repo: repo}
+}
+
+type ListCVsCmd struct {
+	// RequesterID is the authenticated caller, see DownloadCmd. Only their
+	// CVs are listed; Filter.OwnerID is overridden.
+	RequesterID string
+	Filter      port.CVFilter
+	SortBy      port.CVSortField
+	Order       port.SortOrder
+	Limit       int
+	Cursor      string
+}
+
+type ListCVsResult struct {
+	Items      []domain.CV
+	NextCursor string
+}
+
+func (uc *CVQueryUC) ListCVs(ctx context.Context, cmd ListCVsCmd) (*ListCVsResult, error) {
+	log := logger.SimpleFromContext(ctx)
+
+	if cmd.RequesterID == "" {
+		return nil, ErrUnauthenticated
+	}
+	filter := cmd.Filter
+	filter.OwnerID = cmd.RequesterID
+
+	limit := cmd.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	q := port.CVQuery{
+		Filter: filter,
+		SortBy: cmd.SortBy,
+		Order:  cmd.Order,
+		Limit:  limit,
+		Cursor: cmd.Cursor,
+	}.Normalized()
+	log.Infof("listing cvs: limit=%d, sort=%s %s, filter=%+v, has_cursor=%t",
+		limit, q.SortBy, q.Order, q.Filter, cmd.Cursor != "")
+
+	items, next, err := uc.repo.List(ctx, q)
+	if err != nil {
+		log.Errorf("failed to list cvs: %v", err)
+		return nil, err
+	}
+
+	log.Infof("listed cvs: count=%d, has_more=%t", len(items), next != "")
+	return &ListCVsResult{Items: items, NextCursor: next}, nil
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"errors"
+	"fmt"
+)
+
+// ErrUploadIncomplete is returned by CompleteUpload when the file of a CV has
+// not reached the storage yet, or only part of it through a resumable session.
+var ErrUploadIncomplete = domain.NewError(domain.ErrConflict, "upload incomplete")
+
+// ErrUploadClosed is returned by UploadStatus for a CV whose upload ended
+// without accepted content: rejected, expired or deleted.
+var ErrUploadClosed = domain.NewError(domain.ErrConflict, "upload closed")
+
+// UploadProgress is how much of a CV's file has reached the storage.
+type UploadProgress struct {
+	ID     string
+	Status domain.CVStatus
+	// Resumable tells whether the CV is uploaded through a resumable session.
+	Resumable bool
+	// Received is the number of bytes stored so far.
+	Received int64
+	// Complete is set once the whole file is stored.
+	Complete bool
+}
+
+// UploadStatus reports the progress of a pending upload. Resumable sessions
+// are asked for the bytes received; other uploads are stored in one go, so
+// they are either complete or not started. CVs past pending are complete once
+// their content was accepted, otherwise ErrUploadClosed is returned.
+// requesterID must own the CV.
+func (uc *CVUploadUC) UploadStatus(ctx context.Context, id, requesterID string) (*UploadProgress, error) {
+	log := logger.SimpleFromContext(ctx)
+
+	cv, err := findOwned(ctx, uc.repo, id, requesterID)
+	if err != nil {
+		log.Warnf("cv %s not available to %q: %v", id, requesterID, err)
+		return nil, err
+	}
+
+	p := &UploadProgress{ID: cv.ID, Status: cv.Status, Resumable: cv.UploadSessionURI != ""}
+	switch {
+	case cv.Status.HasAcceptedContent():
+		// Completed uploads no longer have a session to ask.
+		p.Received, p.Complete = cv.Size, true
+	case cv.Status != domain.CVStatusPending:
+		log.Warnf("upload of cv %s is closed: status=%s", id, cv.Status)
+		return nil, fmt.Errorf("%w: cv %s is %s", ErrUploadClosed, id, cv.Status)
+	case p.Resumable:
+		st, err := uc.storage.ResumableStatus(ctx, cv.UploadSessionURI)
+		if err != nil {
+			log.Errorf("failed to get session status for id %s: %v", id, err)
+			return nil, err
+		}
+		p.Received, p.Complete = st.Received, st.Complete
+	default:
+		attrs, ok, err := uc.storage.Head(ctx, cv.GCSPath)
+		if err != nil {
+			log.Errorf("failed to head cv for id %s at path %s: %v", id, cv.GCSPath, err)
+			return nil, err
+		}
+		if ok {
+			p.Received, p.Complete = attrs.Size, true
+		}
+	}
+
+	log.Infof("upload status: id=%s, received=%d, complete=%v", id, p.Received, p.Complete)
+	return p, nil
+}
+
+// checkSession returns ErrUploadIncomplete unless the resumable session of cv
+// has stored the whole file.
+func (uc *CVUploadUC) checkSession(ctx context.Context, cv *domain.CV) error {
+	st, err := uc.storage.ResumableStatus(ctx, cv.UploadSessionURI)
+	if errors.Is(err, port.ErrSessionNotFound) {
+		return fmt.Errorf("%w: session of cv %s is gone", ErrUploadIncomplete, cv.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session status: %w", err)
+	}
+	if !st.Complete {
+		return fmt.Errorf("%w: %d bytes received", ErrUploadIncomplete, st.Received)
+	}
+	return nil
+}
@@ -6,32 +6,77 @@ import (
 	logger "cv-platform/internal/log"
 	"cv-platform/internal/port"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// DefaultUploadTTL is how long a signed upload URL stays valid.
+const DefaultUploadTTL = 10 * time.Minute
+
+// DefaultResumableTTL is how long a resumable upload session stays valid,
+// long enough for large files sent over unreliable connections.
+const DefaultResumableTTL = 24 * time.Hour
+
 type CVUploadUC struct {
 	storage port.BlobStorage
 	repo    port.CVRepository
+	policy  UploadPolicy
 }
 
-func NewCVUploadUC(storage port.BlobStorage, repo port.CVRepository) *CVUploadUC {
+func NewCVUploadUC(storage port.BlobStorage, repo port.CVRepository, policy UploadPolicy) *CVUploadUC {
 	return &CVUploadUC{
 		storage: storage,
 		repo:    repo,
+		policy:  policy,
 	}
 }
 
+// Upload methods a client can ask StartUpload for.
+const (
+	// UploadMethodPUT issues a signed URL the file is PUT to.
+	UploadMethodPUT = "PUT"
+	// UploadMethodPOST issues a signed form (POST policy) whose size range is
+	// enforced by the storage itself.
+	UploadMethodPOST = "POST"
+	// UploadMethodResumable opens a resumable session the file is PUT to in
+	// chunks, as with the GCS resumable upload protocol.
+	UploadMethodResumable = "RESUMABLE"
+)
+
 type StartUploadCmd struct {
 	FileName string
 	MimeType string
+	// Size is the size announced by the client, checked against the policy
+	// when set. The real size is checked again on completion.
+	Size int64
+	// Method is UploadMethodPUT (default), UploadMethodPOST or
+	// UploadMethodResumable.
+	Method string
+	// OwnerID is the authenticated caller uploading the CV, its owner. It is
+	// required.
+	OwnerID string
+	// MD5 and CRC32C are optional digests of the file, base64 or hex
+	// encoded. PUT uploads are bound to them; every upload is checked against
+	// them on completion.
+	MD5    string
+	CRC32C string
 }
 
 type StartUploadResult struct {
 	ID        string
 	ObjectKey string
-	SignedURL string
+	// Method tells how to upload: PUT the file to SignedURL, POST a
+	// multipart form with FormFields and the file to SignedURL, or PUT the
+	// file in chunks with Content-Range headers to SessionURI.
+	Method     string
+	SignedURL  string
+	FormFields map[string]string
+	SessionURI string
+	// Headers must be sent with a PUT upload: the content type and the
+	// checksum headers the URL was signed with.
+	Headers   map[string]string
 	ExpiredAt time.Time
 }
 
@@ -39,100 +84,230 @@ func (uc *CVUploadUC) StartUpload(ctx context.Context, cmd StartUploadCmd) (*Sta
 	log := logger.SimpleFromContext(ctx)
 	log.Infof("starting upload process: file=%s, type=%s", cmd.FileName, cmd.MimeType)
 
-	id := uuid.New().String()
-	var ext string
-	if dot := lastDot(cmd.FileName); dot != -1 {
-		ext = cmd.FileName[dot+1:]
+	if cmd.OwnerID == "" {
+		return nil, ErrUnauthenticated
+	}
+	fileName, sums, err := uc.policy.checkStart(cmd)
+	if err != nil {
+		log.Warnf("upload request violates policy: file=%s, %v", cmd.FileName, err)
+		return nil, err
 	}
 
-	objectKey := fmt.Sprintf("cv/%s.%s", id, ext)
-	log.Infof("generating signed url: id=%s, key=%s, ext=%s", id, objectKey, ext)
+	id := uuid.New().String()
+	objectKey := newObjectKey(id, fileName)
+	log.Infof("generating signed url: id=%s, key=%s", id, objectKey)
 
+	method := strings.ToUpper(cmd.Method)
+	if method == "" {
+		method = UploadMethodPUT
+	}
 	opts := port.SignedURLOptions{
-		Method:      "PUT",
+		Method:      method,
 		ContentType: cmd.MimeType,
-		ExpiredAt:   time.Now().Add(10 * time.Minute),
+		ExpiredAt:   time.Now().Add(uc.policy.ttl(method)),
+		MinSize:     1,
+		MaxSize:     uc.policy.MaxSize,
+		Checksums:   sums,
 	}
-
-	url, err := uc.storage.SignedURL(objectKey, opts)
-	if err != nil {
-		log.Errorf("failed to get signed url for id %s: %v", id, err)
-		return nil, err
+	if cmd.Size > 0 {
+		// The announced size is binding for storages that can enforce it.
+		opts.MinSize, opts.MaxSize = cmd.Size, cmd.Size
 	}
 
-	cv := &domain.CV{
+	res := &StartUploadResult{
 		ID:        id,
-		FileName:  cmd.FileName,
-		MimeType:  cmd.MimeType,
-		Size:      0,
-		GCSPath:   objectKey,
-		Status:    domain.CVStatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ObjectKey: objectKey,
+		Method:    method,
+		ExpiredAt: opts.ExpiredAt,
+	}
+	switch method {
+	case UploadMethodPOST:
+		policy, err := uc.storage.SignedPostPolicy(ctx, objectKey, opts)
+		if err != nil {
+			log.Errorf("failed to get signed post policy for id %s: %v", id, err)
+			return nil, err
+		}
+		res.SignedURL, res.FormFields = policy.URL, policy.Fields
+	case UploadMethodResumable:
+		uri, err := uc.storage.StartResumable(ctx, objectKey, opts)
+		if err != nil {
+			log.Errorf("failed to start resumable session for id %s: %v", id, err)
+			return nil, err
+		}
+		res.SessionURI = uri
+	default:
+		url, err := uc.storage.SignedURL(ctx, objectKey, opts)
+		if err != nil {
+			log.Errorf("failed to get signed url for id %s: %v", id, err)
+			return nil, err
+		}
+		res.SignedURL = url
+		res.Headers = map[string]string{"Content-Type": cmd.MimeType}
+		for k, v := range sums.Header() {
+			res.Headers[k] = v[0]
+		}
 	}
 
+	cv := newPendingCV(id, objectKey, fileName, cmd, sums, opts.ExpiredAt)
+	cv.UploadSessionURI = res.SessionURI
+
 	log.Infof("saving cv to repository: id=%s, status=%s", id, cv.Status)
 
-	if err := uc.repo.Create(cv); err != nil {
+	if err := uc.repo.Create(ctx, cv); err != nil {
 		log.Errorf("failed to create cv for id %s: %v", id, err)
 		return nil, err
 	}
 
-	res := &StartUploadResult{
-		ID:        id,
-		ObjectKey: objectKey,
-		SignedURL: url,
-		ExpiredAt: opts.ExpiredAt,
-	}
-
-	log.Infof("upload initialized successfully: id=%s, expires_at=%v", id, res.ExpiredAt)
+	log.Infof("upload initialized successfully: id=%s, method=%s, expires_at=%v", id, res.Method, res.ExpiredAt)
 	return res, nil
 }
 
 type CompleteUploadCmd struct {
 	ID string
+	// RequesterID is the authenticated caller, who must own the CV.
+	RequesterID string
 }
 
 func (uc *CVUploadUC) CompleteUpload(ctx context.Context, cmd CompleteUploadCmd) (*domain.CV, error) {
 	log := logger.SimpleFromContext(ctx)
 	log.Infof("completing upload process for id: %s", cmd.ID)
 
-	cv, err := uc.repo.FindByID(cmd.ID)
+	cv, err := findOwned(ctx, uc.repo, cmd.ID, cmd.RequesterID)
 	if err != nil {
-		log.Errorf("failed to find cv for id %s: %v", cmd.ID, err)
+		log.Warnf("cv %s not available to %q: %v", cmd.ID, cmd.RequesterID, err)
+		return nil, err
+	}
+
+	// Fail fast on repeated or out-of-order calls before touching storage.
+	if !cv.Status.CanTransitionTo(domain.CVStatusUploaded) {
+		err := &domain.TransitionError{ID: cv.ID, From: cv.Status, To: domain.CVStatusUploaded}
+		log.Warnf("rejecting completion for id %s: %v", cmd.ID, err)
 		return nil, err
 	}
 
+	if cv.UploadSessionURI != "" {
+		if err := uc.checkSession(ctx, cv); err != nil {
+			log.Warnf("rejecting completion for id %s: %v", cmd.ID, err)
+			return nil, err
+		}
+	}
+
 	log.Infof("checking object in storage: path=%s", cv.GCSPath)
 
-	ok, size, ctype, err := uc.storage.Head(cv.GCSPath)
+	attrs, ok, err := uc.storage.Head(ctx, cv.GCSPath)
 	if err != nil {
 		log.Errorf("failed to head cv for id %s at path %s: %v", cmd.ID, cv.GCSPath, err)
 		return nil, err
 	}
 	if !ok {
 		log.Errorf("object not found in storage: id=%s, path=%s", cmd.ID, cv.GCSPath)
-		return nil, fmt.Errorf("object not found: %s", cv.GCSPath)
+		return nil, fmt.Errorf("%w: object %s not found", ErrUploadIncomplete, cv.GCSPath)
 	}
 
+	size, ctype := attrs.Size, attrs.ContentType
 	log.Infof("updating cv with file information: id=%s, size=%d, type=%s", cmd.ID, size, ctype)
 
+	now, from := time.Now(), cv.Status
+	if err := cv.TransitionTo(domain.CVStatusUploaded, now); err != nil {
+		log.Warnf("rejecting completion for id %s: %v", cmd.ID, err)
+		return nil, err
+	}
 	cv.Size = size
+	// The session URI grants write access; it is of no use once completed.
+	cv.UploadSessionURI = ""
 	if ctype != "" {
 		cv.MimeType = ctype
 	}
-	cv.Status = domain.CVStatusUploaded
-	cv.UpdatedAt = time.Now()
 
-	if err := uc.repo.Update(cv); err != nil {
+	verr := &ValidationError{Err: ErrUploadRejected}
+	uc.policy.checkSize(size, verr)
+	if err := uc.verifyChecksums(ctx, cv, attrs.Checksums, verr); err != nil {
+		log.Errorf("failed to verify checksums for id %s: %v", cmd.ID, err)
+		return nil, err
+	}
+	detected, err := uc.inspectContent(ctx, cv, verr)
+	if err != nil {
+		log.Errorf("failed to inspect content for id %s: %v", cmd.ID, err)
+		return nil, err
+	}
+	log.Infof("detected file type: id=%s, declared=%s, detected=%s", cmd.ID, cv.MimeType, detected)
+	cv.DetectedMimeType = detected
+
+	var redundant string
+	if len(verr.Fields) == 0 {
+		canonical, path, err := uc.deduplicate(ctx, cv, verr)
+		if err != nil {
+			log.Errorf("failed to look up duplicates for id %s: %v", cmd.ID, err)
+			return nil, err
+		}
+		if canonical != nil {
+			log.Infof("duplicate content: id=%s, canonical=%s, mode=%s", cmd.ID, canonical.ID, uc.policy.Duplicates)
+		}
+		redundant = path
+	}
+	if len(verr.Fields) > 0 {
+		if err := cv.Reject(verr.Reason(), now); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only one of concurrent completions of the CV gets past this update; the
+	// others release the reference they took above.
+	if err := uc.repo.UpdateIfStatus(ctx, cv, from); err != nil {
 		log.Errorf("failed to update cv for id %s: %v", cmd.ID, err)
+		if cv.DuplicateOf != "" {
+			if _, err := uc.repo.AddRef(ctx, cv.DuplicateOf, -1); err != nil {
+				log.Errorf("failed to release reference to cv %s: %v", cv.DuplicateOf, err)
+			}
+		}
 		return nil, fmt.Errorf("failed to update cv: %w", err)
 	}
 
+	if redundant != "" {
+		// The CV now shares the canonical object; a failure only leaves an
+		// unreferenced copy behind.
+		if err := uc.storage.Delete(ctx, redundant); err != nil {
+			log.Warnf("failed to delete redundant upload for id %s at %s: %v", cmd.ID, redundant, err)
+		}
+	}
+
+	if cv.Status == domain.CVStatusRejected {
+		log.Warnf("upload rejected: id=%s, reason=%s", cmd.ID, cv.RejectReason)
+		return cv, verr
+	}
+
 	log.Infof("upload completed successfully: id=%s, status=%s, size=%d", cmd.ID, cv.Status, cv.Size)
 	return cv, nil
 }
 
+// newObjectKey is the storage key of the CV id, keeping the extension of
+// fileName.
+func newObjectKey(id, fileName string) string {
+	var ext string
+	if dot := lastDot(fileName); dot != -1 {
+		ext = strings.ToLower(fileName[dot+1:])
+	}
+	return fmt.Sprintf("cv/%s.%s", id, ext)
+}
+
+func newPendingCV(id, objectKey, fileName string, cmd StartUploadCmd, sums port.Checksums, expires time.Time) *domain.CV {
+	now := time.Now()
+	return &domain.CV{
+		ID:              id,
+		OwnerID:         cmd.OwnerID,
+		FileName:        fileName,
+		MimeType:        cmd.MimeType,
+		Size:            0,
+		GCSPath:         objectKey,
+		MD5:             sums.MD5,
+		CRC32C:          sums.CRC32C,
+		Status:          domain.CVStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		UploadExpiresAt: expires,
+	}
+}
+
 func lastDot(s string) int {
 	for i := len(s) - 1; i >= 0; i-- {
 		if s[i] == '.' {
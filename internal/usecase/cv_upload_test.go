@@ -0,0 +1,275 @@
+package usecase_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/internal/usecase"
+	"cv-platform/pkg/checksum"
+)
+
+func TestCompleteUpload(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the CV to complete.
+		prepare    func(t *testing.T, f *fixture) string
+		requester  string
+		wantErr    error
+		wantStatus domain.CVStatus
+	}{
+		{
+			name: "stored object",
+			prepare: func(t *testing.T, f *fixture) string {
+				res := f.start(t, usecase.StartUploadCmd{})
+				f.storage.Put(res.ObjectKey, pdf, "application/pdf")
+				return res.ID
+			},
+			requester:  owner,
+			wantStatus: domain.CVStatusUploaded,
+		},
+		{
+			name: "object missing",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.start(t, usecase.StartUploadCmd{}).ID
+			},
+			requester:  owner,
+			wantErr:    usecase.ErrUploadIncomplete,
+			wantStatus: domain.CVStatusPending,
+		},
+		{
+			name: "completed twice",
+			prepare: func(t *testing.T, f *fixture) string {
+				cv, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+				if err != nil {
+					t.Fatalf("first CompleteUpload: %v", err)
+				}
+				return cv.ID
+			},
+			requester:  owner,
+			wantErr:    domain.ErrIllegalTransition,
+			wantStatus: domain.CVStatusUploaded,
+		},
+		{
+			name: "expired",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.seed(t, domain.CV{ID: "cv-expired", GCSPath: "cv/cv-expired.pdf", Status: domain.CVStatusExpired}).ID
+			},
+			requester:  owner,
+			wantErr:    domain.ErrIllegalTransition,
+			wantStatus: domain.CVStatusExpired,
+		},
+		{
+			name: "resumable session unfinished",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.start(t, usecase.StartUploadCmd{Method: usecase.UploadMethodResumable}).ID
+			},
+			requester:  owner,
+			wantErr:    usecase.ErrUploadIncomplete,
+			wantStatus: domain.CVStatusPending,
+		},
+		{
+			name: "other owner",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.start(t, usecase.StartUploadCmd{}).ID
+			},
+			requester:  "bob",
+			wantErr:    usecase.ErrForbidden,
+			wantStatus: domain.CVStatusPending,
+		},
+		{
+			name: "anonymous",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.start(t, usecase.StartUploadCmd{}).ID
+			},
+			requester:  "",
+			wantErr:    usecase.ErrUnauthenticated,
+			wantStatus: domain.CVStatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			id := tt.prepare(t, f)
+
+			_, err := f.uploads.CompleteUpload(t.Context(), usecase.CompleteUploadCmd{ID: id, RequesterID: tt.requester})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("CompleteUpload: got err %v, want %v", err, tt.wantErr)
+			}
+			if got := f.find(t, id).Status; got != tt.wantStatus {
+				t.Fatalf("status: got %s, want %s", got, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("unknown cv", func(t *testing.T) {
+		f := newFixture(t, usecase.DefaultUploadPolicy())
+		_, err := f.uploads.CompleteUpload(t.Context(), usecase.CompleteUploadCmd{ID: "nope", RequesterID: owner})
+		if !errors.Is(err, port.ErrCVNotFound) {
+			t.Fatalf("got err %v, want %v", err, port.ErrCVNotFound)
+		}
+	})
+}
+
+func TestCompleteUploadVerifiesChecksums(t *testing.T) {
+	md5Sum, crc32cSum := checksum.Sum(pdf)
+	otherMD5, otherCRC32C := checksum.Sum([]byte("%PDF-1.7 other content"))
+
+	tests := []struct {
+		name       string
+		md5        string
+		crc32c     string
+		wantFields []string
+	}{
+		{name: "none declared"},
+		{name: "both match", md5: md5Sum, crc32c: crc32cSum},
+		{name: "md5 mismatch", md5: otherMD5, crc32c: crc32cSum, wantFields: []string{"md5"}},
+		{name: "crc32c mismatch", md5: md5Sum, crc32c: otherCRC32C, wantFields: []string{"crc32c"}},
+		{name: "both mismatch", md5: otherMD5, crc32c: otherCRC32C, wantFields: []string{"md5", "crc32c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			cv, err := f.upload(t, pdf, usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", MD5: tt.md5, CRC32C: tt.crc32c})
+
+			fields := fieldsOf(err)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("CompleteUpload: %v", err)
+				}
+			} else if !errors.Is(err, usecase.ErrUploadRejected) || !slices.Equal(fields, tt.wantFields) {
+				t.Fatalf("CompleteUpload: got err %v with fields %v, want %v on %v", err, fields, usecase.ErrUploadRejected, tt.wantFields)
+			}
+			// Either way the CV ends up with the digests of the stored object.
+			if cv.MD5 != md5Sum || cv.CRC32C != crc32cSum {
+				t.Fatalf("checksums: got %s/%s, want %s/%s", cv.MD5, cv.CRC32C, md5Sum, crc32cSum)
+			}
+		})
+	}
+}
+
+func TestUploadStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, f *fixture) string
+		want    usecase.UploadProgress
+		wantErr error
+	}{
+		{
+			name: "nothing stored",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.start(t, usecase.StartUploadCmd{}).ID
+			},
+			want: usecase.UploadProgress{Status: domain.CVStatusPending},
+		},
+		{
+			name: "stored, not completed",
+			prepare: func(t *testing.T, f *fixture) string {
+				res := f.start(t, usecase.StartUploadCmd{})
+				f.storage.Put(res.ObjectKey, pdf, "application/pdf")
+				return res.ID
+			},
+			want: usecase.UploadProgress{Status: domain.CVStatusPending, Received: int64(len(pdf)), Complete: true},
+		},
+		{
+			name: "resumable, partly sent",
+			prepare: func(t *testing.T, f *fixture) string {
+				res := f.start(t, usecase.StartUploadCmd{Method: usecase.UploadMethodResumable})
+				req, err := http.NewRequest(http.MethodPut, res.SessionURI, bytes.NewReader(pdf[:10]))
+				if err != nil {
+					t.Fatalf("NewRequest: %v", err)
+				}
+				req.Header.Set("Content-Range", "bytes 0-9/*")
+				resp, err := f.storage.Client().Do(req)
+				if err != nil {
+					t.Fatalf("PUT chunk: %v", err)
+				}
+				resp.Body.Close()
+				return res.ID
+			},
+			want: usecase.UploadProgress{Status: domain.CVStatusPending, Resumable: true, Received: 10},
+		},
+		{
+			name: "completed",
+			prepare: func(t *testing.T, f *fixture) string {
+				cv, err := f.upload(t, pdf, usecase.StartUploadCmd{})
+				if err != nil {
+					t.Fatalf("CompleteUpload: %v", err)
+				}
+				return cv.ID
+			},
+			want: usecase.UploadProgress{Status: domain.CVStatusUploaded, Received: int64(len(pdf)), Complete: true},
+		},
+		{
+			name: "rejected",
+			prepare: func(t *testing.T, f *fixture) string {
+				cv, _ := f.upload(t, []byte("not a pdf"), usecase.StartUploadCmd{})
+				return cv.ID
+			},
+			wantErr: usecase.ErrUploadClosed,
+		},
+		{
+			name: "expired",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.seed(t, domain.CV{ID: "cv-expired", Status: domain.CVStatusExpired}).ID
+			},
+			wantErr: usecase.ErrUploadClosed,
+		},
+		{
+			name: "deleted",
+			prepare: func(t *testing.T, f *fixture) string {
+				return f.seed(t, domain.CV{ID: "cv-deleted", Size: 10, Status: domain.CVStatusDeleted}).ID
+			},
+			wantErr: usecase.ErrUploadClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			id := tt.prepare(t, f)
+
+			got, err := f.uploads.UploadStatus(t.Context(), id, owner)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, domain.ErrConflict) {
+					t.Fatalf("UploadStatus: got %+v, %v, want err %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadStatus: %v", err)
+			}
+			tt.want.ID = id
+			if *got != tt.want {
+				t.Fatalf("UploadStatus: got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUploadTTL checks the validity of the upload URLs by method.
+func TestUploadTTL(t *testing.T) {
+	policy := usecase.DefaultUploadPolicy()
+	policy.UploadTTL, policy.ResumableTTL = 5*time.Minute, 2*time.Hour
+	for method, want := range map[string]time.Duration{
+		usecase.UploadMethodPUT:       5 * time.Minute,
+		usecase.UploadMethodPOST:      5 * time.Minute,
+		usecase.UploadMethodResumable: 2 * time.Hour,
+	} {
+		t.Run(method, func(t *testing.T) {
+			f := newFixture(t, policy)
+			before := time.Now()
+			res := f.start(t, usecase.StartUploadCmd{Method: method})
+			if got := res.ExpiredAt.Sub(before); got < want || got > want+time.Minute {
+				t.Fatalf("ExpiredAt in %s, want %s", got, want)
+			}
+			if got := f.find(t, res.ID).UploadExpiresAt; !got.Equal(res.ExpiredAt) {
+				t.Fatalf("UploadExpiresAt: got %v, want %v", got, res.ExpiredAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"cv-platform/internal/domain"
+	logger "cv-platform/internal/log"
+	"cv-platform/internal/port"
+	"fmt"
+	"time"
+)
+
+// ReaperConfig tunes PendingReaperUC.
+type ReaperConfig struct {
+	// GracePeriod is how long past its upload expiry a pending CV is left
+	// alone, so in-flight uploads started just before expiry can complete.
+	GracePeriod time.Duration
+	// BatchSize is the number of CVs loaded per repository page.
+	BatchSize int
+	// UploadTTL is used as expiry for CVs created without UploadExpiresAt.
+	UploadTTL time.Duration
+}
+
+// PendingReaperUC expires CVs whose client never finished the upload and
+// removes any partial object they left in storage.
+type PendingReaperUC struct {
+	storage port.BlobStorage
+	repo    port.CVRepository
+	cfg     ReaperConfig
+	now     func() time.Time
+}
+
+func NewPendingReaperUC(storage port.BlobStorage, repo port.CVRepository, cfg ReaperConfig) *PendingReaperUC {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.UploadTTL <= 0 {
+		cfg.UploadTTL = DefaultUploadTTL
+	}
+	return &PendingReaperUC{
+		storage: storage,
+		repo:    repo,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+type SweepResult struct {
+	Scanned int
+	Expired int
+	// Cleaned counts expired CVs whose object could only be deleted on a
+	// later sweep.
+	Cleaned int
+	Failed  int
+}
+
+// Sweep makes one pass over the pending CVs and expires those whose upload
+// window, plus the grace period, is over. Failures on individual CVs are logged
+// and counted; they are retried on the next sweep, which first deletes the
+// objects still left by expired CVs.
+func (uc *PendingReaperUC) Sweep(ctx context.Context) (*SweepResult, error) {
+	log := logger.SimpleFromContext(ctx)
+	now := uc.now()
+	res := &SweepResult{}
+
+	// The upload window never ends before creation, so only CVs created
+	// before now-grace can possibly be overdue.
+	createdTo := now.Add(-uc.cfg.GracePeriod)
+	log.Infof("sweeping pending uploads: grace=%s, batch_size=%d", uc.cfg.GracePeriod, uc.cfg.BatchSize)
+
+	err := uc.each(ctx, domain.CVStatusExpired, createdTo, func(cv *domain.CV) {
+		if cv.GCSPath == "" {
+			return
+		}
+		if err := uc.deleteObject(ctx, cv, now); err != nil {
+			log.Errorf("failed to clean up expired cv %s: %v", cv.ID, err)
+			res.Failed++
+			return
+		}
+		log.Infof("cleaned up expired upload: id=%s", cv.ID)
+		res.Cleaned++
+	})
+	if err != nil {
+		return res, err
+	}
+
+	err = uc.each(ctx, domain.CVStatusPending, createdTo, func(cv *domain.CV) {
+		res.Scanned++
+		if !uc.overdue(cv, now) {
+			return
+		}
+		path := cv.GCSPath
+		if err := uc.expire(ctx, cv, now); err != nil {
+			log.Errorf("failed to expire cv %s: %v", cv.ID, err)
+			res.Failed++
+			return
+		}
+		log.Infof("expired abandoned upload: id=%s, path=%s", cv.ID, path)
+		res.Expired++
+	})
+	if err != nil {
+		return res, err
+	}
+
+	log.Infof("sweep finished: scanned=%d, expired=%d, cleaned=%d, failed=%d", res.Scanned, res.Expired, res.Cleaned, res.Failed)
+	return res, nil
+}
+
+// each calls fn for every CV in status created before createdTo, oldest
+// first, one page at a time.
+func (uc *PendingReaperUC) each(ctx context.Context, status domain.CVStatus, createdTo time.Time, fn func(cv *domain.CV)) error {
+	q := port.CVQuery{
+		Filter: port.CVFilter{
+			Status:    status,
+			CreatedTo: createdTo,
+		},
+		SortBy: port.CVSortCreatedAt,
+		Order:  port.SortAsc,
+		Limit:  uc.cfg.BatchSize,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, next, err := uc.repo.List(ctx, q)
+		if err != nil {
+			logger.SimpleFromContext(ctx).Errorf("failed to list %s cvs: %v", status, err)
+			return fmt.Errorf("list %s cvs: %w", status, err)
+		}
+		for i := range batch {
+			fn(&batch[i])
+		}
+		if next == "" {
+			return nil
+		}
+		q.Cursor = next
+	}
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (uc *PendingReaperUC) Run(ctx context.Context, interval time.Duration) {
+	log := logger.SimpleFromContext(ctx)
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := uc.Sweep(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("pending upload sweep failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (uc *PendingReaperUC) overdue(cv *domain.CV, now time.Time) bool {
+	expiresAt := cv.UploadExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = cv.CreatedAt.Add(uc.cfg.UploadTTL)
+	}
+	return now.After(expiresAt.Add(uc.cfg.GracePeriod))
+}
+
+// expire marks the CV expired and only then deletes its partial object: the
+// conditional update loses against a completion that got there first, and
+// once expired the CV can no longer be completed. When the delete fails the
+// CV stays expired with its GCSPath set, so the next sweep retries it.
+func (uc *PendingReaperUC) expire(ctx context.Context, cv *domain.CV, now time.Time) error {
+	if err := cv.TransitionTo(domain.CVStatusExpired, now); err != nil {
+		return err
+	}
+	if err := uc.repo.UpdateIfStatus(ctx, cv, domain.CVStatusPending); err != nil {
+		return fmt.Errorf("update cv: %w", err)
+	}
+	return uc.deleteObject(ctx, cv, now)
+}
+
+// deleteObject deletes the object of expired cv and clears its GCSPath to
+// record that nothing is left to clean up.
+func (uc *PendingReaperUC) deleteObject(ctx context.Context, cv *domain.CV, now time.Time) error {
+	if err := uc.storage.Delete(ctx, cv.GCSPath); err != nil {
+		return fmt.Errorf("delete object %s: %w", cv.GCSPath, err)
+	}
+	cv.GCSPath = ""
+	cv.UpdatedAt = now
+	if err := uc.repo.UpdateIfStatus(ctx, cv, domain.CVStatusExpired); err != nil {
+		return fmt.Errorf("update cv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package usecase_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+// reaperConfig leaves uploads 10 minutes past their expiry.
+var reaperConfig = usecase.ReaperConfig{GracePeriod: 10 * time.Minute, UploadTTL: time.Hour}
+
+// pendingCV is a pending CV created age ago whose upload window closes at
+// expiresIn from now, or after the reaper's UploadTTL when expiresIn is 0.
+func pendingCV(id string, age, expiresIn time.Duration) domain.CV {
+	now := time.Now()
+	cv := domain.CV{ID: id, GCSPath: "cv/" + id + ".pdf", Status: domain.CVStatusPending, CreatedAt: now.Add(-age)}
+	if expiresIn != 0 {
+		cv.UploadExpiresAt = now.Add(expiresIn)
+	}
+	return cv
+}
+
+func TestSweep(t *testing.T) {
+	tests := []struct {
+		name        string
+		cv          domain.CV
+		wantExpired bool
+	}{
+		{name: "window open", cv: pendingCV("cv-open", 2*time.Hour, time.Minute)},
+		{name: "within grace period", cv: pendingCV("cv-grace", 2*time.Hour, -5*time.Minute)},
+		{name: "overdue", cv: pendingCV("cv-overdue", 2*time.Hour, -15*time.Minute), wantExpired: true},
+		{name: "no expiry, recent", cv: pendingCV("cv-recent", 30*time.Minute, 0)},
+		{name: "no expiry, older than the ttl", cv: pendingCV("cv-old", 2*time.Hour, 0), wantExpired: true},
+		{name: "created within the grace period", cv: pendingCV("cv-new", 5*time.Minute, -time.Hour)},
+		{
+			name: "uploaded",
+			cv: domain.CV{ID: "cv-uploaded", GCSPath: "cv/cv-uploaded.pdf", Status: domain.CVStatusUploaded,
+				CreatedAt: time.Now().Add(-2 * time.Hour), UploadExpiresAt: time.Now().Add(-time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			f.seed(t, tt.cv)
+			f.storage.Put(tt.cv.GCSPath, pdf[:10], "application/pdf")
+			reaper := usecase.NewPendingReaperUC(f.storage, f.repo, reaperConfig)
+
+			res, err := reaper.Sweep(t.Context())
+			if err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+			wantStatus, wantPath, wantResult := tt.cv.Status, tt.cv.GCSPath, usecase.SweepResult{}
+			if tt.cv.Status == domain.CVStatusPending && tt.cv.CreatedAt.Before(time.Now().Add(-reaperConfig.GracePeriod)) {
+				wantResult.Scanned = 1
+			}
+			if tt.wantExpired {
+				wantStatus, wantPath, wantResult.Expired = domain.CVStatusExpired, "", 1
+			}
+			if *res != wantResult {
+				t.Fatalf("Sweep: got %+v, want %+v", *res, wantResult)
+			}
+			cv := f.find(t, tt.cv.ID)
+			if cv.Status != wantStatus || cv.GCSPath != wantPath {
+				t.Fatalf("cv: got %s at %q, want %s at %q", cv.Status, cv.GCSPath, wantStatus, wantPath)
+			}
+			if _, ok := f.storage.Get(tt.cv.GCSPath); ok == tt.wantExpired {
+				t.Fatalf("object present: got %t, want %t", ok, !tt.wantExpired)
+			}
+		})
+	}
+}
+
+// TestSweepRetriesCleanup checks that an object the reaper failed to delete
+// is deleted by the next sweep, without expiring the CV again.
+func TestSweepRetriesCleanup(t *testing.T) {
+	f := newFixture(t, usecase.DefaultUploadPolicy())
+	cv := f.seed(t, pendingCV("cv-overdue", 2*time.Hour, -time.Hour))
+	f.storage.Put(cv.GCSPath, pdf[:10], "application/pdf")
+	reaper := usecase.NewPendingReaperUC(f.storage, f.repo, reaperConfig)
+
+	f.storage.FailOn(memory.OpDelete, errors.New("storage down"))
+	res, err := reaper.Sweep(t.Context())
+	if err != nil {
+		t.Fatalf("first Sweep: %v", err)
+	}
+	if want := (usecase.SweepResult{Scanned: 1, Failed: 1}); *res != want {
+		t.Fatalf("first Sweep: got %+v, want %+v", *res, want)
+	}
+	if got := f.find(t, cv.ID); got.Status != domain.CVStatusExpired || got.GCSPath != cv.GCSPath {
+		t.Fatalf("after first Sweep: got %s at %q, want %s at %q", got.Status, got.GCSPath, domain.CVStatusExpired, cv.GCSPath)
+	}
+
+	f.storage.ClearFailures()
+	res, err = reaper.Sweep(t.Context())
+	if err != nil {
+		t.Fatalf("second Sweep: %v", err)
+	}
+	if want := (usecase.SweepResult{Cleaned: 1}); *res != want {
+		t.Fatalf("second Sweep: got %+v, want %+v", *res, want)
+	}
+	if got := f.find(t, cv.ID).GCSPath; got != "" {
+		t.Fatalf("GCSPath after second Sweep: got %q, want empty", got)
+	}
+	if _, ok := f.storage.Get(cv.GCSPath); ok {
+		t.Fatalf("object %s left after second Sweep", cv.GCSPath)
+	}
+
+	res, err = reaper.Sweep(t.Context())
+	if err != nil {
+		t.Fatalf("third Sweep: %v", err)
+	}
+	if want := (usecase.SweepResult{}); *res != want {
+		t.Fatalf("third Sweep: got %+v, want %+v", *res, want)
+	}
+}
+
+// TestSweepPages checks that every page of pending CVs is swept although
+// expiring a page moves its CVs out of the listed status.
+func TestSweepPages(t *testing.T) {
+	f := newFixture(t, usecase.DefaultUploadPolicy())
+	for i := range 7 {
+		f.seed(t, pendingCV(fmt.Sprintf("cv-%d", i), 2*time.Hour+time.Duration(i)*time.Minute, -time.Hour))
+	}
+	cfg := reaperConfig
+	cfg.BatchSize = 3
+	reaper := usecase.NewPendingReaperUC(f.storage, f.repo, cfg)
+
+	res, err := reaper.Sweep(t.Context())
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if res.Expired != 7 || res.Failed != 0 {
+		t.Fatalf("Sweep: got %+v, want 7 expired", *res)
+	}
+	for i := range 7 {
+		if got := f.find(t, fmt.Sprintf("cv-%d", i)).Status; got != domain.CVStatusExpired {
+			t.Fatalf("cv-%d: got %s, want %s", i, got, domain.CVStatusExpired)
+		}
+	}
+}
+
+func TestSweepListFailure(t *testing.T) {
+	f := newFixture(t, usecase.DefaultUploadPolicy())
+	f.seed(t, pendingCV("cv-overdue", 2*time.Hour, -time.Hour))
+	f.repo.FailOn(memory.OpList, errors.New("database down"))
+	reaper := usecase.NewPendingReaperUC(f.storage, f.repo, reaperConfig)
+
+	if _, err := reaper.Sweep(t.Context()); err == nil {
+		t.Fatal("Sweep: got no error, want the List failure")
+	}
+	if got := f.find(t, "cv-overdue").Status; got != domain.CVStatusPending {
+		t.Fatalf("status: got %s, want %s", got, domain.CVStatusPending)
+	}
+}
@@ -2,10 +2,14 @@ package usecase
 
 import (
 	"context"
+	"cv-platform/internal/domain"
 	logger "cv-platform/internal/log"
 	"fmt"
 )
 
+// ErrProfileNotFound is returned for phone numbers without a profile.
+var ErrProfileNotFound = domain.NewError(domain.ErrNotFound, "profile not found")
+
 type ProfileStoreUC struct {
 }
 
@@ -32,7 +36,7 @@ func (uc *ProfileStoreUC) GetProfile(ctx context.Context, cmd GetProfileCmd) (*G
 
 	if cmd.Phone == "1111" {
 		log.Warnf("profile not found for phone %s: blacklisted", cmd.Phone)
-		return nil, fmt.Errorf("phone number %s is not found", cmd.Phone)
+		return nil, fmt.Errorf("%w: phone number %s", ErrProfileNotFound, cmd.Phone)
 	}
 
 	result := &GetProfileResult{
@@ -0,0 +1,270 @@
+package usecase
+
+import (
+	"cv-platform/internal/domain"
+	"cv-platform/internal/port"
+	"cv-platform/pkg/checksum"
+	"cv-platform/pkg/filetype"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidUpload is returned by StartUpload when the request breaks the
+// upload policy. No URL is signed and nothing is stored.
+var ErrInvalidUpload = domain.NewError(domain.ErrValidation, "invalid upload")
+
+// FieldError is a policy violation on one request field.
+type FieldError = domain.FieldError
+
+// ValidationError lists the policy violations of a request. It wraps
+// ErrInvalidUpload or ErrUploadRejected.
+type ValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error() + ": " + e.Reason()
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// FieldErrors lists the violations, see domain.FieldsOf.
+func (e *ValidationError) FieldErrors() []FieldError { return e.Fields }
+
+// Reason joins the field messages into a single line.
+func (e *ValidationError) Reason() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// UploadPolicy restricts what clients may upload. MIME types are compared
+// after filetype.Normalize, extensions case-insensitively without the dot.
+type UploadPolicy struct {
+	AllowedMimeTypes  []string
+	AllowedExtensions []string
+	// MaxSize is the largest accepted file in bytes; 0 means unlimited.
+	MaxSize int64
+	// MaxFileNameLength caps sanitized file names, in characters.
+	MaxFileNameLength int
+	// ASCIIFileNames replaces non-ASCII characters of file names.
+	ASCIIFileNames bool
+	// Duplicates decides what CompleteUpload does with content another CV
+	// already holds.
+	Duplicates DuplicateMode
+	// DuplicatesPerOwner only looks for duplicates among CVs of the same
+	// owner.
+	DuplicatesPerOwner bool
+	// UploadTTL is how long signed upload URLs and POST policies stay valid,
+	// ResumableTTL how long resumable sessions and tus uploads do. Zero
+	// means DefaultUploadTTL and DefaultResumableTTL.
+	UploadTTL    time.Duration
+	ResumableTTL time.Duration
+}
+
+// DuplicateMode is the handling of uploads whose content (size, MD5 and
+// CRC32C) matches an existing CV.
+type DuplicateMode string
+
+const (
+	// DuplicatesAllow keeps every upload as an independent copy.
+	DuplicatesAllow DuplicateMode = "allow"
+	// DuplicatesLink links the new CV to the existing one, sharing its object,
+	// and deletes the uploaded copy.
+	DuplicatesLink DuplicateMode = "link"
+	// DuplicatesReject rejects the new CV.
+	DuplicatesReject DuplicateMode = "reject"
+)
+
+// Valid reports whether m is a known mode.
+func (m DuplicateMode) Valid() bool {
+	switch m {
+	case DuplicatesAllow, DuplicatesLink, DuplicatesReject:
+		return true
+	}
+	return false
+}
+
+// DefaultUploadPolicy accepts every format of filetype.Supported up to 10 MiB
+// and links duplicates of the same owner.
+func DefaultUploadPolicy() UploadPolicy {
+	return UploadPolicy{
+		AllowedMimeTypes:   slices.Clone(filetype.Supported),
+		AllowedExtensions:  []string{"pdf", "docx", "doc", "odt", "rtf", "txt"},
+		MaxSize:            10 << 20,
+		MaxFileNameLength:  255,
+		Duplicates:         DuplicatesLink,
+		DuplicatesPerOwner: true,
+		UploadTTL:          DefaultUploadTTL,
+		ResumableTTL:       DefaultResumableTTL,
+	}
+}
+
+// ttl is how long an upload by method stays open.
+func (p UploadPolicy) ttl(method string) time.Duration {
+	if method == UploadMethodResumable {
+		if p.ResumableTTL > 0 {
+			return p.ResumableTTL
+		}
+		return DefaultResumableTTL
+	}
+	if p.UploadTTL > 0 {
+		return p.UploadTTL
+	}
+	return DefaultUploadTTL
+}
+
+// AllowsType reports whether the MIME type may be uploaded.
+func (p UploadPolicy) AllowsType(mimeType string) bool {
+	mt := filetype.Normalize(mimeType)
+	for _, allowed := range p.AllowedMimeTypes {
+		if filetype.Normalize(allowed) == mt {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsExtension reports whether files named *.ext may be uploaded.
+func (p UploadPolicy) AllowsExtension(ext string) bool {
+	for _, allowed := range p.AllowedExtensions {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "."), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedNameChars are replaced in file names; they are path separators or
+// reserved on common filesystems.
+const reservedNameChars = `/\:*?"<>|`
+
+// SanitizeFileName drops any directory part of name, replaces control and
+// reserved characters (and non-ASCII ones with ASCIIFileNames) with '_',
+// collapses whitespace, trims leading and trailing dots and spaces, and
+// shortens the base name so the result fits MaxFileNameLength. It returns ""
+// when nothing usable is left.
+func (p UploadPolicy) SanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == utf8.RuneError, unicode.IsControl(r), strings.ContainsRune(reservedNameChars, r),
+			p.ASCIIFileNames && r > unicode.MaxASCII:
+			r = '_'
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	clean := strings.Trim(b.String(), ". ")
+
+	if p.MaxFileNameLength > 0 && utf8.RuneCountInString(clean) > p.MaxFileNameLength {
+		ext := path.Ext(clean)
+		base := []rune(strings.TrimSuffix(clean, ext))
+		keep := p.MaxFileNameLength - utf8.RuneCountInString(ext)
+		if keep < 1 {
+			return ""
+		}
+		clean = strings.TrimRight(string(base[:min(keep, len(base))]), ". ") + ext
+	}
+	return clean
+}
+
+// checkStart validates an upload request and returns the sanitized file name
+// and the declared checksums, base64 encoded.
+func (p UploadPolicy) checkStart(cmd StartUploadCmd) (string, port.Checksums, error) {
+	verr := &ValidationError{Err: ErrInvalidUpload}
+
+	fileName := p.SanitizeFileName(cmd.FileName)
+	ext := strings.TrimPrefix(path.Ext(fileName), ".")
+	switch {
+	case strings.TrimSpace(cmd.FileName) == "":
+		verr.add("file_name", "is required")
+	case fileName == "":
+		verr.add("file_name", "has no usable characters")
+	case ext == "":
+		verr.add("file_name", "must have an extension")
+	case !p.AllowsExtension(ext):
+		verr.add("file_name", "extension .%s is not allowed (allowed: %s)", strings.ToLower(ext), strings.Join(p.AllowedExtensions, ", "))
+	}
+
+	mimeType := filetype.Normalize(cmd.MimeType)
+	switch {
+	case mimeType == "":
+		verr.add("mime_type", "is required")
+	case !p.AllowsType(mimeType):
+		verr.add("mime_type", "%s is not allowed", mimeType)
+	default:
+		if want, ok := filetype.ByExtension(ext); ok && want != mimeType {
+			verr.add("mime_type", "%s does not match the .%s extension", mimeType, strings.ToLower(ext))
+		}
+	}
+
+	switch {
+	case cmd.Size < 0:
+		verr.add("size", "must not be negative")
+	case p.MaxSize > 0 && cmd.Size > p.MaxSize:
+		verr.add("size", "%d bytes exceeds the maximum of %d bytes", cmd.Size, p.MaxSize)
+	}
+
+	switch strings.ToUpper(cmd.Method) {
+	case "", UploadMethodPUT, UploadMethodPOST, UploadMethodResumable:
+	default:
+		verr.add("upload_method", "must be %s, %s or %s", UploadMethodPUT, UploadMethodPOST, UploadMethodResumable)
+	}
+
+	var sums port.Checksums
+	var err error
+	if cmd.MD5 != "" {
+		if sums.MD5, err = checksum.ParseMD5(cmd.MD5); err != nil {
+			verr.add("md5", "must be a 16 byte digest, base64 or hex encoded")
+		}
+	}
+	if cmd.CRC32C != "" {
+		if sums.CRC32C, err = checksum.ParseCRC32C(cmd.CRC32C); err != nil {
+			verr.add("crc32c", "must be a 4 byte big-endian digest, base64 or hex encoded")
+		}
+	}
+
+	return fileName, sums, verr.orNil()
+}
+
+// checkSize validates the real size of an uploaded object.
+func (p UploadPolicy) checkSize(size int64, verr *ValidationError) {
+	switch {
+	case size == 0:
+		verr.add("size", "file is empty")
+	case p.MaxSize > 0 && size > p.MaxSize:
+		verr.add("size", "%d bytes exceeds the maximum of %d bytes", size, p.MaxSize)
+	}
+}
@@ -0,0 +1,149 @@
+package usecase_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+func TestStartUploadPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        usecase.StartUploadCmd
+		wantFields []string
+	}{
+		{name: "valid", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", Size: 1024}},
+		{name: "unknown size", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf"}},
+		{name: "mime type parameters", cmd: usecase.StartUploadCmd{FileName: "CV.PDF", MimeType: "Application/PDF; charset=binary"}},
+		{name: "method in lower case", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", Method: "resumable"}},
+		{name: "no file name", cmd: usecase.StartUploadCmd{MimeType: "application/pdf"}, wantFields: []string{"file_name"}},
+		{name: "only reserved characters", cmd: usecase.StartUploadCmd{FileName: "...", MimeType: "application/pdf"}, wantFields: []string{"file_name"}},
+		{name: "no extension", cmd: usecase.StartUploadCmd{FileName: "cv", MimeType: "application/pdf"}, wantFields: []string{"file_name"}},
+		{name: "extension not allowed", cmd: usecase.StartUploadCmd{FileName: "cv.exe", MimeType: "application/pdf"}, wantFields: []string{"file_name"}},
+		{name: "no mime type", cmd: usecase.StartUploadCmd{FileName: "cv.pdf"}, wantFields: []string{"mime_type"}},
+		{name: "mime type not allowed", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "image/png"}, wantFields: []string{"mime_type"}},
+		{name: "mime type of another extension", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "text/plain"}, wantFields: []string{"mime_type"}},
+		{name: "negative size", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", Size: -1}, wantFields: []string{"size"}},
+		{name: "too large", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", Size: 10<<20 + 1}, wantFields: []string{"size"}},
+		{name: "unknown method", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", Method: "PATCH"}, wantFields: []string{"upload_method"}},
+		{name: "malformed md5", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", MD5: "abc"}, wantFields: []string{"md5"}},
+		{name: "malformed crc32c", cmd: usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: "application/pdf", CRC32C: "!"}, wantFields: []string{"crc32c"}},
+		{
+			name:       "everything wrong",
+			cmd:        usecase.StartUploadCmd{FileName: "cv.exe", Size: -1, Method: "GET"},
+			wantFields: []string{"file_name", "mime_type", "size", "upload_method"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, usecase.DefaultUploadPolicy())
+			tt.cmd.OwnerID = owner
+
+			res, err := f.uploads.StartUpload(t.Context(), tt.cmd)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("StartUpload: %v", err)
+				}
+				if cv := f.find(t, res.ID); cv.Status != domain.CVStatusPending {
+					t.Fatalf("status: got %s, want %s", cv.Status, domain.CVStatusPending)
+				}
+				return
+			}
+			if !errors.Is(err, usecase.ErrInvalidUpload) || !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("StartUpload: got err %v, want %v", err, usecase.ErrInvalidUpload)
+			}
+			if got := fieldsOf(err); !slices.Equal(got, tt.wantFields) {
+				t.Fatalf("fields: got %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	policy := usecase.DefaultUploadPolicy()
+	ascii := policy
+	ascii.ASCIIFileNames = true
+	short := policy
+	short.MaxFileNameLength = 10
+
+	tests := []struct {
+		name   string
+		policy usecase.UploadPolicy
+		in     string
+		want   string
+	}{
+		{name: "plain", policy: policy, in: "cv.pdf", want: "cv.pdf"},
+		{name: "unix directories", policy: policy, in: "../../etc/cv.pdf", want: "cv.pdf"},
+		{name: "windows directories", policy: policy, in: `C:\Users\me\cv.pdf`, want: "cv.pdf"},
+		{name: "reserved characters", policy: policy, in: `my:cv*?.pdf`, want: "my_cv__.pdf"},
+		{name: "control characters", policy: policy, in: "cv\x00\x1f.pdf", want: "cv__.pdf"},
+		{name: "whitespace collapsed", policy: policy, in: "  my \t\n cv .pdf ", want: "my cv .pdf"},
+		{name: "leading and trailing dots", policy: policy, in: "..cv.pdf..", want: "cv.pdf"},
+		{name: "unicode kept", policy: policy, in: "résumé.pdf", want: "résumé.pdf"},
+		{name: "unicode replaced", policy: ascii, in: "résumé.pdf", want: "r_sum_.pdf"},
+		{name: "invalid utf-8", policy: policy, in: "cv\xff.pdf", want: "cv_.pdf"},
+		{name: "shortened, extension kept", policy: short, in: "curriculum-vitae.pdf", want: "curric.pdf"},
+		{name: "shortened by characters", policy: short, in: "éééééééééé.pdf", want: "éééééé.pdf"},
+		{name: "extension longer than the limit", policy: short, in: "cv.verylongextension", want: ""},
+		{name: "empty", policy: policy, in: "", want: ""},
+		{name: "directory only", policy: policy, in: "dir/", want: "dir"},
+		{name: "root", policy: policy, in: "/", want: ""},
+		{name: "dots only", policy: policy, in: "..", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.SanitizeFileName(tt.in); got != tt.want {
+				t.Fatalf("SanitizeFileName(%q): got %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompleteUploadInspectsContent checks the policy against what was
+// actually uploaded rather than what was declared.
+func TestCompleteUploadInspectsContent(t *testing.T) {
+	policy := usecase.DefaultUploadPolicy()
+	policy.MaxSize = 1 << 10
+
+	tests := []struct {
+		name       string
+		data       []byte
+		mimeType   string
+		wantFields []string
+	}{
+		{name: "declared type", data: pdf, mimeType: "application/pdf"},
+		{name: "type mismatch", data: []byte("just some text\n"), mimeType: "application/pdf", wantFields: []string{"content"}},
+		{name: "type not allowed", data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), mimeType: "application/pdf", wantFields: []string{"content"}},
+		{name: "empty", data: nil, mimeType: "application/pdf", wantFields: []string{"size"}},
+		{name: "too large", data: append(slices.Clone(pdf), strings.Repeat(" ", 1<<10)...), mimeType: "application/pdf", wantFields: []string{"size"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, policy)
+			cv, err := f.upload(t, tt.data, usecase.StartUploadCmd{FileName: "cv.pdf", MimeType: tt.mimeType})
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("CompleteUpload: %v", err)
+				}
+				if cv.Status != domain.CVStatusUploaded {
+					t.Fatalf("status: got %s, want %s", cv.Status, domain.CVStatusUploaded)
+				}
+				return
+			}
+			if !errors.Is(err, usecase.ErrUploadRejected) || !errors.Is(err, domain.ErrRejected) {
+				t.Fatalf("CompleteUpload: got err %v, want %v", err, usecase.ErrUploadRejected)
+			}
+			if got := fieldsOf(err); !slices.Equal(got, tt.wantFields) {
+				t.Fatalf("fields: got %v, want %v", got, tt.wantFields)
+			}
+			stored := f.find(t, cv.ID)
+			if stored.Status != domain.CVStatusRejected || stored.RejectReason == "" {
+				t.Fatalf("stored: got status %s, reason %q, want %s with a reason", stored.Status, stored.RejectReason, domain.CVStatusRejected)
+			}
+		})
+	}
+}
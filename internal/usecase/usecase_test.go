@@ -0,0 +1,89 @@
+package usecase_test
+
+import (
+	"testing"
+	"time"
+
+	"cv-platform/internal/adapter/memory"
+	"cv-platform/internal/domain"
+	"cv-platform/internal/usecase"
+)
+
+// owner is the authenticated caller of the tests.
+const owner = "alice"
+
+// pdf is content sniffed as application/pdf.
+var pdf = []byte("%PDF-1.7\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+
+// fixture is an upload use case on the in-memory adapters.
+type fixture struct {
+	storage *memory.BlobStorage
+	repo    *memory.CVRepository
+	uploads *usecase.CVUploadUC
+}
+
+func newFixture(t *testing.T, policy usecase.UploadPolicy) *fixture {
+	t.Helper()
+	f := &fixture{storage: memory.NewBlobStorage(), repo: memory.NewCVRepository()}
+	f.uploads = usecase.NewCVUploadUC(f.storage, f.repo, policy)
+	return f
+}
+
+// start starts the PUT upload of cv.pdf for owner.
+func (f *fixture) start(t *testing.T, cmd usecase.StartUploadCmd) *usecase.StartUploadResult {
+	t.Helper()
+	if cmd.FileName == "" {
+		cmd.FileName, cmd.MimeType = "cv.pdf", "application/pdf"
+	}
+	if cmd.OwnerID == "" {
+		cmd.OwnerID = owner
+	}
+	res, err := f.uploads.StartUpload(t.Context(), cmd)
+	if err != nil {
+		t.Fatalf("StartUpload: %v", err)
+	}
+	return res
+}
+
+// upload starts an upload of data, stores it as the client would and
+// completes it, returning the result of CompleteUpload.
+func (f *fixture) upload(t *testing.T, data []byte, cmd usecase.StartUploadCmd) (*domain.CV, error) {
+	t.Helper()
+	res := f.start(t, cmd)
+	f.storage.Put(res.ObjectKey, data, "application/pdf")
+	return f.uploads.CompleteUpload(t.Context(), usecase.CompleteUploadCmd{ID: res.ID, RequesterID: owner})
+}
+
+// seed stores cv as is.
+func (f *fixture) seed(t *testing.T, cv domain.CV) *domain.CV {
+	t.Helper()
+	if cv.OwnerID == "" {
+		cv.OwnerID = owner
+	}
+	if cv.CreatedAt.IsZero() {
+		cv.CreatedAt = time.Now()
+	}
+	if err := f.repo.Create(t.Context(), &cv); err != nil {
+		t.Fatalf("Create %s: %v", cv.ID, err)
+	}
+	return &cv
+}
+
+// find loads CV id, which must exist.
+func (f *fixture) find(t *testing.T, id string) *domain.CV {
+	t.Helper()
+	cv, err := f.repo.FindByID(t.Context(), id)
+	if err != nil {
+		t.Fatalf("FindByID %s: %v", id, err)
+	}
+	return cv
+}
+
+// fieldsOf returns the names of the fields err complains about.
+func fieldsOf(err error) []string {
+	var fields []string
+	for _, fe := range domain.FieldsOf(err) {
+		fields = append(fields, fe.Field)
+	}
+	return fields
+}
@@ -0,0 +1,132 @@
+// Package checksum computes and parses the content digests used to verify
+// uploads end to end: MD5 and CRC32C (Castagnoli), both carried base64
+// encoded, as in the Content-MD5 and X-Goog-Hash headers.
+package checksum
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"strings"
+)
+
+// Request headers carrying checksums. X-Goog-Hash holds comma separated
+// "crc32c=<base64>" and "md5=<base64>" entries.
+const (
+	HeaderMD5  = "Content-MD5"
+	HeaderHash = "X-Goog-Hash"
+)
+
+var (
+	ErrInvalidMD5    = errors.New("checksum: md5 must be 16 bytes, base64 or hex encoded")
+	ErrInvalidCRC32C = errors.New("checksum: crc32c must be 4 bytes, base64 or hex encoded")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Hash computes MD5 and CRC32C of everything written to it.
+type Hash struct {
+	md5 hash.Hash
+	crc hash.Hash32
+}
+
+func New() *Hash {
+	return &Hash{md5: md5.New(), crc: crc32.New(castagnoli)}
+}
+
+// Write never fails.
+func (h *Hash) Write(p []byte) (int, error) {
+	h.md5.Write(p)
+	h.crc.Write(p)
+	return len(p), nil
+}
+
+// MD5 returns the base64 encoded MD5 of the data written so far.
+func (h *Hash) MD5() string {
+	return base64.StdEncoding.EncodeToString(h.md5.Sum(nil))
+}
+
+// CRC32C returns the base64 encoded big-endian CRC32C of the data written so
+// far.
+func (h *Hash) CRC32C() string {
+	return EncodeCRC32C(h.crc.Sum32())
+}
+
+// Sum returns the base64 encoded MD5 and CRC32C of data.
+func Sum(data []byte) (md5Sum, crc32cSum string) {
+	h := New()
+	h.Write(data)
+	return h.MD5(), h.CRC32C()
+}
+
+// EncodeCRC32C encodes a CRC32C value the way GCS reports it.
+func EncodeCRC32C(v uint32) string {
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, v))
+}
+
+// ParseMD5 accepts an MD5 digest in base64 (as in Content-MD5) or hex (as
+// printed by md5sum) and returns it base64 encoded.
+func ParseMD5(s string) (string, error) {
+	b, ok := decode(s, md5.Size)
+	if !ok {
+		return "", ErrInvalidMD5
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// ParseCRC32C accepts a big-endian CRC32C in base64 or hex and returns it
+// base64 encoded.
+func ParseCRC32C(s string) (string, error) {
+	b, ok := decode(s, crc32.Size)
+	if !ok {
+		return "", ErrInvalidCRC32C
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func decode(s string, size int) ([]byte, bool) {
+	s = strings.TrimSpace(s)
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == size {
+		return b, true
+	}
+	if b, err := hex.DecodeString(s); err == nil && len(b) == size {
+		return b, true
+	}
+	return nil, false
+}
+
+// HashHeader formats an X-Goog-Hash value; empty digests are left out.
+func HashHeader(md5Sum, crc32cSum string) string {
+	var parts []string
+	if crc32cSum != "" {
+		parts = append(parts, "crc32c="+crc32cSum)
+	}
+	if md5Sum != "" {
+		parts = append(parts, "md5="+md5Sum)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseHashHeader extracts the digests of X-Goog-Hash values, which may be
+// repeated or comma separated.
+func ParseHashHeader(values []string) (md5Sum, crc32cSum string) {
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			name, sum, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(name) {
+			case "md5":
+				md5Sum = sum
+			case "crc32c":
+				crc32cSum = sum
+			}
+		}
+	}
+	return md5Sum, crc32cSum
+}
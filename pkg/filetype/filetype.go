@@ -0,0 +1,258 @@
+// Package filetype detects the format of CV documents from their content.
+//
+// Detection reads only the parts of a file it needs through an io.ReaderAt:
+// the first few kilobytes, plus the directory of OLE compound files (.doc)
+// and the central directory of ZIP containers (.docx, .odt). It never trusts
+// names or declared content types.
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Canonical MIME types of the supported CV formats.
+const (
+	PDF  = "application/pdf"
+	DOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	DOC  = "application/msword"
+	ODT  = "application/vnd.oasis.opendocument.text"
+	RTF  = "application/rtf"
+	TXT  = "text/plain"
+)
+
+// Container types reported when a file is a ZIP or OLE file of another kind.
+const (
+	Zip        = "application/zip"
+	OLEStorage = "application/x-ole-storage"
+)
+
+// Unknown is reported for content that matches no known signature.
+const Unknown = "application/octet-stream"
+
+// Supported lists the CV formats this package can recognise.
+var Supported = []string{PDF, DOCX, DOC, ODT, RTF, TXT}
+
+var extensions = map[string]string{
+	"pdf":  PDF,
+	"docx": DOCX,
+	"doc":  DOC,
+	"odt":  ODT,
+	"rtf":  RTF,
+	"txt":  TXT,
+}
+
+// ByExtension returns the supported type conventionally stored under ext
+// (without the dot, any case).
+func ByExtension(ext string) (string, bool) {
+	t, ok := extensions[strings.ToLower(ext)]
+	return t, ok
+}
+
+// headLen is the number of leading bytes read for signature checks.
+const headLen = 8 << 10
+
+// maxZipDirLen bounds how much of a ZIP central directory is read.
+const maxZipDirLen = 1 << 20
+
+var aliases = map[string]string{
+	"text/rtf":                RTF,
+	"application/x-rtf":       RTF,
+	"application/x-pdf":       PDF,
+	"application/vnd.ms-word": DOC,
+}
+
+// Normalize lowercases a MIME type, drops its parameters and maps common
+// aliases (e.g. text/rtf) to the canonical types of this package.
+func Normalize(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+	if canonical, ok := aliases[mt]; ok {
+		return canonical
+	}
+	return mt
+}
+
+// Detect returns the MIME type of the size bytes readable from r: one of the
+// supported formats, Zip or OLEStorage for other containers, or the best guess
+// of http.DetectContentType. Only UTF-8 text is reported as TXT. Empty input
+// is Unknown.
+func Detect(r io.ReaderAt, size int64) (string, error) {
+	head, err := readAt(r, 0, min(size, headLen))
+	if err != nil {
+		return "", err
+	}
+	if len(head) == 0 {
+		return Unknown, nil
+	}
+
+	switch {
+	case isPDF(head):
+		return PDF, nil
+	case bytes.HasPrefix(head, []byte(`{\rtf`)):
+		return RTF, nil
+	case bytes.HasPrefix(head, oleMagic):
+		return detectOLE(r, head)
+	case bytes.HasPrefix(head, zipMagic):
+		return detectZip(r, size, head)
+	}
+
+	// DetectContentType recognises markup (HTML, XML) that would otherwise
+	// pass as text; plain text must also be clean UTF-8.
+	sniffed := Normalize(http.DetectContentType(head))
+	if sniffed == TXT && !isText(head, int64(len(head)) < size) {
+		return Unknown, nil
+	}
+	return sniffed, nil
+}
+
+// isPDF requires the header at the start, after at most a byte order mark
+// and whitespace. Readers tolerate junk before it, but accepting that would
+// pass off an HTML page that mentions "%PDF-" as a PDF.
+func isPDF(head []byte) bool {
+	b := bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimLeft(b, " \t\r\n\f"), []byte("%PDF-"))
+}
+
+// isText reports whether head is UTF-8 text without control characters other
+// than common whitespace. When truncated, a rune cut at the end is allowed.
+func isText(head []byte, truncated bool) bool {
+	b := bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && n <= 1 {
+			return truncated && !utf8.FullRune(b)
+		}
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' || r == 0x7f {
+			return false
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// wordCLSIDs are root storage CLSIDs written by Word 6 to Word 2003.
+var wordCLSIDs = [][]byte{
+	{0x06, 0x09, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46},
+	{0x00, 0x09, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46},
+}
+
+// wordDocumentEntry is the UTF-16LE name of the main stream of a .doc file.
+var wordDocumentEntry = utf16le("WordDocument")
+
+// detectOLE reads the first directory sector of a compound file and looks
+// for a Word root CLSID or a WordDocument stream.
+func detectOLE(r io.ReaderAt, head []byte) (string, error) {
+	if len(head) < 512 {
+		return OLEStorage, nil
+	}
+	shift := binary.LittleEndian.Uint16(head[30:32])
+	if shift != 9 && shift != 12 {
+		return OLEStorage, nil
+	}
+	sectorLen := int64(1) << shift
+	dirSector := int64(binary.LittleEndian.Uint32(head[48:52]))
+	dir, err := readAt(r, (dirSector+1)*sectorLen, sectorLen)
+	if err != nil {
+		return "", err
+	}
+
+	// Directory entries are 128 bytes: a 64 byte UTF-16 name, then the
+	// CLSID at offset 80. The root storage is the first entry.
+	for off := 0; off+128 <= len(dir); off += 128 {
+		entry := dir[off : off+128]
+		if off == 0 {
+			for _, clsid := range wordCLSIDs {
+				if bytes.Equal(entry[80:96], clsid) {
+					return DOC, nil
+				}
+			}
+		}
+		if bytes.HasPrefix(entry[:64], wordDocumentEntry) {
+			return DOC, nil
+		}
+	}
+	return OLEStorage, nil
+}
+
+var (
+	zipMagic       = []byte("PK\x03\x04")
+	zipDirMagic    = []byte("PK\x01\x02")
+	zipDirEndMagic = []byte("PK\x05\x06")
+	odtMimetype    = []byte("mimetypeapplication/vnd.oasis.opendocument.text")
+)
+
+// detectZip recognises ODT by the stored mimetype entry the OpenDocument spec
+// requires first in the archive, and DOCX by a word/ part listed in the
+// central directory.
+func detectZip(r io.ReaderAt, size int64, head []byte) (string, error) {
+	if len(head) >= 30+len(odtMimetype) && bytes.HasPrefix(head[30:], odtMimetype) {
+		return ODT, nil
+	}
+
+	// The end of central directory record is 22 bytes plus a comment of at
+	// most 64 KiB.
+	tailLen := min(size, 22+0xffff)
+	tail, err := readAt(r, size-tailLen, tailLen)
+	if err != nil {
+		return "", err
+	}
+	end := bytes.LastIndex(tail, zipDirEndMagic)
+	if end < 0 || len(tail)-end < 22 {
+		return Zip, nil
+	}
+	dirLen := int64(binary.LittleEndian.Uint32(tail[end+12:]))
+	dirOff := int64(binary.LittleEndian.Uint32(tail[end+16:]))
+	if dirOff+dirLen > size {
+		return Zip, nil
+	}
+	dir, err := readAt(r, dirOff, min(dirLen, maxZipDirLen))
+	if err != nil {
+		return "", err
+	}
+
+	for len(dir) >= 46 && bytes.HasPrefix(dir, zipDirMagic) {
+		nameLen := int(binary.LittleEndian.Uint16(dir[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(dir[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(dir[32:]))
+		if len(dir) < 46+nameLen {
+			break
+		}
+		if bytes.HasPrefix(dir[46:46+nameLen], []byte("word/")) {
+			return DOCX, nil
+		}
+		dir = dir[min(len(dir), 46+nameLen+extraLen+commentLen):]
+	}
+	return Zip, nil
+}
+
+// readAt reads up to n bytes at off, returning fewer at the end of input.
+func readAt(r io.ReaderAt, off, n int64) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+func utf16le(s string) []byte {
+	b := make([]byte, 0, 2*len(s))
+	for _, c := range s {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
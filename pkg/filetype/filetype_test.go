@@ -0,0 +1,36 @@
+package filetype_test
+
+import (
+	"bytes"
+	"testing"
+
+	"cv-platform/pkg/filetype"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{"pdf", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj", filetype.PDF},
+		{"pdf after bom", "\xef\xbb\xbf%PDF-1.4\n", filetype.PDF},
+		{"pdf after whitespace", "\r\n \t%PDF-1.4\n", filetype.PDF},
+		{"html mentioning pdf", "<!DOCTYPE html><html><body><script>alert(1)</script>%PDF-1.7</body></html>", "text/html"},
+		{"text mentioning pdf", "My CV is attached as a %PDF-1.7 file.\n", filetype.TXT},
+		{"pdf after junk", "junk%PDF-1.7\n", filetype.TXT},
+		{"rtf", `{\rtf1\ansi Hello}`, filetype.RTF},
+		{"binary", "\x00\x01\x02\x03", filetype.Unknown},
+		{"empty", "", filetype.Unknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filetype.Detect(bytes.NewReader([]byte(tc.data)), int64(len(tc.data)))
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Detect(%q) = %s, want %s", tc.data, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package resumable implements the wire format of the GCS resumable upload
+// protocol shared by the storages that emulate it: chunks are PUT with a
+// Content-Range header, progress is reported with 308 responses carrying a
+// Range header, and an empty PUT with "Content-Range: bytes */<total>" queries
+// the status.
+package resumable
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// StatusIncomplete is the status of responses to chunks that leave the
+// upload unfinished ("308 Resume Incomplete").
+const StatusIncomplete = 308
+
+// UnknownTotal is the Total of a Content-Range whose total size is "*".
+const UnknownTotal = -1
+
+var (
+	ErrInvalidRange = errors.New("resumable: invalid content-range")
+	// ErrGap is returned for chunks starting past the bytes received so far.
+	ErrGap = errors.New("resumable: chunk does not continue the upload")
+)
+
+// ContentRange is a parsed Content-Range request header. Chunks carry bytes
+// First to Last inclusive; status queries ("bytes */total") carry none.
+type ContentRange struct {
+	First, Last int64
+	Total       int64
+	HasData     bool
+}
+
+// ParseContentRange parses "bytes first-last/total" and "bytes */total",
+// where total may be "*". An empty header is a status query of unknown total.
+func ParseContentRange(v string) (ContentRange, error) {
+	cr := ContentRange{Total: UnknownTotal}
+	if strings.TrimSpace(v) == "" {
+		return cr, nil
+	}
+	spec, ok := strings.CutPrefix(strings.TrimSpace(v), "bytes ")
+	if !ok {
+		return cr, ErrInvalidRange
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return cr, ErrInvalidRange
+	}
+	if total != "*" {
+		n, err := strconv.ParseInt(total, 10, 64)
+		if err != nil || n < 0 {
+			return cr, ErrInvalidRange
+		}
+		cr.Total = n
+	}
+	if span == "*" {
+		return cr, nil
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return cr, ErrInvalidRange
+	}
+	var err1, err2 error
+	cr.First, err1 = strconv.ParseInt(first, 10, 64)
+	cr.Last, err2 = strconv.ParseInt(last, 10, 64)
+	if err1 != nil || err2 != nil || cr.First < 0 || cr.Last < cr.First ||
+		cr.Total != UnknownTotal && cr.Last >= cr.Total {
+		return cr, ErrInvalidRange
+	}
+	cr.HasData = true
+	return cr, nil
+}
+
+// Len is the number of bytes a chunk carries.
+func (cr ContentRange) Len() int64 {
+	if !cr.HasData {
+		return 0
+	}
+	return cr.Last - cr.First + 1
+}
+
+// Append copies the part of the chunk body not yet received to w, skipping
+// bytes a retried chunk repeats. It returns the new number of bytes received.
+func (cr ContentRange) Append(w io.Writer, body io.Reader, received int64) (int64, error) {
+	if !cr.HasData {
+		return received, nil
+	}
+	if cr.First > received {
+		return received, ErrGap
+	}
+	if skip := received - cr.First; skip > 0 {
+		if _, err := io.CopyN(io.Discard, body, min(skip, cr.Len())); err != nil {
+			return received, err
+		}
+	}
+	if cr.Last < received {
+		return received, nil
+	}
+	n, err := io.CopyN(w, body, cr.Last+1-received)
+	received += n
+	if err == io.EOF {
+		err = fmt.Errorf("%w: body shorter than range", ErrInvalidRange)
+	}
+	return received, err
+}
+
+// Complete reports whether received covers the total announced by cr.
+func (cr ContentRange) Complete(received int64) bool {
+	return cr.Total != UnknownTotal && received == cr.Total
+}
+
+// RangeHeader formats the Range header of a 308 response, or "" when nothing
+// was received.
+func RangeHeader(received int64) string {
+	if received <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("bytes=0-%d", received-1)
+}
+
+// ParseRangeHeader returns the number of bytes received according to the
+// Range header of a 308 response.
+func ParseRangeHeader(v string) int64 {
+	last, ok := strings.CutPrefix(strings.TrimSpace(v), "bytes=0-")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n + 1
+}
@@ -0,0 +1,102 @@
+// Package tus implements the header formats of the tus 1.0 resumable upload
+// protocol (https://tus.io/protocols/resumable-upload): upload metadata and
+// the checksum extension's Upload-Checksum header.
+package tus
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+)
+
+// Version is the only protocol version spoken.
+const Version = "1.0.0"
+
+// Protocol headers.
+const (
+	HeaderResumable           = "Tus-Resumable"
+	HeaderVersion             = "Tus-Version"
+	HeaderExtension           = "Tus-Extension"
+	HeaderMaxSize             = "Tus-Max-Size"
+	HeaderChecksumAlgorithm   = "Tus-Checksum-Algorithm"
+	HeaderUploadOffset        = "Upload-Offset"
+	HeaderUploadLength        = "Upload-Length"
+	HeaderUploadDeferLength   = "Upload-Defer-Length"
+	HeaderUploadMetadata      = "Upload-Metadata"
+	HeaderUploadChecksum      = "Upload-Checksum"
+	HeaderXHTTPMethodOverride = "X-HTTP-Method-Override"
+)
+
+// ContentTypeOffset is the content type of PATCH requests.
+const ContentTypeOffset = "application/offset+octet-stream"
+
+// StatusChecksumMismatch is the status of PATCH requests whose body does not
+// match their Upload-Checksum.
+const StatusChecksumMismatch = 460
+
+var (
+	ErrInvalidMetadata      = errors.New("tus: invalid upload-metadata")
+	ErrInvalidChecksum      = errors.New("tus: invalid upload-checksum")
+	ErrUnsupportedAlgorithm = errors.New("tus: unsupported checksum algorithm")
+)
+
+// algorithms are the checksum algorithms supported, by tus name.
+var algorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"md5":    md5.New,
+}
+
+// Algorithms is the value of the Tus-Checksum-Algorithm header.
+const Algorithms = "sha1,sha256,md5"
+
+// ParseMetadata parses an Upload-Metadata header: comma separated pairs of a
+// key and a base64 encoded value, which may be omitted.
+func ParseMetadata(v string) (map[string]string, error) {
+	md := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, enc, _ := strings.Cut(pair, " ")
+		if key == "" || strings.ContainsAny(enc, " ") {
+			return nil, ErrInvalidMetadata
+		}
+		val, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, ErrInvalidMetadata
+		}
+		if _, dup := md[key]; dup {
+			return nil, ErrInvalidMetadata
+		}
+		md[key] = string(val)
+	}
+	return md, nil
+}
+
+// ParseChecksum parses an Upload-Checksum header ("<algorithm> <base64
+// digest>") and returns a hash of the algorithm with the expected sum.
+func ParseChecksum(v string) (hash.Hash, []byte, error) {
+	alg, enc, ok := strings.Cut(strings.TrimSpace(v), " ")
+	if !ok {
+		return nil, nil, ErrInvalidChecksum
+	}
+	newHash, ok := algorithms[strings.ToLower(alg)]
+	if !ok {
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+	sum, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, nil, ErrInvalidChecksum
+	}
+	h := newHash()
+	if len(sum) != h.Size() {
+		return nil, nil, ErrInvalidChecksum
+	}
+	return h, sum, nil
+}